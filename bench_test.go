@@ -0,0 +1,213 @@
+package sqltocsv_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+// benchRowCount is the size of the result set used by the benchmarks.
+const benchRowCount = 1000000
+
+// setupBenchDatabase fills a database separate from the one the tests wipe
+// with benchRowCount rows, the first time it is called.
+func setupBenchDatabase(b *testing.B) *sql.DB {
+	db, err := sql.Open("test", "bench")
+	if err != nil {
+		b.Fatalf("Error opening benchdb %v", err)
+	}
+
+	var count int
+	if rows, err := db.Query("SELECT|people|name|"); err == nil {
+		for rows.Next() {
+			count++
+		}
+		rows.Close()
+	}
+	if count == benchRowCount {
+		return db
+	}
+
+	benchExec(b, db, "WIPE")
+	benchExec(b, db, "CREATE|people|name=string,age=int32,bdate=datetime,nickname=nullstring")
+	for i := 0; i < benchRowCount; i++ {
+		benchExec(b, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", fmt.Sprintf("person %d", i), i, time.Unix(int64(i), 0), nil)
+	}
+	return db
+}
+
+func benchExec(b *testing.B, db *sql.DB, query string, args ...any) {
+	if _, err := db.Exec(query, args...); err != nil {
+		b.Fatalf("Exec of %q: %v", query, err)
+	}
+}
+
+func benchmarkWrite(b *testing.B, configure func(*sqltocsv.Converter)) {
+	db := setupBenchDatabase(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rows, err := db.Query("SELECT|people|name,age,bdate,nickname|")
+		if err != nil {
+			b.Fatalf("error querying: %v", err)
+		}
+
+		converter := sqltocsv.New(rows)
+		configure(converter)
+		if err := converter.Write(io.Discard); err != nil {
+			b.Fatalf("error in Write: %v", err)
+		}
+		rows.Close()
+	}
+}
+
+func BenchmarkWrite_1MRows(b *testing.B) {
+	benchmarkWrite(b, func(*sqltocsv.Converter) {})
+}
+
+func BenchmarkWrite_1MRows_BufferSize1MB(b *testing.B) {
+	benchmarkWrite(b, func(converter *sqltocsv.Converter) {
+		converter.BufferSize = 1 << 20
+	})
+}
+
+// slowConnector opens connections whose queries return rows name, age rows,
+// waiting delay before each batch of them as a driver fetching rows over a
+// network would.
+type slowConnector struct {
+	rows  int
+	batch int
+	delay time.Duration
+}
+
+func (c slowConnector) Connect(context.Context) (driver.Conn, error) { return slowConn{c}, nil }
+func (c slowConnector) Driver() driver.Driver                        { return nil }
+
+type slowConn struct{ slowConnector }
+
+func (c slowConn) Prepare(string) (driver.Stmt, error) { return slowStmt{c.slowConnector}, nil }
+func (c slowConn) Close() error                        { return nil }
+func (c slowConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+type slowStmt struct{ slowConnector }
+
+func (s slowStmt) Close() error                               { return nil }
+func (s slowStmt) NumInput() int                              { return 0 }
+func (s slowStmt) Exec([]driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (s slowStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &slowRows{slowConnector: s.slowConnector}, nil
+}
+
+type slowRows struct {
+	slowConnector
+	next int
+}
+
+func (r *slowRows) Columns() []string { return []string{"name", "age"} }
+func (r *slowRows) Close() error      { return nil }
+func (r *slowRows) Next(dest []driver.Value) error {
+	if r.next == r.rows {
+		return io.EOF
+	}
+	if r.next%r.batch == 0 {
+		time.Sleep(r.delay)
+	}
+	dest[0] = fmt.Sprintf("person %d", r.next)
+	dest[1] = int64(r.next)
+	r.next++
+	return nil
+}
+
+// spin keeps the CPU busy for d, standing in for expensive formatting.
+func spin(d time.Duration) {
+	for start := time.Now(); time.Since(start) < d; {
+	}
+}
+
+func benchmarkSlowDriver(b *testing.B, pipelined bool) {
+	db := sql.OpenDB(slowConnector{rows: 2000, batch: 100, delay: 5 * time.Millisecond})
+	defer db.Close()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rows, err := db.Query("SELECT")
+		if err != nil {
+			b.Fatalf("error querying: %v", err)
+		}
+
+		converter := sqltocsv.New(rows)
+		converter.Pipelined = pipelined
+		converter.SetColumnFormatter("name", func(value any) (string, error) {
+			spin(50 * time.Microsecond)
+			return fmt.Sprint(value), nil
+		})
+		if err := converter.Write(io.Discard); err != nil {
+			b.Fatalf("error in Write: %v", err)
+		}
+		rows.Close()
+	}
+}
+
+func BenchmarkWrite_SlowDriver(b *testing.B) {
+	benchmarkSlowDriver(b, false)
+}
+
+func BenchmarkWrite_SlowDriver_Pipelined(b *testing.B) {
+	benchmarkSlowDriver(b, true)
+}
+
+// benchmarkSynthetic writes benchRowCount rows made up on the fly by a
+// typedConnector, so that the numbers are down to sqltocsv rather than the
+// fake database.
+func benchmarkSynthetic(b *testing.B, configure func(*sqltocsv.Converter)) {
+	db := sql.OpenDB(typedConnector{
+		columns: []typedColumn{
+			{"id", "BIGINT", reflect.TypeFor[int64]()},
+			{"name", "VARCHAR", reflect.TypeFor[sql.RawBytes]()},
+			{"score", "DOUBLE", reflect.TypeFor[float64]()},
+			{"created", "DATETIME", reflect.TypeFor[time.Time]()},
+		},
+		count: benchRowCount,
+		generate: func(i int, dest []driver.Value) {
+			dest[0] = int64(i)
+			dest[1] = []byte("person")
+			dest[2] = float64(i) / 4
+			dest[3] = time.Unix(int64(i), 0).UTC()
+		},
+	})
+	defer db.Close()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rows, err := db.Query("SELECT")
+		if err != nil {
+			b.Fatalf("error querying: %v", err)
+		}
+
+		converter := sqltocsv.New(rows)
+		configure(converter)
+		if err := converter.Write(io.Discard); err != nil {
+			b.Fatalf("error in Write: %v", err)
+		}
+		rows.Close()
+	}
+}
+
+func BenchmarkWrite_1MRows_Synthetic(b *testing.B) {
+	benchmarkSynthetic(b, func(*sqltocsv.Converter) {})
+}
+
+func BenchmarkWrite_1MRows_Synthetic_UseColumnTypes(b *testing.B) {
+	benchmarkSynthetic(b, func(converter *sqltocsv.Converter) {
+		converter.UseColumnTypes = true
+	})
+}
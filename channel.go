@@ -0,0 +1,54 @@
+package sqltocsv
+
+import (
+	"context"
+	"fmt"
+)
+
+// Rows converts the rows as Write would and sends each one on the returned
+// channel, starting with the header row if WriteHeaders is set, for
+// pipelines that want the fields rather than CSV text. The rows are scanned
+// in a goroutine that only reads ahead as fast as they're received. Once
+// the row channel is closed the error channel gets exactly one value, nil
+// if every row was sent, and is closed too. Cancelling ctx stops the
+// goroutine, which then reports the cancellation.
+func (c Converter) Rows(ctx context.Context) (<-chan []string, <-chan error) {
+	rows := make(chan []string)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		err := c.sendRows(ctx, rows)
+		close(rows)
+		errs <- err
+	}()
+	return rows, errs
+}
+
+// sendRows does the work of Rows.
+func (c Converter) sendRows(ctx context.Context, rows chan<- []string) (err error) {
+	defer c.closeRows(&err)
+
+	p, err := c.newPlan()
+	if err != nil {
+		return err
+	}
+
+	send := func(row []string) error {
+		select {
+		case rows <- row:
+			return nil
+		case <-ctx.Done():
+			return fmt.Errorf("export cancelled: %w", ctx.Err())
+		}
+	}
+	if c.WriteHeaders {
+		if err := send(p.headers); err != nil {
+			return err
+		}
+	}
+	return c.eachRow(ctx, p, func(row []string, _ []any) error {
+		// eachRow reuses row for the next one
+		return send(append([]string(nil), row...))
+	})
+}
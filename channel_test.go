@@ -0,0 +1,118 @@
+package sqltocsv_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestRowsChannel(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=Bob,age=?,bdate=?", 2, time.Unix(0, 0).UTC())
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age,bdate|"))
+	converter.TimeFormat = time.DateOnly
+	rows, errs := converter.Rows(context.Background())
+
+	var got [][]string
+	for row := range rows {
+		got = append(got, row)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("error from Rows: %v", err)
+	}
+	if _, open := <-errs; open {
+		t.Error("expected the error channel to be closed after its one value")
+	}
+
+	expected := [][]string{{"name", "age", "bdate"}, {"Alice", "1", "1973-11-29"}, {"Bob", "2", "1970-01-01"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected rows %q, got %q", expected, got)
+	}
+}
+
+func TestRowsChannelWithoutHeaders(t *testing.T) {
+	converter := getConverter(t)
+	converter.WriteHeaders = false
+	converter.IncludeColumns = []string{"name"}
+	rows, errs := converter.Rows(context.Background())
+
+	var got [][]string
+	for row := range rows {
+		got = append(got, row)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("error from Rows: %v", err)
+	}
+	if expected := [][]string{{"Alice"}}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected rows %q, got %q", expected, got)
+	}
+}
+
+func TestRowsChannelError(t *testing.T) {
+	converter := getConverter(t)
+	failure := errors.New("can't format")
+	converter.SetColumnFormatter("age", func(any) (string, error) { return "", failure })
+	rows, errs := converter.Rows(context.Background())
+
+	for range rows {
+	}
+	if err := <-errs; !errors.Is(err, failure) {
+		t.Errorf("expected the formatter error, got %v", err)
+	}
+}
+
+func TestRowsChannelCancel(t *testing.T) {
+	db := sql.OpenDB(typedConnector{
+		columns: []typedColumn{{"id", "BIGINT", reflect.TypeFor[int64]()}},
+		count:   1000000,
+		generate: func(i int, dest []driver.Value) {
+			dest[0] = int64(i)
+		},
+	})
+	defer db.Close()
+	query, err := db.Query("SELECT")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+	defer query.Close()
+
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, errs := sqltocsv.New(query).Rows(ctx)
+	for i := -1; i < 3; i++ {
+		row := <-rows
+		if i >= 0 && row[0] != strconv.Itoa(i) {
+			t.Fatalf("expected row %d, got %q", i, row)
+		}
+	}
+	cancel()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the cancellation, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected cancelling to stop the goroutine")
+	}
+	if _, open := <-rows; open {
+		t.Error("expected the row channel to be closed")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("expected %d goroutines after cancelling, got %d", before, after)
+	}
+}
@@ -0,0 +1,72 @@
+package sqltocsv
+
+import (
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// UTF16LE is the Encoding for the UTF-16LE that Excel opens on a double
+// click with non-ASCII text and tab delimiters intact. It doesn't write a
+// byte order mark itself, so that there's only ever the one, but Excel needs
+// it: set WriteBOM with it to start the file with \xFF\xFE.
+var UTF16LE encoding.Encoding = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+
+// unmappableReplacement is what ReplaceUnmappable writes for runes the
+// Encoding can't represent.
+const unmappableReplacement = '?'
+
+// encodingWriter returns a writer transcoding into c.Encoding on its way to
+// w, or nil if there's no Encoding. It has to be closed once everything has
+// been written to it, which doesn't close w.
+func (c Converter) encodingWriter(w io.Writer) *transform.Writer {
+	if c.Encoding == nil {
+		return nil
+	}
+	var encoder transform.Transformer = c.Encoding.NewEncoder()
+	if c.ReplaceUnmappable {
+		encoder = replaceUnmappable{encoder}
+	}
+	return transform.NewWriter(w, encoder)
+}
+
+// repertoireError is the error x/text encoders fail with when a rune isn't
+// in their character set.
+type repertoireError interface {
+	Replacement() byte
+}
+
+// replaceUnmappable writes unmappableReplacement for each rune its encoder
+// fails on, the way encoding.ReplaceUnsupported does but with a '?' rather
+// than the encoding's own substitute character (often an invisible 0x1A).
+type replaceUnmappable struct {
+	transform.Transformer
+}
+
+func (r replaceUnmappable) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	nDst, nSrc, err = r.Transformer.Transform(dst, src, atEOF)
+	for err != nil {
+		if _, ok := err.(repertoireError); !ok {
+			return nDst, nSrc, err
+		}
+		if nDst == len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		dst[nDst] = unmappableReplacement
+		nDst++
+		_, size := utf8.DecodeRune(src[nSrc:])
+		nSrc += size
+
+		err = nil
+		if nSrc < len(src) {
+			var n, m int
+			n, m, err = r.Transformer.Transform(dst[nDst:], src[nSrc:], atEOF)
+			nDst += n
+			nSrc += m
+		}
+	}
+	return nDst, nSrc, nil
+}
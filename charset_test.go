@@ -0,0 +1,114 @@
+package sqltocsv_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestEncoding(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "Иван Петров", 2, time.Unix(0, 0), "Ваня, \"дружище\"")
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "Zoë Müller", 3, time.Unix(0, 0), "«Zo»")
+
+	for _, test := range []struct {
+		name     string
+		encoding encoding.Encoding
+		replace  bool
+		age      int
+		expected string
+	}{
+		{
+			name:     "windows-1251",
+			encoding: charmap.Windows1251,
+			age:      2,
+			expected: "имя,прозвище\nИван Петров,\"Ваня, \"\"дружище\"\"\"\n",
+		},
+		{
+			name:     "latin-1",
+			encoding: charmap.ISO8859_1,
+			age:      3,
+			expected: "name,nickname\nZoë Müller,«Zo»\n",
+		},
+		{
+			name:     "latin-1 replaced",
+			encoding: charmap.ISO8859_1,
+			replace:  true,
+			age:      2,
+			expected: "name,nickname\n???? ??????,\"????, \"\"???????\"\"\"\n",
+		},
+	} {
+		converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,nickname|age=?", test.age))
+		converter.Encoding = test.encoding
+		converter.ReplaceUnmappable = test.replace
+		if test.encoding == charmap.Windows1251 {
+			converter.Headers = []string{"имя", "прозвище"}
+		}
+
+		actual, err := converter.WriteString()
+		if err != nil {
+			t.Fatalf("%s: error in WriteString: %v", test.name, err)
+		}
+
+		// check the bytes themselves as well as that they decode back
+		expected, err := test.encoding.NewEncoder().String(test.expected)
+		if err != nil {
+			t.Fatalf("%s: error encoding expected output: %v", test.name, err)
+		}
+		if actual != expected {
+			t.Errorf("%s: expected bytes %q, got %q", test.name, expected, actual)
+		}
+		decoded, err := test.encoding.NewDecoder().String(actual)
+		if err != nil {
+			t.Fatalf("%s: error decoding output: %v", test.name, err)
+		}
+		assertCsvMatch(t, test.expected, decoded)
+	}
+}
+
+func TestEncodingUnmappable(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "Иван", 2, time.Unix(0, 0), nil)
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name|"))
+	converter.Encoding = charmap.ISO8859_1
+	if _, err := converter.WriteString(); err == nil {
+		t.Error("expected error for a name Latin-1 can't represent")
+	}
+}
+
+func TestUTF16LE(t *testing.T) {
+	db := setupTypesDatabase(t)
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|types|s,b,i32,i64,t,nt,ns,f,nf,ni,d|"))
+	converter.Encoding = sqltocsv.UTF16LE
+	converter.WriteBOM = true
+
+	actual, stats, err := converter.WriteStringWithStats()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	if !strings.HasPrefix(actual, "\xFF\xFE") || strings.HasPrefix(actual[2:], "\xFF\xFE") {
+		t.Errorf("expected a single UTF-16LE BOM, got %q", actual[:min(len(actual), 6)])
+	}
+	if stats.BytesWritten != int64(len(actual)) {
+		t.Errorf("expected %d bytes written, got %d", len(actual), stats.BytesWritten)
+	}
+
+	decoded, err := unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder().String(actual)
+	if err != nil {
+		t.Fatalf("error decoding %q: %v", actual, err)
+	}
+	golden, err := os.ReadFile(filepath.Join("testdata", "default.golden.csv"))
+	if err != nil {
+		t.Fatalf("error reading the golden file: %v", err)
+	}
+	assertCsvMatch(t, string(golden), decoded)
+}
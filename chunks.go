@@ -0,0 +1,146 @@
+package sqltocsv
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+)
+
+// WriteFileChunks writes the CSV across as many files as needed to hold at
+// most rowsPerFile data rows each. File names are made by passing a 1-based
+// chunk number to fmt.Sprintf(pattern, n), e.g. "export-%04d.csv", and every
+// file starts with its own header row. It returns the names of the files
+// created, including any created before an error occurred, and Chunks
+// describes each of them afterwards. With Atomic set each file only takes
+// its name once it's complete, so a failed export leaves no partial file
+// behind. Each file is written the way Write would write it, with its own
+// Encoding, BufferSize buffer and footers, the footers given the Stats of
+// that file. The SetChecksum digest is of all the files, one after the
+// other. If ManifestFile is set a manifest listing the files is written
+// along with them, see WriteManifest.
+func (c Converter) WriteFileChunks(pattern string, rowsPerFile int) (names []string, err error) {
+	defer c.closeRows(&err)
+
+	if rowsPerFile <= 0 {
+		return nil, fmt.Errorf("rowsPerFile must be positive, got %d", rowsPerFile)
+	}
+	if strings.Contains(fmt.Sprintf(pattern, 1), "%!") {
+		return nil, fmt.Errorf("file name pattern %q must contain a single verb for the chunk number", pattern)
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	p, err := c.newPlan()
+	if err != nil {
+		return nil, err
+	}
+	p.keepValues = c.needsNulls()
+
+	cw := &chunkWriter{c: c, p: p, pattern: pattern, rowsPerFile: rowsPerFile, start: time.Now()}
+	if c.checksum != nil {
+		c.checksum.Reset()
+		defer c.saveChecksum(p)
+		cw.sum = c.checksum
+	}
+	err = c.eachRow(context.Background(), p, cw.write)
+	if err == nil && cw.file == nil && c.WriteEmptyChunk {
+		err = cw.open()
+	}
+	if err != nil {
+		if cw.file != nil {
+			cw.file.abort() // close, but only return/handle the write error
+		}
+		p.last.chunks = cw.chunks
+		return cw.names, err
+	}
+
+	err = cw.close()
+	p.last.chunks = cw.chunks
+	if err == nil && c.ManifestFile != "" {
+		err = c.WriteManifest(c.ManifestFile, cw.chunks)
+	}
+	return cw.names, err
+}
+
+// chunkWriter writes rows to the current chunk file, moving on to a new
+// file once rowsPerFile rows have been written to it.
+type chunkWriter struct {
+	c           Converter
+	p           *plan
+	pattern     string
+	rowsPerFile int
+	start       time.Time
+	sum         hash.Hash // the SetChecksum hash, which every file goes through in turn
+
+	names  []string
+	chunks []ChunkInfo // of the files closed so far
+	file   *outputFile
+	bytes  int64
+	out    *csvOutput
+	rows   int
+}
+
+func (cw *chunkWriter) write(row []string, values []any) error {
+	if cw.file == nil || cw.rows == cw.rowsPerFile {
+		if err := cw.close(); err != nil {
+			return err
+		}
+		if err := cw.open(); err != nil {
+			return err
+		}
+	}
+
+	if err := writeRecord(cw.out.csvWriter, row, values); err != nil {
+		return fmt.Errorf("failed to write data row to csv %w", err)
+	}
+	cw.rows++
+	return nil
+}
+
+func (cw *chunkWriter) open() error {
+	name := fmt.Sprintf(cw.pattern, len(cw.names)+1)
+	f, err := cw.c.createFile(name)
+	if err != nil {
+		return err
+	}
+	cw.names = append(cw.names, name)
+	cw.file = f
+	cw.bytes = 0
+	cw.out = cw.c.newCSVOutput(f, &cw.bytes, cw.sum)
+	cw.rows = 0
+	return cw.c.writePreamble(cw.out.writer, cw.out.csvWriter, cw.p)
+}
+
+func (cw *chunkWriter) close() error {
+	if cw.file == nil {
+		return nil
+	}
+	f := cw.file
+	cw.file = nil
+
+	// each file has its own footer, with the rows and bytes of that file
+	err := cw.c.writeFooters(cw.out.csvWriter, cw.out.flush, func() Stats {
+		stats := cw.p.last.stats
+		stats.RowsWritten = int64(cw.rows)
+		stats.BytesWritten = cw.bytes
+		stats.Duration = time.Since(cw.start)
+		return stats
+	})
+	if err == nil {
+		err = cw.out.flush(true)
+	}
+	if err != nil {
+		f.abort()
+		return err
+	}
+	if err := f.commit(); err != nil {
+		return err
+	}
+	cw.p.last.stats.BytesWritten += cw.bytes
+	cw.chunks = append(cw.chunks, ChunkInfo{Path: f.name, Rows: int64(cw.rows), Bytes: cw.bytes})
+	return nil
+}
@@ -0,0 +1,149 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestWriteFileChunks(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=Bob,age=?,bdate=?,nickname=?", 2, time.Unix(0, 0), nil)
+	exec(t, db, "INSERT|people|name=Carol,age=?,bdate=?,nickname=?", 3, time.Unix(0, 0), nil)
+
+	rows, err := db.Query("SELECT|people|name,age|")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+
+	pattern := filepath.Join(t.TempDir(), "export-%04d.csv")
+	names, err := sqltocsv.New(rows).WriteFileChunks(pattern, 2)
+	if err != nil {
+		t.Fatalf("error in WriteFileChunks: %v", err)
+	}
+
+	if len(names) != 2 {
+		t.Fatalf("expected 2 chunks, got %v", names)
+	}
+	if filepath.Base(names[1]) != "export-0002.csv" {
+		t.Errorf("unexpected chunk name %v", names[1])
+	}
+	assertCsvMatch(t, "name,age\nAlice,1\nBob,2\n", readFile(t, names[0]))
+	assertCsvMatch(t, "name,age\nCarol,3\n", readFile(t, names[1]))
+}
+
+func TestWriteFileChunksEmpty(t *testing.T) {
+	pattern := filepath.Join(t.TempDir(), "export-%d.csv")
+
+	converter := sqltocsv.New(getEmptyTestRows(t))
+	names, err := converter.WriteFileChunks(pattern, 10)
+	if err != nil {
+		t.Fatalf("error in WriteFileChunks: %v", err)
+	}
+	if len(names) != 0 {
+		t.Errorf("expected no chunks, got %v", names)
+	}
+
+	converter = sqltocsv.New(getEmptyTestRows(t))
+	converter.WriteEmptyChunk = true
+	names, err = converter.WriteFileChunks(pattern, 10)
+	if err != nil {
+		t.Fatalf("error in WriteFileChunks: %v", err)
+	}
+	if len(names) != 1 {
+		t.Fatalf("expected a single chunk, got %v", names)
+	}
+	assertCsvMatch(t, "name,age,bdate\n", readFile(t, names[0]))
+}
+
+func TestWriteFileChunksOutputOptions(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		setup    func(c *sqltocsv.Converter)
+		checksum bool
+		expected []string
+	}{
+		{
+			name:     "Encoding",
+			setup:    func(c *sqltocsv.Converter) { c.Encoding = charmap.Windows1252 },
+			expected: []string{"id,name\n1,Zo\xEB\n2,Zo\xEB\n", "id,name\n3,Zo\xEB\n"},
+		},
+		{
+			name: "footers",
+			setup: func(c *sqltocsv.Converter) {
+				c.WriteRowCountFooter = true
+				c.SetFooter(func(stats sqltocsv.Stats) []string {
+					return []string{"TOTAL", strconv.FormatInt(stats.RowsWritten, 10)}
+				})
+			},
+			expected: []string{
+				"id,name\n1,Zoë\n2,Zoë\nTOTAL,2\n__rowcount__,2\n",
+				"id,name\n3,Zoë\nTOTAL,1\n__rowcount__,1\n",
+			},
+		},
+		{
+			name:     "SetChecksum",
+			setup:    func(c *sqltocsv.Converter) { c.SetChecksum(sha256.New()) },
+			checksum: true,
+			expected: []string{"id,name\n1,Zoë\n2,Zoë\n", "id,name\n3,Zoë\n"},
+		},
+		{
+			name:     "BufferSize",
+			setup:    func(c *sqltocsv.Converter) { c.BufferSize = 4 },
+			expected: []string{"id,name\n1,Zoë\n2,Zoë\n", "id,name\n3,Zoë\n"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			converter := sqltocsv.NewFromSource(&sliceSource{
+				columns: []string{"id", "name"},
+				rows:    [][]any{{1, "Zoë"}, {2, "Zoë"}, {3, "Zoë"}},
+			})
+			test.setup(converter)
+
+			names, err := converter.WriteFileChunks(filepath.Join(t.TempDir(), "export-%d.csv"), 2)
+			if err != nil {
+				t.Fatalf("error in WriteFileChunks: %v", err)
+			}
+			if len(names) != len(test.expected) {
+				t.Fatalf("expected %d chunks, got %v", len(test.expected), names)
+			}
+			var all []byte
+			for i, name := range names {
+				data := readFile(t, name)
+				assertCsvMatch(t, test.expected[i], data)
+				if chunk := converter.Chunks()[i]; chunk.Bytes != int64(len(data)) {
+					t.Errorf("expected chunk %d to be %d bytes, got %d", i+1, len(data), chunk.Bytes)
+				}
+				all = append(all, data...)
+			}
+			if digest := sha256.Sum256(all); test.checksum && !bytes.Equal(converter.Checksum(), digest[:]) {
+				t.Errorf("expected the checksum of every chunk, got %x", converter.Checksum())
+			}
+		})
+	}
+}
+
+func TestWriteFileChunksInvalidArguments(t *testing.T) {
+	if _, err := getConverter(t).WriteFileChunks("export-%d.csv", 0); err == nil {
+		t.Error("expected error for zero rowsPerFile")
+	}
+	if _, err := getConverter(t).WriteFileChunks("export.csv", 10); err == nil {
+		t.Error("expected error for pattern without a verb")
+	}
+}
+
+func readFile(t *testing.T, name string) string {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("error reading %v: %v", name, err)
+	}
+	return string(data)
+}
@@ -0,0 +1,185 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+// closingSource is a sliceSource that records how often it's closed.
+type closingSource struct {
+	sliceSource
+	closed   int
+	closeErr error
+}
+
+func (s *closingSource) Close() error {
+	s.closed++
+	return s.closeErr
+}
+
+func newClosingSource() *closingSource {
+	return &closingSource{sliceSource: sliceSource{
+		columns: []string{"id", "name"},
+		rows:    [][]any{{int64(1), "Alice"}, {int64(2), "Bob"}},
+	}}
+}
+
+func TestCloseRows(t *testing.T) {
+	source := newClosingSource()
+	converter := sqltocsv.NewFromSource(source)
+	converter.CloseRows = true
+
+	csv, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	assertCsvMatch(t, "id,name\n1,Alice\n2,Bob\n", csv)
+	if source.closed != 1 {
+		t.Errorf("expected the rows to be closed once, got %d", source.closed)
+	}
+}
+
+func TestCloseRowsOnError(t *testing.T) {
+	source := newClosingSource()
+	source.rows[1] = []any{int64(2)} // fails to scan
+	converter := sqltocsv.NewFromSource(source)
+	converter.CloseRows = true
+
+	if _, err := converter.WriteString(); err == nil {
+		t.Fatal("expected the scan error")
+	}
+	if source.closed != 1 {
+		t.Errorf("expected the rows to be closed once, got %d", source.closed)
+	}
+}
+
+func TestCloseRowsError(t *testing.T) {
+	source := newClosingSource()
+	source.closeErr = errors.New("connection reset")
+	converter := sqltocsv.NewFromSource(source)
+	converter.CloseRows = true
+
+	name := filepath.Join(t.TempDir(), "people.csv")
+	err := converter.WriteFile(name)
+	if !errors.Is(err, source.closeErr) {
+		t.Fatalf("expected the close error, got %v", err)
+	}
+	if source.closed != 1 {
+		t.Errorf("expected the rows to be closed once, got %d", source.closed)
+	}
+	assertCsvMatch(t, "id,name\n1,Alice\n2,Bob\n", readFile(t, name))
+}
+
+func TestCloseRowsOff(t *testing.T) {
+	source := newClosingSource()
+	if _, err := sqltocsv.NewFromSource(source).WriteString(); err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	if source.closed != 0 {
+		t.Errorf("expected the rows to be left open, got %d closes", source.closed)
+	}
+}
+
+func TestWriteFileClosesRows(t *testing.T) {
+	db := setupDatabase(t)
+	rows := queryTestRows(t, db, "SELECT|people|name,age,bdate|")
+
+	// fails before a single row is read
+	name := filepath.Join(t.TempDir(), "missing", "test.csv")
+	if err := sqltocsv.WriteFile(name, rows); err == nil {
+		t.Fatal("expected an error writing to a missing directory")
+	}
+	assertRowsClosed(t, db)
+}
+
+func TestCloseRowsErrorWriters(t *testing.T) {
+	dir := t.TempDir()
+	for name, write := range map[string]func(c *sqltocsv.Converter) error{
+		"WriteGzipFile": func(c *sqltocsv.Converter) error { return c.WriteGzipFile(filepath.Join(dir, "people.csv.gz")) },
+		"WriteZstdFile": func(c *sqltocsv.Converter) error { return c.WriteZstdFile(filepath.Join(dir, "people.csv.zst")) },
+		"WriteZipFile": func(c *sqltocsv.Converter) error {
+			return c.WriteZipFile(filepath.Join(dir, "people.zip"), "people.csv")
+		},
+		"WriteXlsxFile":    func(c *sqltocsv.Converter) error { return c.WriteXlsxFile(filepath.Join(dir, "people.xlsx"), "People") },
+		"WriteParquetFile": func(c *sqltocsv.Converter) error { return c.WriteParquetFile(filepath.Join(dir, "people.parquet")) },
+		"WriteFileChunks": func(c *sqltocsv.Converter) error {
+			_, err := c.WriteFileChunks(filepath.Join(dir, "people-%d.csv"), 1)
+			return err
+		},
+		"WriteMulti":      func(c *sqltocsv.Converter) error { return c.WriteMulti(io.Discard, io.Discard) },
+		"WriteJSONLines":  func(c *sqltocsv.Converter) error { return c.WriteJSONLines(io.Discard) },
+		"WriteHTML":       func(c *sqltocsv.Converter) error { return c.WriteHTML(io.Discard) },
+		"WriteMarkdown":   func(c *sqltocsv.Converter) error { return c.WriteMarkdown(io.Discard) },
+		"WriteSQLInserts": func(c *sqltocsv.Converter) error { return c.WriteSQLInserts(io.Discard, "people") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			source := newClosingSource()
+			source.closeErr = errors.New("connection reset")
+			converter := sqltocsv.NewFromSource(source)
+			converter.CloseRows = true
+
+			if err := write(converter); !errors.Is(err, source.closeErr) {
+				t.Errorf("expected the close error, got %v", err)
+			}
+			if source.closed != 1 {
+				t.Errorf("expected the rows to be closed once, got %d", source.closed)
+			}
+		})
+	}
+}
+
+func TestPackageWritersCloseRows(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	for name, write := range map[string]func(rows *sql.Rows) error{
+		"WriteGzipFile": func(rows *sql.Rows) error { return sqltocsv.WriteGzipFile(filepath.Join(missing, "test.csv.gz"), rows) },
+		"WriteZstdFile": func(rows *sql.Rows) error {
+			return sqltocsv.WriteZstdFile(filepath.Join(missing, "test.csv.zst"), rows)
+		},
+		"WriteTSVFile": func(rows *sql.Rows) error { return sqltocsv.WriteTSVFile(filepath.Join(missing, "test.tsv"), rows) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			db := setupDatabase(t)
+			// fails before a single row is read
+			if err := write(queryTestRows(t, db, "SELECT|people|name,age,bdate|")); err == nil {
+				t.Fatal("expected an error writing to a missing directory")
+			}
+			assertRowsClosed(t, db)
+		})
+	}
+
+	t.Run("WriteTSVString", func(t *testing.T) {
+		db := setupDatabase(t)
+		if _, err := sqltocsv.WriteTSVString(queryTestRows(t, db, "SELECT|people|name,age,bdate|")); err != nil {
+			t.Fatalf("error in WriteTSVString: %v", err)
+		}
+		assertRowsClosed(t, db)
+	})
+
+	t.Run("ServeCSV", func(t *testing.T) {
+		db := setupDatabase(t)
+		rows := queryTestRows(t, db, "SELECT|people|name,age,bdate|")
+		if err := sqltocsv.ServeCSV(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), rows, "people.csv"); err != nil {
+			t.Fatalf("error in ServeCSV: %v", err)
+		}
+		assertRowsClosed(t, db)
+	})
+
+	t.Run("NewReader", func(t *testing.T) {
+		db := setupDatabase(t)
+		r := sqltocsv.NewReader(queryTestRows(t, db, "SELECT|people|name,age,bdate|"))
+		if _, err := io.Copy(&bytes.Buffer{}, r); err != nil {
+			t.Fatalf("error reading: %v", err)
+		}
+		if err := r.Close(); err != nil {
+			t.Fatalf("error closing the reader: %v", err)
+		}
+		assertRowsClosed(t, db)
+	})
+}
@@ -0,0 +1,93 @@
+package sqltocsv
+
+import (
+	"errors"
+	"fmt"
+)
+
+// selectColumns works out which of the query columns end up in the output
+// and in what order, returning their indexes into queryColumns.
+func (c Converter) selectColumns(queryColumns []string) ([]int, error) {
+	if len(c.IncludeColumns) > 0 && len(c.ExcludeColumns) > 0 {
+		return nil, errors.New("IncludeColumns and ExcludeColumns can't both be set")
+	}
+
+	included, err := columnSet("included", c.IncludeColumns, queryColumns)
+	if err != nil {
+		return nil, err
+	}
+	excluded, err := columnSet("excluded", c.ExcludeColumns, queryColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]int, 0, len(queryColumns))
+	for i, name := range queryColumns {
+		if len(included) > 0 && !included[name] {
+			continue
+		}
+		if excluded[name] {
+			continue
+		}
+		selected = append(selected, i)
+	}
+
+	if len(c.ColumnOrder) == 0 {
+		return selected, nil
+	}
+	return c.orderColumns(selected, queryColumns)
+}
+
+// orderColumns rearranges selected to follow ColumnOrder, with any
+// remaining columns appended in query order unless DropUnordered is set.
+func (c Converter) orderColumns(selected []int, queryColumns []string) ([]int, error) {
+	positions := make(map[string]int, len(selected))
+	for _, idx := range selected {
+		positions[queryColumns[idx]] = idx
+	}
+
+	ordered := make([]int, 0, len(selected))
+	placed := make(map[string]bool, len(c.ColumnOrder))
+	for _, name := range c.ColumnOrder {
+		if placed[name] {
+			return nil, fmt.Errorf("column %q appears more than once in ColumnOrder", name)
+		}
+		idx, ok := positions[name]
+		if !ok {
+			return nil, fmt.Errorf("ordered column %q is not in the output", name)
+		}
+		placed[name] = true
+		ordered = append(ordered, idx)
+	}
+
+	if !c.DropUnordered {
+		for _, idx := range selected {
+			if !placed[queryColumns[idx]] {
+				ordered = append(ordered, idx)
+			}
+		}
+	}
+	return ordered, nil
+}
+
+// columnSet turns names into a set, erroring on any name that isn't one of
+// the query columns. kind is only used to make the error readable.
+func columnSet(kind string, names []string, queryColumns []string) (map[string]bool, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]bool, len(queryColumns))
+	for _, name := range queryColumns {
+		known[name] = true
+	}
+
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !known[name] {
+			return nil, fmt.Errorf("%s column %q is not in the result set", kind, name)
+		}
+		set[name] = true
+	}
+	return set, nil
+}
@@ -0,0 +1,89 @@
+package sqltocsv_test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIncludeColumns(t *testing.T) {
+	converter := getConverter(t)
+	converter.IncludeColumns = []string{"bdate", "name"}
+
+	var seenColumns []string
+	converter.SetRowPreProcessor(func(row []string, columnNames []string) (bool, []string) {
+		seenColumns = columnNames
+		return true, row
+	})
+
+	expected := "name,bdate\nAlice,1973-11-29T21:33:09Z\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+	if strings.Join(seenColumns, ",") != "name,bdate" {
+		t.Errorf("preprocessor saw columns %v", seenColumns)
+	}
+}
+
+func TestExcludeColumns(t *testing.T) {
+	converter := getConverter(t)
+	converter.ExcludeColumns = []string{"age"}
+
+	expected := "name,bdate\nAlice,1973-11-29T21:33:09Z\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+}
+
+func TestColumnSelectionErrors(t *testing.T) {
+	converter := getConverter(t)
+	converter.IncludeColumns = []string{"name"}
+	converter.ExcludeColumns = []string{"age"}
+	if _, err := converter.WriteString(); err == nil {
+		t.Error("expected error when both IncludeColumns and ExcludeColumns are set")
+	}
+
+	converter = getConverter(t)
+	converter.ExcludeColumns = []string{"nope"}
+	csv, err := converter.WriteString()
+	if err == nil || !strings.Contains(err.Error(), `"nope"`) {
+		t.Errorf("expected unknown column error, got %v", err)
+	}
+	if csv != "" {
+		t.Errorf("expected no output, got %q", csv)
+	}
+}
+
+func TestColumnOrder(t *testing.T) {
+	converter := getConverter(t)
+	converter.ColumnOrder = []string{"bdate", "name"}
+
+	expected := "bdate,name,age\n1973-11-29T21:33:09Z,Alice,1\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+}
+
+func TestColumnOrderDropUnordered(t *testing.T) {
+	converter := getConverter(t)
+	converter.ColumnOrder = []string{"age", "name"}
+	converter.DropUnordered = true
+
+	expected := "age,name\n1,Alice\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+}
+
+func TestColumnOrderDuplicate(t *testing.T) {
+	converter := getConverter(t)
+	converter.WriteBOM = true
+	converter.ColumnOrder = []string{"age", "age"}
+
+	csv, err := converter.WriteString()
+	if err == nil || !strings.Contains(err.Error(), "more than once") {
+		t.Errorf("expected duplicate column error, got %v", err)
+	}
+	if csv != "" {
+		t.Errorf("expected no output, got %q", csv)
+	}
+}
@@ -0,0 +1,112 @@
+package sqltocsv
+
+import (
+	"database/sql"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	scannerType     = reflect.TypeFor[sql.Scanner]()
+	nullStringType  = reflect.TypeFor[sql.NullString]()
+	nullInt64Type   = reflect.TypeFor[sql.NullInt64]()
+	nullUint64Type  = reflect.TypeFor[sql.Null[uint64]]()
+	nullFloat64Type = reflect.TypeFor[sql.NullFloat64]()
+	nullBoolType    = reflect.TypeFor[sql.NullBool]()
+	nullTimeType    = reflect.TypeFor[sql.NullTime]()
+	timeType        = reflect.TypeFor[time.Time]()
+)
+
+// scanTypeFor picks what UseColumnTypes scans a column into from what the
+// driver reports about it, or nil to scan it into any as usual. Base types
+// are scanned through their sql.Null* counterpart so that NULLs survive, and
+// DECIMAL and NUMERIC columns are kept as strings, as converting them to
+// float64 could lose precision. So are INTERVAL columns, which PostgreSQL
+// drivers give as text, so that they're written as they are whatever the
+// BinaryConverter.
+func scanTypeFor(columnType *sql.ColumnType) reflect.Type {
+	databaseType := strings.ToUpper(columnType.DatabaseTypeName())
+	if strings.Contains(databaseType, "DECIMAL") || strings.Contains(databaseType, "NUMERIC") {
+		return nullStringType
+	}
+	if databaseType == "INTERVAL" {
+		return nullStringType
+	}
+
+	scanType := columnType.ScanType()
+	if scanType == nil {
+		return nil
+	}
+	if scanType == timeType {
+		return nullTimeType
+	}
+	switch scanType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return nullInt64Type
+	case reflect.Uint, reflect.Uint64:
+		// too big for an int64 above math.MaxInt64
+		return nullUint64Type
+	case reflect.Float32, reflect.Float64:
+		return nullFloat64Type
+	case reflect.Bool:
+		return nullBoolType
+	case reflect.String:
+		return nullStringType
+	}
+	// types like sql.NullInt64 or a driver's own NullTime can be used as
+	// they are
+	if scanType.Kind() != reflect.Interface && reflect.PointerTo(scanType).Implements(scannerType) {
+		return scanType
+	}
+	return nil
+}
+
+// scanPointers fills valuePtrs with the destinations to pass to rows.Scan:
+// a pointer into values for columns scanned into any, or a new value of the
+// column's scan type.
+func (p *plan) scanPointers(values, valuePtrs []any) {
+	for i := range values {
+		if p.scanTypes == nil || p.scanTypes[i] == nil {
+			valuePtrs[i] = &values[i]
+			continue
+		}
+		valuePtrs[i] = reflect.New(p.scanTypes[i]).Interface()
+	}
+}
+
+// collectScanned copies the values scanned into the typed destinations made
+// by scanPointers into values, unwrapping any sql.Null* types so that column
+// formatters and preprocessors see nil or a plain value as they usually do.
+func (p *plan) collectScanned(values, valuePtrs []any) {
+	for i, t := range p.scanTypes {
+		if t != nil {
+			values[i] = unwrapNull(reflect.ValueOf(valuePtrs[i]).Elem().Interface())
+		}
+	}
+}
+
+// keepIntervalText converts the INTERVAL columns of rows that report their
+// column types with String whatever their BinaryConverter, as UseColumnTypes
+// does by scanning them into strings, for when it isn't set. The types are
+// only asked for when a column has another BinaryConverter.
+func (p *plan) keepIntervalText(c Converter) {
+	if !slices.ContainsFunc(p.perColumn, func(column Converter) bool { return column.BinaryConverter != String }) {
+		return
+	}
+	typer, ok := c.rows.(columnTyper)
+	if !ok {
+		return
+	}
+	columnTypes, err := typer.ColumnTypes()
+	if err != nil {
+		return // they're converted as any other []byte then
+	}
+	for i, n := range p.selected {
+		if n < len(columnTypes) && strings.ToUpper(columnTypes[n].DatabaseTypeName()) == "INTERVAL" {
+			p.perColumn[i].BinaryConverter = String
+		}
+	}
+}
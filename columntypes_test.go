@@ -0,0 +1,164 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+// typedColumn describes a column of a typedConnector result set.
+type typedColumn struct {
+	name         string
+	databaseType string
+	scanType     reflect.Type
+}
+
+// typedConnector opens connections whose queries return rows, with column
+// types, the way a driver like MySQL's does: text and numbers alike come back
+// as []byte. If generate is set the query instead returns count rows made by
+// it, one at a time. Any resultSets follow as further result sets.
+type typedConnector struct {
+	columns    []typedColumn
+	rows       [][]driver.Value
+	count      int
+	generate   func(i int, dest []driver.Value)
+	resultSets []typedConnector
+}
+
+func (c typedConnector) Connect(context.Context) (driver.Conn, error) { return typedConn{c}, nil }
+func (c typedConnector) Driver() driver.Driver                        { return nil }
+
+type typedConn struct{ typedConnector }
+
+func (c typedConn) Prepare(string) (driver.Stmt, error) { return typedStmt{c.typedConnector}, nil }
+func (c typedConn) Close() error                        { return nil }
+func (c typedConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+type typedStmt struct{ typedConnector }
+
+func (s typedStmt) Close() error                               { return nil }
+func (s typedStmt) NumInput() int                              { return 0 }
+func (s typedStmt) Exec([]driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (s typedStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &typedRows{typedConnector: s.typedConnector}, nil
+}
+
+type typedRows struct {
+	typedConnector
+	next int
+}
+
+func (r *typedRows) Columns() []string {
+	names := make([]string, len(r.columns))
+	for i, column := range r.columns {
+		names[i] = column.name
+	}
+	return names
+}
+
+func (r *typedRows) ColumnTypeDatabaseTypeName(i int) string { return r.columns[i].databaseType }
+func (r *typedRows) ColumnTypeScanType(i int) reflect.Type   { return r.columns[i].scanType }
+func (r *typedRows) Close() error                            { return nil }
+func (r *typedRows) Next(dest []driver.Value) error {
+	if r.generate != nil {
+		if r.next == r.count {
+			return io.EOF
+		}
+		r.generate(r.next, dest)
+		r.next++
+		return nil
+	}
+	if r.next == len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
+
+func (r *typedRows) HasNextResultSet() bool { return len(r.resultSets) > 0 }
+func (r *typedRows) NextResultSet() error {
+	if len(r.resultSets) == 0 {
+		return io.EOF
+	}
+	next := r.resultSets[0]
+	next.resultSets = r.resultSets[1:]
+	*r = typedRows{typedConnector: next}
+	return nil
+}
+
+func queryTypedRows(t *testing.T) *sql.Rows {
+	db := sql.OpenDB(typedConnector{
+		columns: []typedColumn{
+			{"id", "BIGINT", reflect.TypeFor[int64]()},
+			{"ratio", "DOUBLE", reflect.TypeFor[float64]()},
+			{"name", "VARCHAR", reflect.TypeFor[sql.RawBytes]()},
+			{"price", "DECIMAL", reflect.TypeFor[sql.RawBytes]()},
+			{"active", "BOOL", reflect.TypeFor[bool]()},
+			{"created", "DATETIME", reflect.TypeFor[time.Time]()},
+			{"score", "INT", reflect.TypeFor[sql.NullInt64]()},
+		},
+		rows: [][]driver.Value{
+			{[]byte("1"), []byte("0.5"), []byte("Alice"), []byte("12345678901234567890.123456789"), []byte("1"), time.Unix(0, 0).UTC(), []byte("10")},
+			{[]byte("2"), nil, nil, nil, nil, nil, nil},
+		},
+	})
+	t.Cleanup(func() { db.Close() })
+
+	rows, err := db.Query("SELECT")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+	t.Cleanup(func() { rows.Close() })
+	return rows
+}
+
+func TestUseColumnTypes(t *testing.T) {
+	converter := sqltocsv.New(queryTypedRows(t))
+	converter.UseColumnTypes = true
+	converter.FloatFormat = "%.2f"
+	converter.TrueString = "yes"
+	converter.NullString = "NULL"
+
+	expected := "id,ratio,name,price,active,created,score\n" +
+		"1,0.50,Alice,12345678901234567890.123456789,yes,1970-01-01T00:00:00Z,10\n" +
+		"2,NULL,NULL,NULL,NULL,NULL,NULL\n"
+	assertCsvMatch(t, expected, converter.String())
+}
+
+func TestUseColumnTypesOff(t *testing.T) {
+	converter := sqltocsv.New(queryTypedRows(t))
+	converter.FloatFormat = "%.2f"
+	converter.TrueString = "yes"
+
+	// without column types the []byte values are written as they are
+	expected := "id,ratio,name,price,active,created,score\n" +
+		"1,0.5,Alice,12345678901234567890.123456789,1,1970-01-01T00:00:00Z,10\n" +
+		"2,,,,,,\n"
+	assertCsvMatch(t, expected, converter.String())
+}
+
+func TestUseColumnTypesJSONLines(t *testing.T) {
+	converter := sqltocsv.New(queryTypedRows(t))
+	converter.UseColumnTypes = true
+	converter.Pipelined = true
+	converter.IncludeColumns = []string{"id", "ratio", "price", "active"}
+
+	var out bytes.Buffer
+	if err := converter.WriteJSONLines(&out); err != nil {
+		t.Fatalf("error in WriteJSONLines: %v", err)
+	}
+
+	expected := `{"id":1,"ratio":0.5,"price":"12345678901234567890.123456789","active":true}` + "\n" +
+		`{"id":2,"ratio":null,"price":null,"active":null}` + "\n"
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
@@ -0,0 +1,25 @@
+package sqltocsv
+
+import "io"
+
+// WriteCopyText writes the rows in the text format of PostgreSQL's COPY,
+// which loads faster than CSV, ready to be piped into COPY table FROM STDIN:
+// tab separated, without a header row, NULLs as \N and with backslash,
+// tab, newline and carriage return escaped as \\, \t, \n and \r. Other
+// options apply as they do to Write. As the format has a layout of its
+// own, a Dialect set with ApplyDialect is dropped along with the options it
+// set, such as WriteBOM.
+func (c Converter) WriteCopyText(w io.Writer) error {
+	if c.dialect != 0 {
+		c.clearDialect()
+	}
+	c.Delimiter = '\t'
+	c.DelimiterString = ""
+	c.EscapeMode = true
+	c.EscapeChar = '\\'
+	c.copyText = true
+	c.NullString = `\N`
+	c.WriteHeaders = false
+	c.UseCRLF = false
+	return c.Write(w)
+}
@@ -0,0 +1,104 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+// parseCopyText reads data the way PostgreSQL's COPY ... FROM reads its
+// text format: a line per row, tab separated fields, \N on its own for NULL
+// (returned as nil) and backslash escapes.
+func parseCopyText(t *testing.T, data string) [][]*string {
+	t.Helper()
+	if !strings.HasSuffix(data, "\n") {
+		t.Fatalf("unterminated row in %q", data)
+	}
+	var rows [][]*string
+	for _, line := range strings.Split(strings.TrimSuffix(data, "\n"), "\n") {
+		var row []*string
+		for _, field := range strings.Split(line, "\t") {
+			if field == `\N` {
+				row = append(row, nil)
+				continue
+			}
+			var value strings.Builder
+			for i := 0; i < len(field); i++ {
+				if field[i] != '\\' {
+					value.WriteByte(field[i])
+					continue
+				}
+				i++
+				if i == len(field) {
+					t.Fatalf("trailing backslash in %q", line)
+				}
+				switch field[i] {
+				case 'b':
+					value.WriteByte('\b')
+				case 'f':
+					value.WriteByte('\f')
+				case 'n':
+					value.WriteByte('\n')
+				case 'r':
+					value.WriteByte('\r')
+				case 't':
+					value.WriteByte('\t')
+				case 'v':
+					value.WriteByte('\v')
+				default:
+					value.WriteByte(field[i])
+				}
+			}
+			s := value.String()
+			row = append(row, &s)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestWriteCopyText(t *testing.T) {
+	values := []any{`back\slash`, "carriage\rreturn", "crlf\r\n", "tab\tnewline\n", `\N`, `\.`, "", nil}
+	rows := make([][]any, len(values))
+	for i, value := range values {
+		rows[i] = []any{int64(i), value}
+	}
+	converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"id", "value"}, rows: rows})
+
+	var out bytes.Buffer
+	if err := converter.WriteCopyText(&out); err != nil {
+		t.Fatalf("error in WriteCopyText: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "0\tback\\\\slash\n1\tcarriage\\rreturn\n2\tcrlf\\r\\n\n3\ttab\\tnewline\\n\n") {
+		t.Errorf("unexpected escaping in %q", out.String())
+	}
+
+	parsed := parseCopyText(t, out.String())
+	if len(parsed) != len(values) {
+		t.Fatalf("expected %d rows, got %d from %q", len(values), len(parsed), out.String())
+	}
+	for i, value := range values {
+		var expected *string
+		if value != nil {
+			s := value.(string)
+			expected = &s
+		}
+		if !reflect.DeepEqual(parsed[i][1], expected) {
+			t.Errorf("row %d: expected %v, got %v in %q", i, value, parsed[i][1], out.String())
+		}
+	}
+}
+
+func TestWriteCopyTextHeaders(t *testing.T) {
+	converter := getConverter(t)
+	converter.WriteHeaders = true
+
+	var out bytes.Buffer
+	if err := converter.WriteCopyText(&out); err != nil {
+		t.Fatalf("error in WriteCopyText: %v", err)
+	}
+	assertCsvMatch(t, "Alice\t1\t1973-11-29T21:33:09Z\n", out.String())
+}
@@ -0,0 +1,108 @@
+package sqltocsv
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// decimalSeparator returns the DecimalSeparator, reporting false if it
+// isn't set or is a decimal point anyway.
+func (c Converter) decimalSeparator() (rune, bool) {
+	if c.DecimalSeparator == 0 || c.DecimalSeparator == '.' {
+		return 0, false
+	}
+	return c.DecimalSeparator, true
+}
+
+// appendDecimal replaces the decimal point in the number appended to dst
+// from start with the DecimalSeparator, if one is set.
+func (c Converter) appendDecimal(dst []byte, start int) []byte {
+	sep, ok := c.decimalSeparator()
+	if !ok {
+		return dst
+	}
+	i := bytes.IndexByte(dst[start:], '.')
+	if i < 0 {
+		return dst
+	}
+	i += start
+	if sep < utf8.RuneSelf {
+		dst[i] = byte(sep)
+		return dst
+	}
+	tail := append([]byte(nil), dst[i+1:]...)
+	return append(utf8.AppendRune(dst[:i], sep), tail...)
+}
+
+// decimalText is appendDecimal for a number that has been formatted into a
+// string of its own.
+func (c Converter) decimalText(s string) string {
+	if sep, ok := c.decimalSeparator(); ok {
+		return strings.Replace(s, ".", string(sep), 1)
+	}
+	return s
+}
+
+// isNumericText reports whether s is a number as a database would write a
+// DECIMAL or float as text: a sign, digits with at most one decimal point
+// among them, and an exponent.
+func isNumericText(s string) bool {
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		s = s[1:]
+	}
+	digits, point := 0, false
+	for s != "" {
+		switch ch := s[0]; {
+		case ch >= '0' && ch <= '9':
+			digits++
+		case ch == '.' && !point:
+			point = true
+		case (ch == 'e' || ch == 'E') && digits > 0:
+			return isExponent(s[1:])
+		default:
+			return false
+		}
+		s = s[1:]
+	}
+	return digits > 0
+}
+
+// isExponent reports whether s is the exponent of a number, after the e.
+func isExponent(s string) bool {
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		s = s[1:]
+	}
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDecimalSeparator checks the DecimalSeparator can be told apart from
+// the rest of the CSV. One that is part of the delimiter, as a comma is by
+// default, needs QuoteAll so that spreadsheets don't split numbers in two on
+// seeing it unquoted.
+func (c Converter) checkDecimalSeparator() error {
+	sep, ok := c.decimalSeparator()
+	if !ok {
+		return nil
+	}
+	switch {
+	case sep == utf8.RuneError || !utf8.ValidRune(sep):
+		return fmt.Errorf("invalid DecimalSeparator: %q is an invalid rune", sep)
+	case sep == '\r' || sep == '\n':
+		return fmt.Errorf("invalid DecimalSeparator: %q can't be a carriage return or newline", sep)
+	case sep == c.quote():
+		return fmt.Errorf("invalid DecimalSeparator: %q is the quote character", sep)
+	case strings.ContainsRune(c.delimiter(), sep) && !c.QuoteAll:
+		return fmt.Errorf("DecimalSeparator %q is part of the delimiter %q, which needs QuoteAll", sep, c.delimiter())
+	}
+	return nil
+}
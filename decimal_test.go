@@ -0,0 +1,74 @@
+package sqltocsv_test
+
+import (
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestDecimalSeparator(t *testing.T) {
+	rows := [][]any{
+		{3.14, float32(-0.5), 42.0, "1.5", []byte("2.25")},
+	}
+	for i, test := range []struct {
+		delimiter   rune
+		separator   rune
+		floatFormat string
+		expected    string
+	}{
+		{delimiter: ';', separator: ',', expected: "f64;f32;whole;text;bytes\n3,14;-0,5;42;1.5;2.25\n"},
+		{delimiter: ';', separator: ',', floatFormat: "%.2f", expected: "f64;f32;whole;text;bytes\n3,14;-0,50;42,00;1.5;2.25\n"},
+		{delimiter: '\t', separator: '٫', expected: "f64\tf32\twhole\ttext\tbytes\n3٫14\t-0٫5\t42\t1.5\t2.25\n"},
+		{delimiter: ';', separator: '.', expected: "f64;f32;whole;text;bytes\n3.14;-0.5;42;1.5;2.25\n"},
+	} {
+		converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"f64", "f32", "whole", "text", "bytes"}, rows: rows})
+		converter.Delimiter = test.delimiter
+		converter.DecimalSeparator = test.separator
+		converter.FloatFormat = test.floatFormat
+		csv, err := converter.WriteString()
+		if err != nil {
+			t.Fatalf("%d: error in WriteString: %v", i, err)
+		}
+		assertCsvMatch(t, test.expected, csv)
+	}
+}
+
+func TestDecimalColumns(t *testing.T) {
+	rows := [][]any{
+		{[]byte("1234.50"), "-1.5e3", "v1.2", []byte("3.75")},
+		{[]byte("12"), "1.2.3", "", nil},
+	}
+	converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"amount", "ratio", "version", "other"}, rows: rows})
+	converter.Delimiter = ';'
+	converter.DecimalSeparator = ','
+	converter.DecimalColumns = []string{"amount", "ratio", "version"}
+
+	expected := "amount;ratio;version;other\n1234,50;-1,5e3;v1.2;3.75\n12;1.2.3;;\n"
+	csv, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	assertCsvMatch(t, expected, csv)
+}
+
+func TestDecimalSeparatorDelimiter(t *testing.T) {
+	converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"price"}, rows: [][]any{{4.5}}})
+	converter.DecimalSeparator = ','
+	if _, err := converter.WriteString(); err == nil {
+		t.Fatal("expected an error for a DecimalSeparator that is the delimiter")
+	}
+
+	converter.QuoteAll = true
+	csv, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString with QuoteAll: %v", err)
+	}
+	assertCsvMatch(t, "\"price\"\n\"4,5\"\n", csv)
+
+	for _, separator := range []rune{'"', '\n', -1} {
+		converter.DecimalSeparator = separator
+		if err := converter.Validate(); err == nil {
+			t.Errorf("expected an error for DecimalSeparator %q", separator)
+		}
+	}
+}
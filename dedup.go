@@ -0,0 +1,54 @@
+package sqltocsv
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"slices"
+)
+
+// deduplicator spots rows that SkipDuplicateRows or DedupAllRows drop.
+type deduplicator struct {
+	previous []string // the last row written, for SkipDuplicateRows
+
+	// hashes of every row written, for DedupAllRows
+	seen map[[sha256.Size]byte]struct{}
+	buf  []byte
+}
+
+// newDeduplicator returns a deduplicator for the Converter's settings, or nil
+// if rows aren't being deduplicated.
+func (c Converter) newDeduplicator() *deduplicator {
+	switch {
+	case c.DedupAllRows:
+		return &deduplicator{seen: make(map[[sha256.Size]byte]struct{})}
+	case c.SkipDuplicateRows:
+		return &deduplicator{}
+	}
+	return nil
+}
+
+// duplicate reports whether row should be dropped, and otherwise records it
+// as written.
+func (d *deduplicator) duplicate(row []string) bool {
+	if d.seen == nil {
+		if d.previous != nil && slices.Equal(row, d.previous) {
+			return true
+		}
+		d.previous = append(d.previous[:0], row...)
+		return false
+	}
+
+	// each field is length prefixed so that ["ab", "c"] and ["a", "bc"]
+	// don't hash the same
+	d.buf = binary.AppendUvarint(d.buf[:0], uint64(len(row)))
+	for _, field := range row {
+		d.buf = binary.AppendUvarint(d.buf, uint64(len(field)))
+		d.buf = append(d.buf, field...)
+	}
+	sum := sha256.Sum256(d.buf)
+	if _, ok := d.seen[sum]; ok {
+		return true
+	}
+	d.seen[sum] = struct{}{}
+	return false
+}
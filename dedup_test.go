@@ -0,0 +1,72 @@
+package sqltocsv_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func getDuplicateRowsConverter(t *testing.T) *sqltocsv.Converter {
+	db := setupDatabase(t)
+	for _, name := range []string{"Alice", "Alice", "Bob", "Bob", "Bob", "Alice", "alice"} {
+		exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", name, 1, time.Unix(0, 0), nil)
+	}
+	return sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age|"))
+}
+
+func TestSkipDuplicateRows(t *testing.T) {
+	converter := getDuplicateRowsConverter(t)
+	converter.SkipDuplicateRows = true
+	converter.RowNumberHeader = "#"
+
+	csv, stats, err := converter.WriteStringWithStats()
+	if err != nil {
+		t.Fatalf("error in WriteStringWithStats: %v", err)
+	}
+	// only runs are dropped, and the row number doesn't stop them matching
+	expected := "#,name,age\n1,Alice,1\n2,Bob,1\n3,Alice,1\n4,alice,1\n"
+	assertCsvMatch(t, expected, csv)
+	if stats.DuplicateRows != 4 || stats.RowsWritten != 4 {
+		t.Errorf("expected 4 rows written and 4 duplicates, got %+v", stats)
+	}
+}
+
+func TestDedupAllRows(t *testing.T) {
+	converter := getDuplicateRowsConverter(t)
+	converter.DedupAllRows = true
+
+	csv, stats, err := converter.WriteStringWithStats()
+	if err != nil {
+		t.Fatalf("error in WriteStringWithStats: %v", err)
+	}
+	assertCsvMatch(t, "name,age\nAlice,1\nBob,1\nalice,1\n", csv)
+	if stats.DuplicateRows != 5 {
+		t.Errorf("expected 5 duplicates, got %d", stats.DuplicateRows)
+	}
+}
+
+func TestDedupAfterPreProcessor(t *testing.T) {
+	converter := getDuplicateRowsConverter(t)
+	converter.SkipDuplicateRows = true
+	converter.SetRowPreProcessor(func(row []string, _ []string) (bool, []string) {
+		// fields that differ before the preprocessor but not after
+		return true, []string{row[0][:1] + "…", row[1]}
+	})
+
+	assertCsvMatch(t, "name,age\nA…,1\nB…,1\nA…,1\na…,1\n", converter.String())
+}
+
+func TestDedupAllRowsFieldBoundaries(t *testing.T) {
+	converter := getDuplicateRowsConverter(t)
+	converter.DedupAllRows = true
+	converter.SetRowPreProcessor(func(row []string, _ []string) (bool, []string) {
+		// the same text split differently isn't a duplicate
+		if row[0] == "Bob" {
+			return true, []string{"Al", "ice1"}
+		}
+		return true, []string{"Alice", "1"}
+	})
+
+	assertCsvMatch(t, "name,age\nAlice,1\nAl,ice1\n", converter.String())
+}
@@ -0,0 +1,134 @@
+package sqltocsv
+
+import "fmt"
+
+// Dialect is a preset of the options that decide how the CSV is laid out,
+// for ApplyDialect.
+type Dialect int
+
+const (
+	// RFC 4180: comma delimited, \r\n line endings, fields quoted with
+	// double quotes only when they need it, and nothing before the header
+	// row.
+	DialectRFC4180 Dialect = iota + 1
+	// Excel in the locales that write decimals with a comma: semicolon
+	// delimited, \r\n line endings, a UTF-8 byte order mark so that Excel
+	// reads the file as UTF-8, and a DecimalSeparator of ','.
+	DialectExcelEU
+	// Comma delimited with \n line endings.
+	DialectUnix
+	// Tab separated values as IANA registers them: tab delimited, \n line
+	// endings and fields never quoted, with tabs and line breaks in values
+	// replaced by a space.
+	DialectTSVStrict
+)
+
+var dialectNames = map[Dialect]string{
+	DialectRFC4180:   "DialectRFC4180",
+	DialectExcelEU:   "DialectExcelEU",
+	DialectUnix:      "DialectUnix",
+	DialectTSVStrict: "DialectTSVStrict",
+}
+
+func (d Dialect) String() string {
+	if name, ok := dialectNames[d]; ok {
+		return name
+	}
+	return fmt.Sprintf("Dialect(%d)", int(d))
+}
+
+// ApplyDialect sets the delimiter, line endings, quoting, byte order mark
+// and DecimalSeparator options to those of the Dialect, leaving the rest as
+// they are. They can still be changed afterwards, but Validate, and so
+// Write, fails if a change breaks the Dialect, such as a Delimiter of ','
+// for DialectTSVStrict.
+func (c *Converter) ApplyDialect(d Dialect) {
+	c.clearDialect()
+	c.dialect = d
+
+	switch d {
+	case DialectRFC4180:
+		c.UseCRLF = true
+	case DialectExcelEU:
+		c.Delimiter = ';'
+		c.UseCRLF = true
+		c.WriteBOM = true
+		c.DecimalSeparator = ','
+	case DialectTSVStrict:
+		c.Delimiter = '\t'
+		c.UnquotedOutput = true
+		c.UnquotedStrategy = UnquotedReplace
+		c.UnquotedReplacement = " "
+	}
+}
+
+// clearDialect drops the Dialect, putting the options ApplyDialect sets back
+// to those of New.
+func (c *Converter) clearDialect() {
+	c.dialect = 0
+
+	c.Delimiter = ','
+	c.DelimiterString = ""
+	c.QuoteChar = 0
+	c.QuoteAll = false
+	c.EscapeMode = false
+	c.UnquotedOutput = false
+	c.UseCRLF = false
+	c.WriteBOM = false
+	c.WriteSepHint = false
+	c.DecimalSeparator = 0
+}
+
+// checkDialect returns an error if an option has been changed since
+// ApplyDialect in a way that breaks the Dialect.
+func (c Converter) checkDialect() error {
+	var needs string
+	switch c.dialect {
+	case 0:
+		return nil
+	case DialectRFC4180:
+		switch {
+		case c.delimiter() != ",":
+			needs = "a comma Delimiter"
+		case !c.UseCRLF:
+			needs = "UseCRLF"
+		case c.quote() != '"' || c.EscapeMode || c.UnquotedOutput:
+			needs = "fields quoted with double quotes, without EscapeMode or UnquotedOutput"
+		case c.WriteBOM || c.WriteSepHint || len(c.PrologueLines) > 0:
+			needs = "nothing before the header row, without WriteBOM, WriteSepHint or PrologueLines"
+		}
+	case DialectExcelEU:
+		switch {
+		case c.delimiter() != ";":
+			needs = "a semicolon Delimiter"
+		case !c.UseCRLF:
+			needs = "UseCRLF"
+		case !c.WriteBOM || c.WriteSepHint:
+			needs = "WriteBOM, without WriteSepHint"
+		case c.EscapeMode || c.UnquotedOutput:
+			needs = "quoted fields, without EscapeMode or UnquotedOutput"
+		}
+	case DialectUnix:
+		switch {
+		case c.delimiter() != ",":
+			needs = "a comma Delimiter"
+		case c.UseCRLF:
+			needs = "UseCRLF unset"
+		}
+	case DialectTSVStrict:
+		switch {
+		case c.delimiter() != "\t":
+			needs = "a tab Delimiter"
+		case c.UseCRLF:
+			needs = "UseCRLF unset"
+		case !c.UnquotedOutput || c.QuoteAll:
+			needs = "UnquotedOutput, without QuoteAll"
+		}
+	default:
+		return fmt.Errorf("unknown Dialect %d", int(c.dialect))
+	}
+	if needs != "" {
+		return fmt.Errorf("options changed since ApplyDialect break %v, which needs %s", c.dialect, needs)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func dialectConverter(d sqltocsv.Dialect) *sqltocsv.Converter {
+	converter := sqltocsv.NewFromSource(&sliceSource{
+		columns: []string{"name", "note", "price"},
+		rows:    [][]any{{"a,b;c", "tab\there \"quoted\"", 1.5}},
+	})
+	converter.ApplyDialect(d)
+	return converter
+}
+
+func TestApplyDialect(t *testing.T) {
+	for _, test := range []struct {
+		dialect  sqltocsv.Dialect
+		expected string
+	}{
+		{sqltocsv.DialectRFC4180, "name,note,price\r\n\"a,b;c\",\"tab\there \"\"quoted\"\"\",1.5\r\n"},
+		{sqltocsv.DialectExcelEU, "\xEF\xBB\xBFname;note;price\r\n\"a,b;c\";\"tab\there \"\"quoted\"\"\";1,5\r\n"},
+		{sqltocsv.DialectUnix, "name,note,price\n\"a,b;c\",\"tab\there \"\"quoted\"\"\",1.5\n"},
+		{sqltocsv.DialectTSVStrict, "name\tnote\tprice\na,b;c\ttab here \"quoted\"\t1.5\n"},
+	} {
+		t.Run(test.dialect.String(), func(t *testing.T) {
+			converter := dialectConverter(test.dialect)
+			converter.NullString = `\N` // doesn't break any of them
+			csv, err := converter.WriteString()
+			if err != nil {
+				t.Fatalf("error in WriteString: %v", err)
+			}
+			assertCsvMatch(t, test.expected, csv)
+		})
+	}
+}
+
+func TestApplyDialectOverrides(t *testing.T) {
+	converter := dialectConverter(sqltocsv.DialectTSVStrict)
+	converter.ApplyDialect(sqltocsv.DialectUnix)
+	csv, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	assertCsvMatch(t, "name,note,price\n\"a,b;c\",\"tab\there \"\"quoted\"\"\",1.5\n", csv)
+}
+
+func TestDialectConflicts(t *testing.T) {
+	for _, test := range []struct {
+		dialect sqltocsv.Dialect
+		change  func(c *sqltocsv.Converter)
+	}{
+		{sqltocsv.DialectRFC4180, func(c *sqltocsv.Converter) { c.Delimiter = ';' }},
+		{sqltocsv.DialectRFC4180, func(c *sqltocsv.Converter) { c.UseCRLF = false }},
+		{sqltocsv.DialectRFC4180, func(c *sqltocsv.Converter) { c.QuoteChar = '\'' }},
+		{sqltocsv.DialectRFC4180, func(c *sqltocsv.Converter) { c.WriteBOM = true }},
+		{sqltocsv.DialectExcelEU, func(c *sqltocsv.Converter) { c.DelimiterString = ";;" }},
+		{sqltocsv.DialectExcelEU, func(c *sqltocsv.Converter) { c.WriteSepHint = true }},
+		{sqltocsv.DialectUnix, func(c *sqltocsv.Converter) { c.UseCRLF = true }},
+		{sqltocsv.DialectTSVStrict, func(c *sqltocsv.Converter) { c.Delimiter = ',' }},
+		{sqltocsv.DialectTSVStrict, func(c *sqltocsv.Converter) { c.UnquotedOutput = false }},
+	} {
+		converter := dialectConverter(test.dialect)
+		if err := converter.Validate(); err != nil {
+			t.Fatalf("%v: expected the dialect to be valid, got %v", test.dialect, err)
+		}
+		test.change(converter)
+		err := converter.Validate()
+		if err == nil || !strings.Contains(err.Error(), test.dialect.String()) {
+			t.Errorf("%v: expected an error naming the dialect, got %v", test.dialect, err)
+		}
+	}
+
+	converter := dialectConverter(sqltocsv.Dialect(42))
+	if err := converter.Validate(); err == nil {
+		t.Error("expected an error for an unknown dialect")
+	}
+}
+
+func TestApplyDialectFixedFormats(t *testing.T) {
+	for name, write := range map[string]func(c *sqltocsv.Converter, w io.Writer) error{
+		"WriteCopyText":  (*sqltocsv.Converter).WriteCopyText,
+		"WriteMySQLDump": (*sqltocsv.Converter).WriteMySQLDump,
+	} {
+		var expected bytes.Buffer
+		if err := write(dialectConverter(0), &expected); err != nil {
+			t.Fatalf("error in %s: %v", name, err)
+		}
+		for _, d := range []sqltocsv.Dialect{sqltocsv.DialectRFC4180, sqltocsv.DialectExcelEU, sqltocsv.DialectUnix, sqltocsv.DialectTSVStrict} {
+			var out bytes.Buffer
+			if err := write(dialectConverter(d), &out); err != nil {
+				t.Errorf("%v: error in %s: %v", d, name, err)
+			} else if out.String() != expected.String() {
+				t.Errorf("%v: expected %s to write %q, got %q", d, name, expected.String(), out.String())
+			}
+		}
+	}
+}
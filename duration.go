@@ -0,0 +1,36 @@
+package sqltocsv
+
+import (
+	"strconv"
+	"time"
+)
+
+// DurationFormat is how time.Duration values are written, in
+// Converter.DurationFormat. It doesn't apply to PostgreSQL INTERVAL columns,
+// which drivers give as text: they're written as they are, whatever the
+// BinaryConverter, as long as the rows report their column types the way
+// *sql.Rows does.
+type DurationFormat int
+
+const (
+	// DurationString writes durations as time.Duration's String method
+	// does, such as 1h23m45.5s, or 0s.
+	DurationString DurationFormat = iota
+	// DurationSeconds writes durations as a number of seconds, with as
+	// many decimal places as they need, such as 5025.5.
+	DurationSeconds
+	// DurationMilliseconds writes durations as a whole number of
+	// milliseconds, truncated towards zero, such as 5025500.
+	DurationMilliseconds
+)
+
+// formatDuration formats d as DurationFormat says.
+func (c Converter) formatDuration(d time.Duration) string {
+	switch c.DurationFormat {
+	case DurationSeconds:
+		return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+	case DurationMilliseconds:
+		return strconv.FormatInt(d.Milliseconds(), 10)
+	}
+	return d.String()
+}
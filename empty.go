@@ -0,0 +1,63 @@
+package sqltocsv
+
+import (
+	"context"
+	"errors"
+)
+
+// EmptyMode is what Write and WriteFile do when there are no data rows to
+// write, in Converter.EmptyMode.
+type EmptyMode int
+
+const (
+	// EmptyWriteHeader writes the header row (if WriteHeaders is set)
+	// whether or not any rows follow it, so that WriteFile always creates
+	// a file.
+	EmptyWriteHeader EmptyMode = iota
+	// EmptySkipFile writes nothing at all, not even the header row or
+	// BOM, and WriteFile doesn't create the file.
+	EmptySkipFile
+	// EmptyError is EmptySkipFile, but the export then fails with
+	// ErrNoRows.
+	EmptyError
+)
+
+// ErrNoRows is returned with EmptyError set when there were no data rows to
+// write.
+var ErrNoRows = errors.New("no rows to write")
+
+// writeFileLazily is WriteFile for when the file is only to be created
+// once there is something to write to it, which writeCSV holds off on
+// until the first row.
+func (c Converter) writeFileLazily(ctx context.Context, csvFileName string) error {
+	lazy := &lazyFile{create: func() (*outputFile, error) { return c.createFile(csvFileName) }}
+
+	err := c.WriteContext(ctx, lazy)
+	if lazy.f == nil {
+		return errors.Join(err, lazy.err)
+	}
+	if err != nil {
+		lazy.f.abort() // close, but only return/handle the write error
+		return err
+	}
+	return lazy.f.commit()
+}
+
+// lazyFile is an io.Writer that creates its file on the first write.
+type lazyFile struct {
+	create func() (*outputFile, error)
+	f      *outputFile
+	err    error // from create
+}
+
+func (l *lazyFile) Write(p []byte) (int, error) {
+	if l.f == nil {
+		if l.err == nil {
+			l.f, l.err = l.create()
+		}
+		if l.err != nil {
+			return 0, l.err
+		}
+	}
+	return l.f.Write(p)
+}
@@ -0,0 +1,86 @@
+package sqltocsv_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestEmptyModeWriteHeader(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "empty.csv")
+	if err := sqltocsv.New(getEmptyTestRows(t)).WriteFile(name); err != nil {
+		t.Fatalf("error in WriteFile: %v", err)
+	}
+
+	contents, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("error reading %v: %v", name, err)
+	}
+	assertCsvMatch(t, "name,age,bdate\n", string(contents))
+}
+
+func TestEmptyModeSkipFile(t *testing.T) {
+	for _, mode := range []sqltocsv.EmptyMode{sqltocsv.EmptySkipFile, sqltocsv.EmptyError} {
+		var expected error
+		if mode == sqltocsv.EmptyError {
+			expected = sqltocsv.ErrNoRows
+		}
+
+		name := filepath.Join(t.TempDir(), "empty.csv")
+		converter := sqltocsv.New(getEmptyTestRows(t))
+		converter.EmptyMode = mode
+		converter.WriteBOM = true
+		if err := converter.WriteFile(name); !errors.Is(err, expected) {
+			t.Fatalf("mode %d: expected %v from WriteFile, got %v", mode, expected, err)
+		}
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("mode %d: expected no file to be created, got %v", mode, err)
+		}
+
+		converter = sqltocsv.New(getEmptyTestRows(t))
+		converter.EmptyMode = mode
+		csv, err := converter.WriteString()
+		if !errors.Is(err, expected) {
+			t.Fatalf("mode %d: expected %v from WriteString, got %v", mode, expected, err)
+		}
+		if csv != "" {
+			t.Errorf("mode %d: expected nothing to be written, got %q", mode, csv)
+		}
+	}
+}
+
+func TestEmptyModeSkipFileWithRows(t *testing.T) {
+	for _, mode := range []sqltocsv.EmptyMode{sqltocsv.EmptySkipFile, sqltocsv.EmptyError} {
+		name := filepath.Join(t.TempDir(), "people.csv")
+		converter := getConverter(t)
+		converter.EmptyMode = mode
+		if err := converter.WriteFile(name); err != nil {
+			t.Fatalf("mode %d: error in WriteFile: %v", mode, err)
+		}
+
+		contents, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("mode %d: error reading %v: %v", mode, name, err)
+		}
+		assertCsvMatch(t, "name,age,bdate\nAlice,1,1973-11-29T21:33:09Z\n", string(contents))
+	}
+}
+
+func TestEmptyModeFilteredRows(t *testing.T) {
+	converter := getConverter(t)
+	converter.EmptyMode = sqltocsv.EmptyError
+	converter.SetRowPreProcessor(func(columns []string, _ []string) (bool, []string) {
+		return false, columns
+	})
+
+	_, stats, err := converter.WriteStringWithStats()
+	if !errors.Is(err, sqltocsv.ErrNoRows) {
+		t.Fatalf("expected ErrNoRows, got %v", err)
+	}
+	if stats.RowsSkipped != 1 {
+		t.Errorf("expected 1 skipped row, got %d", stats.RowsSkipped)
+	}
+}
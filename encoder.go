@@ -0,0 +1,145 @@
+package sqltocsv
+
+import (
+	"bufio"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// encoder is a minimal CSV encoder for the output options encoding/csv
+// doesn't support. It follows RFC 4180 in the same way csv.Writer does, with
+// quote in place of the double quote, unless unquoted is set.
+type encoder struct {
+	w        *bufio.Writer
+	comma    string
+	quote    rune
+	quoteAll bool
+	useCRLF  bool
+
+	unquoted bool
+	replacer *strings.Replacer // for fields an unquoted encoder can't write as they are, nil to fail on them
+
+	escape        rune   // escape character for EscapeMode, which replaces quoting, 0 if not set
+	copyText      bool   // for WriteCopyText, which escapes tabs as \t
+	preserveNulls bool   // for PreserveNullVsEmpty
+	nullString    string // written as it is for NULLs by EscapeMode and PreserveNullVsEmpty
+
+	values []any // of the record being written by writeValues, where nil is NULL
+}
+
+// writeRecord writes a data row to w. values are the typed values behind
+// the fields if the plan kept them, for an encoder that writes NULLs
+// differently to the other fields.
+func writeRecord(w recordWriter, row []string, values []any) error {
+	if e, ok := w.(*encoder); ok && values != nil {
+		e.values = values
+		defer func() { e.values = nil }()
+	}
+	return w.Write(row)
+}
+
+// isNull reports whether field i of the record being written is a NULL.
+func (e *encoder) isNull(i int) bool {
+	return e.values != nil && e.values[i] == nil
+}
+
+// needsNulls reports whether the CSV writer needs to tell NULLs from the
+// other fields, and so the plan's typed values, see writeRecord.
+func (c Converter) needsNulls() bool {
+	return c.EscapeMode || c.PreserveNullVsEmpty
+}
+
+// Write writes a single record, quoting fields as needed. Like csv.Writer
+// it is buffered, so call Flush and check Error once done.
+func (e *encoder) Write(record []string) error {
+	if e.unquoted {
+		return e.writeUnquoted(record)
+	}
+	if e.escape != 0 {
+		return e.writeEscaped(record)
+	}
+
+	for i, field := range record {
+		if i > 0 {
+			e.w.WriteString(e.comma)
+		}
+
+		if e.preserveNulls && e.isNull(i) {
+			e.w.WriteString(e.nullString)
+			continue
+		}
+		if !e.quoteAll && !e.fieldNeedsQuotes(field) && !(e.preserveNulls && (field == "" || field == e.nullString)) {
+			e.w.WriteString(field)
+			continue
+		}
+
+		e.w.WriteRune(e.quote)
+		for _, r := range field {
+			switch {
+			case r == e.quote:
+				e.w.WriteRune(r)
+				e.w.WriteRune(r)
+			case r == '\n' && e.useCRLF:
+				e.w.WriteString("\r\n")
+			case r == '\r' && e.useCRLF:
+				// dropped, as csv.Writer does, since \n becomes \r\n
+			default:
+				e.w.WriteRune(r)
+			}
+		}
+		_, err := e.w.WriteRune(e.quote)
+		if err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if e.useCRLF {
+		_, err = e.w.WriteString("\r\n")
+	} else {
+		err = e.w.WriteByte('\n')
+	}
+	return err
+}
+
+// fieldNeedsQuotes reports whether field has to be quoted to survive a
+// round trip through a CSV reader.
+func (e *encoder) fieldNeedsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` || e.containsComma(field) || strings.ContainsRune(field, e.quote) || strings.ContainsAny(field, "\r\n") {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(field)
+	return unicode.IsSpace(r)
+}
+
+// containsComma reports whether field contains the delimiter, or for a
+// DelimiterString of more than one character ends with the start of it, as
+// "a|" does "||": written as it is, the reader would take the delimiter to
+// start a character early.
+func (e *encoder) containsComma(field string) bool {
+	if strings.Contains(field, e.comma) {
+		return true
+	}
+	for i := 1; i < len(e.comma); i++ {
+		if strings.HasSuffix(field, e.comma[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (e *encoder) Flush() {
+	e.w.Flush()
+}
+
+// Error reports any error that has occurred during a previous Write or
+// Flush.
+func (e *encoder) Error() error {
+	_, err := e.w.Write(nil)
+	return err
+}
@@ -0,0 +1,277 @@
+package sqltocsv_test
+
+import (
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestQuoteAll(t *testing.T) {
+	converter := getConverter(t)
+	converter.QuoteAll = true
+
+	expected := `"name","age","bdate"` + "\n" + `"Alice","1","1973-11-29T21:33:09Z"` + "\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+}
+
+func TestQuoteAllDelimiterAndCRLF(t *testing.T) {
+	converter := getConverter(t)
+	converter.QuoteAll = true
+	converter.Delimiter = ';'
+	converter.UseCRLF = true
+
+	expected := `"name";"age";"bdate"` + "\r\n" + `"Alice";"1";"1973-11-29T21:33:09Z"` + "\r\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+}
+
+func TestQuoteAllRoundTrip(t *testing.T) {
+	db := setupDatabase(t)
+	values := []string{`say "hi"`, "a;b", "multi\nline", " padded ", ""}
+	for i, value := range values {
+		exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", value, i, time.Unix(0, 0), nil)
+	}
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name|"))
+	converter.QuoteAll = true
+	converter.Delimiter = ';'
+
+	reader := csv.NewReader(strings.NewReader(converter.String()))
+	reader.Comma = ';'
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("error reading output back: %v", err)
+	}
+
+	expected := append([]string{"name", "Alice"}, values...)
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records, got %d", len(expected), len(records))
+	}
+	for i, record := range records {
+		if record[0] != expected[i] {
+			t.Errorf("record %d: expected %q, got %q", i, expected[i], record[0])
+		}
+	}
+}
+
+func TestQuoteChar(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "O'Brien, Pat", 2, time.Unix(0, 0), `say "hi"`)
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,nickname|age=?", 2))
+	converter.QuoteChar = '\''
+
+	// double quotes are nothing special any more
+	expected := "name,nickname\n'O''Brien, Pat',say \"hi\"\n"
+	assertCsvMatch(t, expected, converter.String())
+}
+
+func TestQuoteCharQuoteAll(t *testing.T) {
+	converter := getConverter(t)
+	converter.QuoteChar = '\''
+	converter.QuoteAll = true
+	converter.UseCRLF = true
+
+	expected := "'name','age','bdate'\r\n'Alice','1','1973-11-29T21:33:09Z'\r\n"
+	assertCsvMatch(t, expected, converter.String())
+}
+
+func TestQuoteCharRoundTrip(t *testing.T) {
+	db := setupDatabase(t)
+	values := []string{"it's", "''", "a|b", "multi\nline", " padded ", `"double"`, "ünï'cödé", ""}
+	for i, value := range values {
+		exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", value, i, time.Unix(0, 0), nil)
+	}
+
+	for _, quote := range []rune{'\'', '~', '§'} {
+		converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name|"))
+		converter.QuoteChar = quote
+		converter.Delimiter = '|'
+
+		records, err := readQuoted(converter.String(), '|', quote)
+		if err != nil {
+			t.Fatalf("%q: error reading output back: %v", quote, err)
+		}
+
+		expected := append([]string{"name", "Alice"}, values...)
+		if len(records) != len(expected) {
+			t.Fatalf("%q: expected %d records, got %d", quote, len(expected), len(records))
+		}
+		for i, record := range records {
+			if len(record) != 1 || record[0] != expected[i] {
+				t.Errorf("%q: record %d: expected %q, got %q", quote, i, expected[i], record)
+			}
+		}
+	}
+}
+
+// readQuoted parses RFC 4180 style CSV like csv.Reader, but with quote as
+// the quote character.
+func readQuoted(s string, comma, quote rune) ([][]string, error) {
+	var (
+		records [][]string
+		record  []string
+		field   strings.Builder
+		quoted  bool
+	)
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quoted && r == quote && i+1 < len(runes) && runes[i+1] == quote:
+			field.WriteRune(quote)
+			i++
+		case quoted && r == quote:
+			quoted = false
+		case quoted:
+			field.WriteRune(r)
+		case r == quote && field.Len() == 0:
+			quoted = true
+		case r == comma:
+			record = append(record, field.String())
+			field.Reset()
+		case r == '\n':
+			records = append(records, append(record, field.String()))
+			record = nil
+			field.Reset()
+		default:
+			field.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quoted field")
+	}
+	if record != nil || field.Len() > 0 {
+		return nil, errors.New("missing newline after last record")
+	}
+	return records, nil
+}
+
+func TestDelimiterString(t *testing.T) {
+	source := &sliceSource{
+		columns: []string{"id", "name"},
+		rows:    [][]any{{int64(1), "a|b"}, {int64(2), "a||b"}, {int64(3), "ends|"}},
+	}
+	converter := sqltocsv.NewFromSource(source)
+	converter.DelimiterString = "||"
+
+	expected := "id||name\n1||a|b\n2||\"a||b\"\n3||\"ends|\"\n"
+	assertCsvMatch(t, expected, converter.String())
+}
+
+func TestDelimiterStringUnquoted(t *testing.T) {
+	rows := [][]any{{int64(1), "a|b"}, {int64(2), "a||b"}}
+
+	converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"id", "name"}, rows: rows})
+	converter.DelimiterString = "||"
+	converter.UnquotedOutput = true
+	converter.UnquotedStrategy = sqltocsv.UnquotedReplace
+	converter.UnquotedReplacement = "/"
+	assertCsvMatch(t, "id||name\n1||a|b\n2||a/b\n", converter.String())
+
+	converter = sqltocsv.NewFromSource(&sliceSource{columns: []string{"id", "name"}, rows: rows})
+	converter.DelimiterString = "||"
+	converter.UnquotedOutput = true
+	converter.UnquotedStrategy = sqltocsv.UnquotedError
+	if _, err := converter.WriteString(); err == nil || !strings.Contains(err.Error(), `"a||b"`) {
+		t.Errorf("expected an error for the field containing the delimiter, got %v", err)
+	}
+}
+
+func TestDelimiterStringInvalid(t *testing.T) {
+	for _, delimiter := range []string{`"|`, "|\n"} {
+		converter := getConverter(t)
+		converter.DelimiterString = delimiter
+		if err := converter.Validate(); err == nil {
+			t.Errorf("expected an error for DelimiterString %q", delimiter)
+		}
+	}
+}
+
+// parseCopyCSV reads data the way PostgreSQL's COPY ... CSV does, for the
+// fields of a single line per record: an unquoted field the same as null is
+// a NULL, returned as nil, and a quoted one never is.
+func parseCopyCSV(t *testing.T, data, null string) [][]*string {
+	t.Helper()
+	var records [][]*string
+	for _, line := range strings.Split(strings.TrimSuffix(data, "\n"), "\n") {
+		var record []*string
+		for more := true; more; {
+			if !strings.HasPrefix(line, `"`) {
+				var value string
+				value, line, more = strings.Cut(line, ",")
+				if value == null {
+					record = append(record, nil)
+				} else {
+					record = append(record, &value)
+				}
+				continue
+			}
+
+			end := 1
+			for {
+				i := strings.IndexByte(line[end:], '"')
+				if i < 0 {
+					t.Fatalf("unterminated quote in %q", line)
+				}
+				end += i + 1
+				if !strings.HasPrefix(line[end:], `"`) {
+					break
+				}
+				end++
+			}
+			value := strings.ReplaceAll(line[1:end-1], `""`, `"`)
+			record = append(record, &value)
+			line, more = strings.CutPrefix(line[end:], ",")
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestPreserveNullVsEmpty(t *testing.T) {
+	for _, null := range []string{"", "NULL"} {
+		values := []any{"", nil, "NULL", "x", `say "hi"`}
+		rows := make([][]any, len(values))
+		for i, value := range values {
+			rows[i] = []any{value, int64(i)}
+		}
+		converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"value", "id"}, rows: rows})
+		converter.PreserveNullVsEmpty = true
+		converter.NullString = null
+
+		csv, err := converter.WriteString()
+		if err != nil {
+			t.Fatalf("null %q: error in WriteString: %v", null, err)
+		}
+		records := parseCopyCSV(t, csv, null)
+		if len(records) != len(values)+1 {
+			t.Fatalf("null %q: expected %d records, got %q", null, len(values)+1, csv)
+		}
+		for i, value := range values {
+			got := records[i+1][0]
+			switch {
+			case value == nil && got != nil:
+				t.Errorf("null %q: row %d: expected NULL, got %q in %q", null, i, *got, csv)
+			case value != nil && (got == nil || *got != value):
+				t.Errorf("null %q: row %d: expected %q, got %v in %q", null, i, value, got, csv)
+			}
+		}
+	}
+}
+
+func TestPreserveNullVsEmptyQuoteAll(t *testing.T) {
+	source := &sliceSource{columns: []string{"a", "b"}, rows: [][]any{{nil, ""}}}
+	converter := sqltocsv.NewFromSource(source)
+	converter.PreserveNullVsEmpty = true
+	converter.QuoteAll = true
+
+	assertCsvMatch(t, `"a","b"`+"\n"+`,""`+"\n", converter.String())
+}
@@ -0,0 +1,249 @@
+package sqltocsv
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The parts of the format written by WriteEncrypted.
+const (
+	encryptedMagic     = "SQLCSV\x01"
+	encryptedSaltSize  = 32
+	encryptedChunkSize = 64 * 1024
+	encryptedInfo      = "sqltocsv encrypted csv v1"
+	encryptedKeySize   = 32
+)
+
+// ErrDecrypt is returned from reading a Decrypt stream that was encrypted
+// with another key, or has been corrupted or cut short.
+var ErrDecrypt = errors.New("failed to decrypt: wrong key or corrupted data")
+
+// WriteEncrypted writes the CSV to the writer provided encrypted with key,
+// which has to be 32 bytes, as it's written, so no plaintext reaches w. The
+// CSV is the same as Write would write, and Decrypt reads it back.
+//
+// The encrypted stream is
+//
+//	"SQLCSV" 0x01 salt chunk...
+//
+// where salt is 32 random bytes and each chunk is the AES-256-GCM
+// ciphertext, tag included, of the next 65536 bytes of the CSV. The last
+// chunk may be shorter, or empty, and there is always one. The key the
+// chunks are sealed with is derived from key with HKDF-SHA256, using the
+// salt and the info string "sqltocsv encrypted csv v1", so no two streams
+// share one. The 12 byte nonce of each chunk is its number, counting from
+// 0, as a big-endian 11 byte integer, then 0x01 for the last chunk or 0x00
+// for the others, as in the STREAM construction, so a stream that has been
+// cut short fails to decrypt rather than reading as a shorter CSV. There is
+// no additional data.
+func (c Converter) WriteEncrypted(w io.Writer, key []byte) (err error) {
+	defer c.closeRows(&err)
+	c.ownsRows, c.CloseRows = false, false // closed by the defer, just the once
+
+	ew, err := newEncryptWriter(w, key)
+	if err != nil {
+		return err
+	}
+	if err := c.Write(ew); err != nil {
+		return err
+	}
+	return ew.Close()
+}
+
+// streamCipher derives the AES-256-GCM cipher of a stream from the key and
+// the stream's salt.
+func streamCipher(key, salt []byte) (cipher.AEAD, error) {
+	if len(key) != encryptedKeySize {
+		return nil, fmt.Errorf("encryption key is %d bytes, it has to be %d", len(key), encryptedKeySize)
+	}
+	streamKey, err := hkdf.Key(sha256.New, key, salt, encryptedInfo, encryptedKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive stream key: %w", err)
+	}
+	block, err := aes.NewCipher(streamKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce returns the nonce of chunk number n of a stream.
+func chunkNonce(nonce []byte, n uint64, last bool) []byte {
+	clear(nonce)
+	binary.BigEndian.PutUint64(nonce[3:11], n)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// encryptWriter encrypts what's written to it in chunks, holding back a
+// full chunk until more is written so it knows whether it's the last.
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	buf    []byte // plaintext of the chunk being filled
+	sealed []byte // ciphertext of the chunk being written
+	nonce  []byte
+	n      uint64 // number of the chunk being filled
+	err    error
+}
+
+// newEncryptWriter writes the header of a stream to w, returning the writer
+// for the chunks.
+func newEncryptWriter(w io.Writer, key []byte) (*encryptWriter, error) {
+	salt := make([]byte, encryptedSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	aead, err := streamCipher(key, salt)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, encryptedMagic); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(salt); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{
+		w:      w,
+		aead:   aead,
+		buf:    make([]byte, 0, encryptedChunkSize),
+		sealed: make([]byte, 0, encryptedChunkSize+aead.Overhead()),
+		nonce:  make([]byte, aead.NonceSize()),
+	}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	written := 0
+	for len(p) > 0 {
+		if len(e.buf) == encryptedChunkSize {
+			if e.err = e.seal(false); e.err != nil {
+				return written, e.err
+			}
+		}
+		n := copy(e.buf[len(e.buf):encryptedChunkSize], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close writes the last chunk. It doesn't close the underlying writer.
+func (e *encryptWriter) Close() error {
+	if e.err != nil {
+		return e.err
+	}
+	err := e.seal(true)
+	e.err = err
+	if err == nil {
+		e.err = errors.New("encrypted stream is closed")
+	}
+	return err
+}
+
+// seal encrypts and writes the chunk being filled.
+func (e *encryptWriter) seal(last bool) error {
+	e.sealed = e.aead.Seal(e.sealed[:0], chunkNonce(e.nonce, e.n, last), e.buf, nil)
+	if _, err := e.w.Write(e.sealed); err != nil {
+		return err
+	}
+	e.buf = e.buf[:0]
+	e.n++
+	return nil
+}
+
+// Decrypt returns a reader of the CSV encrypted by WriteEncrypted with key
+// into r. Reading returns ErrDecrypt, after any chunks that decrypted, if
+// the key is wrong or the stream has been changed or cut short, so nothing
+// read is to be trusted until it has reached io.EOF.
+func Decrypt(r io.Reader, key []byte) io.Reader {
+	return &decryptReader{r: bufio.NewReaderSize(r, encryptedChunkSize+64), key: key}
+}
+
+// decryptReader decrypts a stream a chunk at a time.
+type decryptReader struct {
+	r     *bufio.Reader
+	key   []byte
+	aead  cipher.AEAD // nil until the header is read
+	buf   []byte      // ciphertext of the chunk being read
+	plain []byte      // what's left to be read of the last chunk decrypted
+	nonce []byte
+	n     uint64
+	err   error // returned once plain is used up, io.EOF after the last chunk
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.err = d.next()
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// next decrypts the next chunk into plain, reading the header first if it
+// hasn't been. It returns io.EOF once the last chunk has been decrypted.
+func (d *decryptReader) next() error {
+	if d.aead == nil {
+		header := make([]byte, len(encryptedMagic)+encryptedSaltSize)
+		if _, err := io.ReadFull(d.r, header); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrDecrypt
+		} else if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(header, []byte(encryptedMagic)) {
+			return errors.New("not a sqltocsv encrypted stream")
+		}
+		aead, err := streamCipher(d.key, header[len(encryptedMagic):])
+		if err != nil {
+			return err
+		}
+		d.aead = aead
+		d.buf = make([]byte, encryptedChunkSize+aead.Overhead())
+		d.nonce = make([]byte, aead.NonceSize())
+	}
+
+	n, err := io.ReadFull(d.r, d.buf)
+	last := false
+	switch {
+	case err == io.ErrUnexpectedEOF || err == io.EOF:
+		last = true
+	case err != nil:
+		return err
+	default:
+		if _, err := d.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	plain, err := d.aead.Open(d.buf[:0], chunkNonce(d.nonce, d.n, last), d.buf[:n], nil)
+	if err != nil {
+		return ErrDecrypt
+	}
+	d.plain = plain
+	d.n++
+	if last {
+		return io.EOF
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func encrypt(t *testing.T, converter *sqltocsv.Converter, key []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := converter.WriteEncrypted(&buf, key); err != nil {
+		t.Fatalf("error in WriteEncrypted: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestWriteEncrypted(t *testing.T) {
+	encrypted := encrypt(t, getConverter(t), testKey)
+	if bytes.Contains(encrypted, []byte("Alice")) {
+		t.Error("expected no plaintext in the encrypted stream")
+	}
+
+	decrypted, err := io.ReadAll(sqltocsv.Decrypt(bytes.NewReader(encrypted), testKey))
+	if err != nil {
+		t.Fatalf("error decrypting: %v", err)
+	}
+	assertCsvMatch(t, "name,age,bdate\nAlice,1,1973-11-29T21:33:09Z\n", string(decrypted))
+
+	if again := encrypt(t, getConverter(t), testKey); bytes.Equal(again, encrypted) {
+		t.Error("expected two streams encrypted with the same key to differ")
+	}
+}
+
+func TestWriteEncryptedSizes(t *testing.T) {
+	const chunk = 64 * 1024
+	for _, size := range []int{0, 1, 100, chunk - 1, chunk, chunk + 1, 3 * chunk, 3*chunk + 12345} {
+		var rows [][]any
+		expected := ""
+		if size > 0 {
+			rows = [][]any{{strings.Repeat("x", size-1)}}
+			expected = rows[0][0].(string) + "\n"
+		}
+		converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"value"}, rows: rows})
+		converter.WriteHeaders = false
+
+		encrypted := encrypt(t, converter, testKey)
+		decrypted, err := io.ReadAll(sqltocsv.Decrypt(bytes.NewReader(encrypted), testKey))
+		if err != nil {
+			t.Fatalf("%d bytes: error decrypting: %v", size, err)
+		}
+		if string(decrypted) != expected {
+			t.Errorf("%d bytes: decrypted %d bytes that don't match", size, len(decrypted))
+		}
+
+		// a stream cut short at a chunk boundary must not read as a
+		// shorter CSV
+		if size > chunk {
+			cut := 7 + 32 + chunk + 16
+			_, err := io.ReadAll(sqltocsv.Decrypt(bytes.NewReader(encrypted[:cut]), testKey))
+			if !errors.Is(err, sqltocsv.ErrDecrypt) {
+				t.Errorf("%d bytes: expected ErrDecrypt for a truncated stream, got %v", size, err)
+			}
+		}
+	}
+}
+
+func TestDecryptWrongKey(t *testing.T) {
+	encrypted := encrypt(t, getConverter(t), testKey)
+
+	wrongKey := bytes.ToUpper(testKey)
+	_, err := io.ReadAll(sqltocsv.Decrypt(bytes.NewReader(encrypted), wrongKey))
+	if !errors.Is(err, sqltocsv.ErrDecrypt) {
+		t.Errorf("expected ErrDecrypt for the wrong key, got %v", err)
+	}
+
+	tampered := bytes.Clone(encrypted)
+	tampered[len(tampered)-1] ^= 1
+	_, err = io.ReadAll(sqltocsv.Decrypt(bytes.NewReader(tampered), testKey))
+	if !errors.Is(err, sqltocsv.ErrDecrypt) {
+		t.Errorf("expected ErrDecrypt for a tampered stream, got %v", err)
+	}
+
+	_, err = io.ReadAll(sqltocsv.Decrypt(strings.NewReader("name,age\n"), testKey))
+	if err == nil {
+		t.Error("expected an error decrypting a plain CSV")
+	}
+}
+
+func TestWriteEncryptedKeySize(t *testing.T) {
+	var buf bytes.Buffer
+	if err := getConverter(t).WriteEncrypted(&buf, testKey[:16]); err == nil {
+		t.Error("expected an error for a 16 byte key")
+	}
+	if buf.Len() > 0 {
+		t.Errorf("expected nothing written, got %d bytes", buf.Len())
+	}
+}
@@ -0,0 +1,39 @@
+package sqltocsv
+
+import (
+	"strconv"
+	"time"
+)
+
+// Values for TimeFormat, or TimeFormats for individual columns, that write
+// times as an integer count of seconds, milliseconds or microseconds since
+// the Unix epoch instead of formatting them. Times before 1970 come out
+// negative, rounded down to the second, millisecond or microsecond before.
+const (
+	EpochSeconds = "epoch-seconds"
+	EpochMillis  = "epoch-millis"
+	EpochMicros  = "epoch-micros"
+)
+
+// appendEpoch appends t to dst as the epoch TimeFormat says, reporting
+// false, leaving dst as it is, if TimeFormat isn't one of them.
+func (c Converter) appendEpoch(dst []byte, t time.Time) ([]byte, bool) {
+	switch c.TimeFormat {
+	case EpochSeconds:
+		return strconv.AppendInt(dst, t.Unix(), 10), true
+	case EpochMillis:
+		return strconv.AppendInt(dst, t.UnixMilli(), 10), true
+	case EpochMicros:
+		return strconv.AppendInt(dst, t.UnixMicro(), 10), true
+	}
+	return dst, false
+}
+
+// isEpochFormat reports whether layout is one of the epoch TimeFormats.
+func isEpochFormat(layout string) bool {
+	switch layout {
+	case EpochSeconds, EpochMillis, EpochMicros:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package sqltocsv
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// escapeChar returns the EscapeChar, or a backslash if it isn't set.
+func (c Converter) escapeChar() rune {
+	if c.EscapeChar != '\x00' {
+		return c.EscapeChar
+	}
+	return '\\'
+}
+
+// checkEscape returns an error if EscapeMode is set along with options it
+// can't be, or with an EscapeChar that would be mistaken for something else.
+func (c Converter) checkEscape() error {
+	if !c.EscapeMode {
+		return nil
+	}
+	if c.QuoteAll || c.UnquotedOutput || c.PreserveNullVsEmpty {
+		return errors.New("EscapeMode can't be used with QuoteAll, UnquotedOutput or PreserveNullVsEmpty")
+	}
+	escape := string(c.escapeChar())
+	if strings.Contains(c.delimiter(), escape) || strings.ContainsAny(escape, "\r\n") {
+		return errors.New("EscapeChar can't be part of the delimiter or a line break")
+	}
+	return nil
+}
+
+// writeEscaped writes a record for EscapeMode, with the fields as they are
+// but for the escape character put in front of each delimiter and the
+// escape character itself, and in place of line breaks and NUL as \n, \r
+// and \0 are, and tabs as \t too for WriteCopyText. A NULL is written as
+// NullString, and any other field that would read back as one gets an escape
+// character in front of it.
+func (e *encoder) writeEscaped(record []string) error {
+	for i, field := range record {
+		if i > 0 {
+			e.w.WriteString(e.comma)
+		}
+		if e.isNull(i) {
+			e.w.WriteString(e.nullString)
+			continue
+		}
+		if field != "" && field == e.nullString {
+			e.w.WriteRune(e.escape)
+		}
+
+		for len(field) > 0 {
+			if e.copyText && field[0] == '\t' {
+				e.w.WriteRune(e.escape)
+				e.w.WriteByte('t')
+				field = field[1:]
+				continue
+			}
+			if strings.HasPrefix(field, e.comma) {
+				e.w.WriteRune(e.escape)
+				e.w.WriteString(e.comma)
+				field = field[len(e.comma):]
+				continue
+			}
+			r, size := utf8.DecodeRuneInString(field)
+			switch r {
+			case e.escape:
+				e.w.WriteRune(e.escape)
+				e.w.WriteRune(e.escape)
+			case '\n':
+				e.w.WriteRune(e.escape)
+				e.w.WriteByte('n')
+			case '\r':
+				e.w.WriteRune(e.escape)
+				e.w.WriteByte('r')
+			case 0:
+				e.w.WriteRune(e.escape)
+				e.w.WriteByte('0')
+			default:
+				e.w.WriteString(field[:size])
+			}
+			field = field[size:]
+		}
+	}
+
+	var err error
+	if e.useCRLF {
+		_, err = e.w.WriteString("\r\n")
+	} else {
+		err = e.w.WriteByte('\n')
+	}
+	return err
+}
@@ -0,0 +1,136 @@
+package sqltocsv_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+// parseEscaped reads data the way MySQL's LOAD DATA does with FIELDS
+// ESCAPED BY the escape character and nothing ENCLOSED BY: records end at
+// unescaped newlines and fields at unescaped delimiters, the escape sequences
+// \0, \b, \n, \r, \t and \Z stand for what they do in SQL strings, any
+// other escaped character for itself, and an escaped N on its own is a NULL,
+// returned as nil.
+func parseEscaped(t *testing.T, data string, delimiter, escape rune) [][]*string {
+	t.Helper()
+	var records [][]*string
+	var record []*string
+	var field strings.Builder
+	null, escaped := false, false
+	endField := func() {
+		if null {
+			record = append(record, nil)
+		} else {
+			value := field.String()
+			record = append(record, &value)
+		}
+		field.Reset()
+		null = false
+	}
+	for _, r := range data {
+		switch {
+		case escaped:
+			escaped = false
+			switch r {
+			case 'n':
+				field.WriteByte('\n')
+			case 'r':
+				field.WriteByte('\r')
+			case '0':
+				field.WriteByte(0)
+			case 'b':
+				field.WriteByte('\b')
+			case 't':
+				field.WriteByte('\t')
+			case 'Z':
+				field.WriteByte(0x1a)
+			case 'N':
+				if field.Len() == 0 {
+					null = true
+					continue
+				}
+				field.WriteRune(r)
+			default:
+				field.WriteRune(r)
+			}
+		case r == escape:
+			escaped = true
+		case r == delimiter:
+			endField()
+		case r == '\n':
+			endField()
+			records = append(records, record)
+			record = nil
+		default:
+			field.WriteRune(r)
+		}
+		if null && field.Len() > 0 {
+			t.Fatalf("characters after an escaped N in %q", data)
+		}
+	}
+	if escaped || field.Len() > 0 || record != nil {
+		t.Fatalf("unterminated record in %q", data)
+	}
+	return records
+}
+
+func TestEscapeModeRoundTrip(t *testing.T) {
+	values := []any{"a,b", "multi\nline\r\n", `back\slash`, "", `\N`, "tab\tnul\x00", nil, "héllo"}
+	rows := make([][]any, len(values))
+	for i, value := range values {
+		rows[i] = []any{int64(i), value}
+	}
+	converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"id", "value,text"}, rows: rows})
+	converter.EscapeMode = true
+	converter.NullString = `\N`
+
+	csv, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	records := parseEscaped(t, csv, ',', '\\')
+	if len(records) != len(values)+1 {
+		t.Fatalf("expected %d records, got %d from %q", len(values)+1, len(records), csv)
+	}
+	if header := []string{*records[0][0], *records[0][1]}; !reflect.DeepEqual(header, []string{"id", "value,text"}) {
+		t.Errorf("unexpected header %q", header)
+	}
+	for i, value := range values {
+		got := records[i+1][1]
+		switch {
+		case value == nil && got != nil:
+			t.Errorf("row %d: expected NULL, got %q", i, *got)
+		case value != nil && (got == nil || *got != value):
+			t.Errorf("row %d: expected %q, got %v in %q", i, value, got, csv)
+		}
+	}
+}
+
+func TestEscapeMode(t *testing.T) {
+	source := &sliceSource{columns: []string{"a", "b"}, rows: [][]any{{"x;y", nil}, {"NULL", `c:\`}}}
+	converter := sqltocsv.NewFromSource(source)
+	converter.EscapeMode = true
+	converter.EscapeChar = '~'
+	converter.Delimiter = ';'
+	converter.NullString = "NULL"
+
+	assertCsvMatch(t, "a;b\nx~;y;NULL\n~NULL;c:\\\n", converter.String())
+}
+
+func TestEscapeModeInvalid(t *testing.T) {
+	for i, configure := range []func(*sqltocsv.Converter){
+		func(c *sqltocsv.Converter) { c.QuoteAll = true },
+		func(c *sqltocsv.Converter) { c.EscapeChar = ',' },
+		func(c *sqltocsv.Converter) { c.EscapeChar = '\n' },
+	} {
+		converter := getConverter(t)
+		converter.EscapeMode = true
+		configure(converter)
+		if err := converter.Validate(); err == nil {
+			t.Errorf("%d: expected an error", i)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package sqltocsv
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// StaticColumn is a column with the same value in every row, added to the
+// output by StaticColumns.
+type StaticColumn struct {
+	Name  string // header of the column
+	Value string // written as it is, without any of the conversion options
+}
+
+// computedColumn is a column added by AddComputedColumn.
+type computedColumn struct {
+	header string
+	fn     ComputedColumnFunc
+}
+
+// extraColumn is a column of the output that doesn't come from the query.
+// Extra columns are added after the preprocessor has run, so it never sees
+// them, and their values aren't rewritten by options like MaxFieldLength.
+type extraColumn struct {
+	header    string
+	rowNumber bool               // numbers the rows written, see RowNumberHeader
+	compute   ComputedColumnFunc // or works out each row's value, see AddComputedColumn
+	value     string             // otherwise the column's value in every row
+}
+
+// extraColumns returns the extra columns to go before and after the query's.
+// The row number is outermost, then the static columns, with the computed
+// columns next to the query's columns.
+func (c Converter) extraColumns() (before, after []extraColumn) {
+	for _, column := range c.computedColumns {
+		computed := extraColumn{header: column.header, compute: column.fn}
+		if c.ComputedColumnsFirst {
+			before = append(before, computed)
+		} else {
+			after = append(after, computed)
+		}
+	}
+
+	static := make([]extraColumn, len(c.StaticColumns))
+	for i, column := range c.StaticColumns {
+		static[i] = extraColumn{header: column.Name, value: column.Value}
+	}
+	if c.StaticColumnsFirst {
+		before = append(static, before...)
+	} else {
+		after = append(after, static...)
+	}
+
+	if c.RowNumberHeader != "" {
+		number := extraColumn{header: c.RowNumberHeader, rowNumber: true}
+		if c.RowNumberLast {
+			after = append(after, number)
+		} else {
+			before = append([]extraColumn{number}, before...)
+		}
+	}
+	return before, after
+}
+
+// withExtraHeaders returns headers with those of the extra columns added,
+// leaving headers itself alone.
+func withExtraHeaders(headers []string, before, after []extraColumn) []string {
+	out := make([]string, 0, len(before)+len(headers)+len(after))
+	for _, column := range before {
+		out = append(out, column.header)
+	}
+	out = append(out, headers...)
+	for _, column := range after {
+		out = append(out, column.header)
+	}
+	return out
+}
+
+// addExtraColumns appends row to dst with the extra columns added, for the
+// number'th row written, which is row rowNumber of the result set. source
+// holds the values for computed columns, see sourceValues. If values isn't
+// nil the same is done for it in dstValues, with the row number as an int64.
+func (p *plan) addExtraColumns(dst []string, dstValues []any, row []string, values []any, number int64, source map[string]string, rowNumber int) ([]string, []any, error) {
+	add := func(columns []extraColumn) error {
+		for _, column := range columns {
+			var field string
+			var value any
+			switch {
+			case column.rowNumber:
+				field, value = strconv.FormatInt(number, 10), number
+			case column.compute != nil:
+				var err error
+				if field, err = column.compute(source); err != nil {
+					return fmt.Errorf("failed to compute column %q in row %d: %w", column.header, rowNumber, err)
+				}
+				value = field
+			default:
+				field, value = column.value, column.value
+			}
+			dst = append(dst, field)
+			if values != nil {
+				dstValues = append(dstValues, value)
+			}
+		}
+		return nil
+	}
+
+	if err := add(p.before); err != nil {
+		return nil, nil, err
+	}
+	dst = append(dst, row...)
+	if values != nil {
+		dstValues = append(dstValues, values...)
+	}
+	if err := add(p.after); err != nil {
+		return nil, nil, err
+	}
+
+	if values == nil {
+		return dst, nil, nil
+	}
+	return dst, dstValues, nil
+}
+
+// sourceValues fills source, for the computed columns, with every query
+// column's value in row rowNumber of the result set. The output columns are
+// taken from row, as converted, and the others are converted from values
+// the same way they would have been.
+func (c Converter) sourceValues(p *plan, source map[string]string, row []string, values []any, rowNumber int) error {
+	for i, idx := range p.selected {
+		source[p.queryColumns[idx]] = row[i]
+	}
+	for i, idx := range p.unselected {
+		name := p.queryColumns[idx]
+		var text string
+		var err error
+		handled := false
+		if formatter := c.columnFormatters[name]; formatter != nil {
+			text, err = formatter(values[idx])
+			handled = true
+		} else if c.valueConverter != nil {
+			text, handled, err = c.valueConverter(name, values[idx])
+		}
+		if err == nil && !handled {
+			text, err = p.unselectedConverters[i].convert(values[idx])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to convert column %q in row %d: %w", name, rowNumber, err)
+		}
+		source[name] = text
+	}
+	return nil
+}
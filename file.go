@@ -0,0 +1,85 @@
+package sqltocsv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// outputFile is the file a WriteFile style export goes to. With Atomic set
+// the File is a temporary file in the same directory as name, which only
+// takes name's place once commit has synced and closed it.
+type outputFile struct {
+	*os.File
+	name   string
+	atomic bool
+	sync   bool
+}
+
+// fileMode is the mode files are created with, FileMode or os.Create's 0666.
+func (c Converter) fileMode() os.FileMode {
+	if c.FileMode == 0 {
+		return 0o666
+	}
+	return c.FileMode
+}
+
+// createFile creates the file for an export to name. Callers write to it and
+// then either commit it or, on any error, abort it.
+func (c Converter) createFile(name string) (*outputFile, error) {
+	if !c.Atomic {
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, c.fileMode())
+		if err != nil {
+			return nil, err
+		}
+		return &outputFile{File: f, name: name, sync: c.Sync}, nil
+	}
+
+	// os.CreateTemp makes the file 0600, so it's never any more open than
+	// FileMode asks for while it's being written
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	out := &outputFile{File: f, name: name, atomic: true, sync: true}
+	if c.FileMode != 0 {
+		if err := f.Chmod(c.FileMode); err != nil {
+			out.abort()
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// commit closes the file, first syncing it if asked to and renaming it onto
+// the destination if it's a temporary one. The temporary file is removed if
+// any of that fails.
+func (f *outputFile) commit() error {
+	if f.sync {
+		if err := f.Sync(); err != nil {
+			f.abort()
+			return err
+		}
+	}
+	if !f.atomic {
+		return f.Close()
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), f.name); err != nil {
+		return errors.Join(err, os.Remove(f.Name()))
+	}
+	return nil
+}
+
+// abort closes the file after a failed export, removing it if it's a
+// temporary one. A non-atomic file is left as far as it got.
+func (f *outputFile) abort() {
+	f.Close()
+	if f.atomic {
+		os.Remove(f.Name())
+	}
+}
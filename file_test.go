@@ -0,0 +1,152 @@
+package sqltocsv_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestAtomicWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "test.csv")
+	if err := os.WriteFile(name, []byte("old contents\n"), 0o644); err != nil {
+		t.Fatalf("error writing %v: %v", name, err)
+	}
+
+	converter := getConverter(t)
+	converter.Atomic = true
+	if err := converter.WriteFile(name); err != nil {
+		t.Fatalf("error in WriteFile: %v", err)
+	}
+
+	contents, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("error reading %v: %v", name, err)
+	}
+	assertCsvMatch(t, "name,age,bdate\nAlice,1,1973-11-29T21:33:09Z\n", string(contents))
+	assertOnlyFile(t, dir, "test.csv")
+}
+
+func TestAtomicWriteFileError(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "test.csv")
+	if err := os.WriteFile(name, []byte("old contents\n"), 0o644); err != nil {
+		t.Fatalf("error writing %v: %v", name, err)
+	}
+
+	converter := getConverter(t)
+	converter.Atomic = true
+	errConvert := errors.New("can't convert")
+	converter.SetValueConverter(func(string, any) (string, bool, error) {
+		return "", false, errConvert
+	})
+	if err := converter.WriteFile(name); !errors.Is(err, errConvert) {
+		t.Fatalf("expected the converter's error, got %v", err)
+	}
+
+	// the old file is left alone and the temporary one is gone
+	contents, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("error reading %v: %v", name, err)
+	}
+	if string(contents) != "old contents\n" {
+		t.Errorf("expected the old file to be untouched, got %q", contents)
+	}
+	assertOnlyFile(t, dir, "test.csv")
+}
+
+func TestAtomicWriteGzipFile(t *testing.T) {
+	dir := t.TempDir()
+	converter := getConverter(t)
+	converter.Atomic = true
+	if err := converter.WriteGzipFile(filepath.Join(dir, "test.csv.gz")); err != nil {
+		t.Fatalf("error in WriteGzipFile: %v", err)
+	}
+	assertOnlyFile(t, dir, "test.csv.gz")
+}
+
+func TestAtomicWriteFileChunks(t *testing.T) {
+	dir := t.TempDir()
+	source := &sliceSource{
+		columns: []string{"id"},
+		rows:    [][]any{{int64(1)}, {int64(2)}, {int64(3)}},
+		err:     errors.New("connection reset"),
+	}
+	converter := sqltocsv.NewFromSource(source)
+	converter.Atomic = true
+
+	// the first chunk is complete, the second fails part way through
+	names, err := converter.WriteFileChunks(filepath.Join(dir, "chunk-%d.csv"), 2)
+	if !errors.Is(err, source.err) {
+		t.Fatalf("expected the rows' error, got %v", err)
+	}
+	if len(names) != 2 {
+		t.Errorf("expected the names of both chunks, got %v", names)
+	}
+	assertCsvMatch(t, "id\n1\n2\n", readFile(t, filepath.Join(dir, "chunk-1.csv")))
+	assertOnlyFile(t, dir, "chunk-1.csv")
+}
+
+func TestFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are unix only")
+	}
+
+	dir := t.TempDir()
+	for _, test := range []struct {
+		name   string
+		mode   os.FileMode
+		atomic bool
+		write  func(converter *sqltocsv.Converter, name string) error
+	}{
+		{"write.csv", 0o600, false, (*sqltocsv.Converter).WriteFile},
+		{"sync.csv", 0o600, false, func(converter *sqltocsv.Converter, name string) error {
+			converter.Sync = true
+			return converter.WriteFile(name)
+		}},
+		{"atomic.csv", 0o640, true, (*sqltocsv.Converter).WriteFile},
+		{"atomic-default.csv", 0, true, (*sqltocsv.Converter).WriteFile},
+		{"append.csv", 0o600, false, (*sqltocsv.Converter).AppendFile},
+		{"gzip.csv.gz", 0o600, false, (*sqltocsv.Converter).WriteGzipFile},
+	} {
+		converter := getConverter(t)
+		converter.FileMode = test.mode
+		converter.Atomic = test.atomic
+		name := filepath.Join(dir, test.name)
+		if err := test.write(converter, name); err != nil {
+			t.Fatalf("%s: error writing: %v", test.name, err)
+		}
+
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("%s: error in stat: %v", test.name, err)
+		}
+		expected := test.mode
+		if expected == 0 {
+			expected = 0o600
+		}
+		if mode := info.Mode().Perm(); mode != expected {
+			t.Errorf("%s: expected mode %v, got %v", test.name, expected, mode)
+		}
+	}
+}
+
+// assertOnlyFile checks that name is the only file left in dir.
+func assertOnlyFile(t *testing.T, dir, name string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("error reading %v: %v", dir, err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if len(names) != 1 || names[0] != name {
+		t.Errorf("expected only %s in %s, got %q", name, dir, names)
+	}
+}
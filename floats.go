@@ -0,0 +1,19 @@
+package sqltocsv
+
+import "math"
+
+// specialFloat returns what NaNString, InfString and NegInfString say to
+// write for f, reporting false if f isn't NaN or infinite or they aren't set.
+func (c Converter) specialFloat(f float64) (string, bool) {
+	switch {
+	case math.IsNaN(f) && c.NaNString != nil:
+		return *c.NaNString, true
+	case math.IsInf(f, 1) && c.InfString != nil:
+		return *c.InfString, true
+	case math.IsInf(f, -1) && c.NegInfString != nil:
+		return *c.NegInfString, true
+	case math.IsInf(f, -1) && c.InfString != nil:
+		return "-" + *c.InfString, true
+	}
+	return "", false
+}
@@ -0,0 +1,51 @@
+package sqltocsv
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// FooterFunc returns the record to write after the last data row, given the
+// Stats of the export so far. Returning nil writes no footer.
+type FooterFunc func(stats Stats) []string
+
+// SetFooter lets you specify a FooterFunc for a trailer record at the end of
+// the CSV, such as TOTAL,12345. It's called once every row has been written,
+// after all the result sets with AllResultSets, and what it returns is
+// written as it is, without the preprocessor or any other data row options.
+// The footer isn't counted in Stats.RowsWritten. WriteFileChunks writes one
+// at the end of each file, given the Stats of that file.
+func (c *Converter) SetFooter(fn FooterFunc) {
+	c.footer = fn
+}
+
+// rowCountFooter is the first field of the WriteRowCountFooter record.
+const rowCountFooter = "__rowcount__"
+
+// writeFooters writes the SetFooter and WriteRowCountFooter records, in that
+// order, once the data rows have been flushed so the Stats returned by stats
+// are up to date.
+func (c Converter) writeFooters(csvWriter recordWriter, flush func(final bool) error, stats func() Stats) error {
+	if c.footer == nil && !c.WriteRowCountFooter {
+		return nil
+	}
+	if err := flush(false); err != nil {
+		return err
+	}
+
+	current := stats()
+	if c.footer != nil {
+		if footer := c.footer(current); footer != nil {
+			if err := csvWriter.Write(footer); err != nil {
+				return fmt.Errorf("failed to write footer: %w", err)
+			}
+		}
+	}
+	if c.WriteRowCountFooter {
+		footer := []string{rowCountFooter, strconv.FormatInt(current.RowsWritten, 10)}
+		if err := csvWriter.Write(footer); err != nil {
+			return fmt.Errorf("failed to write row count footer: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package sqltocsv_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestSetFooter(t *testing.T) {
+	converter := getConverter(t)
+	converter.SetRowPreProcessor(func(row []string, _ []string) (bool, []string) {
+		return true, []string{row[0] + "!", row[1], row[2]}
+	})
+	var seen sqltocsv.Stats
+	converter.SetFooter(func(stats sqltocsv.Stats) []string {
+		seen = stats
+		return []string{"TOTAL", strconv.FormatInt(stats.RowsWritten, 10)}
+	})
+
+	csv, stats, err := converter.WriteStringWithStats()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	assertCsvMatch(t, "name,age,bdate\nAlice!,1,1973-11-29T21:33:09Z\nTOTAL,1\n", csv)
+	if stats.RowsWritten != 1 {
+		t.Errorf("expected the footer not to count as a row, got %d rows", stats.RowsWritten)
+	}
+	if header := int64(len("name,age,bdate\n")); seen.BytesWritten <= header {
+		t.Errorf("expected the footer to see the bytes of the data rows, got %d", seen.BytesWritten)
+	}
+}
+
+func TestSetFooterWithRowCount(t *testing.T) {
+	converter := getConverter(t)
+	converter.WriteRowCountFooter = true
+	converter.SetFooter(func(sqltocsv.Stats) []string { return []string{"END"} })
+
+	csv, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	assertCsvMatch(t, "name,age,bdate\nAlice,1,1973-11-29T21:33:09Z\nEND\n__rowcount__,1\n", csv)
+}
+
+func TestSetFooterNil(t *testing.T) {
+	converter := getConverter(t)
+	converter.SetFooter(func(sqltocsv.Stats) []string { return nil })
+
+	csv, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	assertCsvMatch(t, "name,age,bdate\nAlice,1,1973-11-29T21:33:09Z\n", csv)
+}
+
+func TestWriteRowCountFooter(t *testing.T) {
+	tests := []struct {
+		rows     func(*testing.T) *sqltocsv.Converter
+		expected string
+	}{
+		{getConverter, "name,age,bdate\nAlice,1,1973-11-29T21:33:09Z\n__rowcount__,1\n"},
+		{func(t *testing.T) *sqltocsv.Converter { return sqltocsv.New(getEmptyTestRows(t)) }, "name,age,bdate\n__rowcount__,0\n"},
+	}
+	for i, test := range tests {
+		converter := test.rows(t)
+		converter.WriteRowCountFooter = true
+
+		csv, err := converter.WriteString()
+		if err != nil {
+			t.Fatalf("%d: error in WriteString: %v", i, err)
+		}
+		assertCsvMatch(t, test.expected, csv)
+	}
+}
+
+func TestFooterEmptySkipFile(t *testing.T) {
+	converter := sqltocsv.New(getEmptyTestRows(t))
+	converter.EmptyMode = sqltocsv.EmptySkipFile
+	converter.WriteRowCountFooter = true
+
+	csv, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	if csv != "" {
+		t.Errorf("expected no footer without any rows, got %q", csv)
+	}
+}
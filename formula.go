@@ -0,0 +1,29 @@
+package sqltocsv
+
+import "strings"
+
+// defaultFormulaPrefix is what SanitizeFormulas puts in front of a value when
+// FormulaPrefix isn't set. Spreadsheets show a leading ' as text and hide it.
+const defaultFormulaPrefix = "'"
+
+// formulaTriggers are the characters that make a spreadsheet treat a cell as
+// a formula.
+const formulaTriggers = "=+-@"
+
+// sanitizeFormula prefixes s if SanitizeFormulas is set and s would be run as
+// a formula when the CSV is opened in a spreadsheet. Leading spaces, tabs and
+// line breaks are skipped when looking for the trigger character, as
+// spreadsheets skip them too.
+func (c Converter) sanitizeFormula(s string) string {
+	if !c.SanitizeFormulas {
+		return s
+	}
+	trimmed := strings.TrimLeft(s, " \t\r\n")
+	if trimmed == "" || !strings.ContainsRune(formulaTriggers, rune(trimmed[0])) {
+		return s
+	}
+	if c.FormulaPrefix != "" {
+		return c.FormulaPrefix + s
+	}
+	return defaultFormulaPrefix + s
+}
@@ -0,0 +1,40 @@
+package sqltocsv_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestSanitizeFormulas(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "WIPE")
+	exec(t, db, "CREATE|people|name=string,age=int32,bdate=datetime,nickname=nullstring")
+	for _, name := range []string{"=1+1", "+1", "-1", "@SUM(A1)", "\t=cmd", " \r=cmd", "a=b", "plain"} {
+		exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", name, -1, time.Unix(0, 0), nil)
+	}
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age|"))
+	converter.SanitizeFormulas = true
+
+	expected := "name,age\n'=1+1,-1\n'+1,-1\n'-1,-1\n'@SUM(A1),-1\n'\t=cmd,-1\n\"' \r=cmd\",-1\na=b,-1\nplain,-1\n"
+	assertCsvMatch(t, expected, converter.String())
+
+	converter = sqltocsv.New(queryTestRows(t, db, "SELECT|people|name|"))
+	converter.SanitizeFormulas = true
+	converter.FormulaPrefix = "_"
+	converter.SetRowPreProcessor(func(row []string, _ []string) (bool, []string) {
+		return row[0] == "_@SUM(A1)", row
+	})
+
+	assertCsvMatch(t, "name\n_@SUM(A1)\n", converter.String())
+}
+
+func TestSanitizeFormulasOff(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "=HYPERLINK(\"x\")", 2, time.Unix(0, 0), nil)
+
+	expected := "name\nAlice\n\"=HYPERLINK(\"\"x\"\")\"\n"
+	assertCsvMatch(t, expected, sqltocsv.New(queryTestRows(t, db, "SELECT|people|name|")).String())
+}
@@ -1,3 +1,23 @@
 module github.com/armantarkhanian/sqltocsv
 
-go 1.24
+go 1.24.0
+
+require (
+	github.com/jackc/pgx/v5 v5.8.0
+	github.com/klauspost/compress v1.17.9
+	github.com/parquet-go/parquet-go v0.25.1
+	github.com/pashagolub/pgxmock/v4 v4.9.0
+	golang.org/x/net v0.47.0
+	golang.org/x/text v0.31.0
+)
+
+require (
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+	golang.org/x/sync v0.18.0 // indirect
+	golang.org/x/sys v0.38.0 // indirect
+)
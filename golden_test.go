@@ -0,0 +1,75 @@
+package sqltocsv_test
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// setupTypesDatabase fills a table with a column of every type the fake
+// driver supports, with awkward values and NULLs in them.
+func setupTypesDatabase(t *testing.T) *sql.DB {
+	db := setupDatabase(t)
+	exec(t, db, "CREATE|types|s=string,b=blob,i32=int32,i64=int64,t=bool,nt=nullbool,ns=nullstring,f=float64,nf=nullfloat64,ni=nullint64,d=datetime")
+
+	// blobs can only be given inline
+	insert := "INSERT|types|s=?,b=%s,i32=?,i64=?,t=?,nt=?,ns=?,f=?,nf=?,ni=?,d=?"
+	exec(t, db, fmt.Sprintf(insert, "bytes"), "plain", 0, int64(0), false, nil, nil, 0.0, nil, nil, time.Unix(0, 0).UTC())
+	exec(t, db, fmt.Sprintf(insert, "a\"b"), "with, comma", -2147483648, int64(math.MinInt64), true, true, "nick", -1.5, 1e21, int64(42), time.Date(2009, 11, 10, 23, 0, 0, 123456789, time.UTC))
+	exec(t, db, fmt.Sprintf(insert, "ünïcode"), "with \"quotes\"\nand newline", 2147483647, int64(math.MaxInt64), true, false, "", math.MaxFloat64, 1e-7, int64(-42), time.Date(1999, 12, 31, 23, 59, 59, 0, time.FixedZone("", -5*3600)))
+	exec(t, db, fmt.Sprintf(insert, "x y"), "", 1234, int64(1234567890123), false, nil, nil, math.SmallestNonzeroFloat64, nil, nil, time.Date(2024, 2, 29, 12, 0, 0, 500, time.UTC))
+	return db
+}
+
+func TestGoldenOutput(t *testing.T) {
+	db := setupTypesDatabase(t)
+
+	for name, setup := range map[string]func(*sqltocsv.Converter){
+		"default": func(*sqltocsv.Converter) {},
+		"options": func(c *sqltocsv.Converter) {
+			c.TimeFormat = "2006-01-02 15:04:05.000"
+			c.TimeLocation = time.FixedZone("", 3600)
+			c.FloatFormat = "%.3f"
+			c.TrueString, c.FalseString = "yes", "no"
+			c.NullString = "NULL"
+			c.BinaryConverter = sqltocsv.Hex
+		},
+		"pipelined": func(c *sqltocsv.Converter) {
+			c.Pipelined = true
+		},
+	} {
+		converter := sqltocsv.New(queryTestRows(t, db, "SELECT|types|s,b,i32,i64,t,nt,ns,f,nf,ni,d|"))
+		setup(converter)
+		actual, err := converter.WriteString()
+		if err != nil {
+			t.Fatalf("%s: error in WriteString: %v", name, err)
+		}
+
+		golden := filepath.Join("testdata", name+".golden.csv")
+		if name == "pipelined" {
+			golden = filepath.Join("testdata", "default.golden.csv")
+		}
+		if *updateGolden && name != "pipelined" {
+			if err := os.WriteFile(golden, []byte(actual), 0o644); err != nil {
+				t.Fatalf("error updating %s: %v", golden, err)
+			}
+		}
+
+		expected, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("error reading %s: %v", golden, err)
+		}
+		if actual != string(expected) {
+			t.Errorf("%s: output doesn't match %s\nexpected:\n%s\nactual:\n%s", name, golden, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package sqltocsv
+
+import (
+	"compress/gzip"
+	"database/sql"
+)
+
+// WriteGzipFile will write a gzip compressed CSV file to the file name
+// specified (with headers) based on whatever is in the sql.Rows you pass in.
+func WriteGzipFile(gzipFileName string, rows *sql.Rows) error {
+	return newClosing(rows).WriteGzipFile(gzipFileName)
+}
+
+// WriteGzipFile writes the CSV gzip compressed to the filename specified,
+// return an error if problem. The gzip stream is closed before the file so
+// the footer is always written, and errors from either close are returned.
+func (c Converter) WriteGzipFile(gzipFileName string) (err error) {
+	defer c.closeRows(&err)
+	c.ownsRows, c.CloseRows = false, false // closed by the defer, just the once
+
+	f, err := c.createFile(gzipFileName)
+	if err != nil {
+		return err
+	}
+
+	gz, err := gzip.NewWriterLevel(f, c.gzipLevel())
+	if err != nil {
+		f.abort()
+		return err
+	}
+
+	err = c.Write(gz)
+	if err != nil {
+		gz.Close() // close, but only return/handle the write error
+		f.abort()
+		return err
+	}
+
+	if err = gz.Close(); err != nil {
+		f.abort()
+		return err
+	}
+
+	return f.commit()
+}
+
+// gzipLevel is the level to compress at: GzipLevel, gzip.DefaultCompression
+// if it's unset, or gzip.NoCompression with GzipUncompressed, as 0 can't be
+// told apart from an unset GzipLevel.
+func (c Converter) gzipLevel() int {
+	switch {
+	case c.GzipUncompressed:
+		return gzip.NoCompression
+	case c.GzipLevel == 0:
+		return gzip.DefaultCompression
+	}
+	return c.GzipLevel
+}
@@ -0,0 +1,80 @@
+package sqltocsv_test
+
+import (
+	"compress/gzip"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestWriteGzipFile(t *testing.T) {
+	checkQueryAgainstResult(t, func(rows *sql.Rows) string {
+		gzipFileName := filepath.Join(t.TempDir(), "test.csv.gz")
+		err := sqltocsv.WriteGzipFile(gzipFileName, rows)
+		if err != nil {
+			t.Fatalf("error in WriteGzipFile: %v", err)
+		}
+
+		return readGzipFile(t, gzipFileName)
+	})
+}
+
+func TestGzipLevel(t *testing.T) {
+	converter := getConverter(t)
+	converter.GzipLevel = gzip.BestCompression
+
+	gzipFileName := filepath.Join(t.TempDir(), "test.csv.gz")
+	if err := converter.WriteGzipFile(gzipFileName); err != nil {
+		t.Fatalf("error in WriteGzipFile: %v", err)
+	}
+
+	expected := "name,age,bdate\nAlice,1,1973-11-29T21:33:09Z\n"
+	assertCsvMatch(t, expected, readGzipFile(t, gzipFileName))
+
+	converter = getConverter(t)
+	converter.GzipLevel = 42
+	if err := converter.WriteGzipFile(gzipFileName); err == nil {
+		t.Error("expected error for invalid gzip level")
+	}
+}
+
+func TestGzipUncompressed(t *testing.T) {
+	value := strings.Repeat("Alice", 100)
+	converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"name"}, rows: [][]any{{value}}})
+	converter.GzipUncompressed = true
+
+	gzipFileName := filepath.Join(t.TempDir(), "test.csv.gz")
+	if err := converter.WriteGzipFile(gzipFileName); err != nil {
+		t.Fatalf("error in WriteGzipFile: %v", err)
+	}
+
+	expected := "name\n" + value + "\n"
+	assertCsvMatch(t, expected, readGzipFile(t, gzipFileName))
+	if raw := readFile(t, gzipFileName); !strings.Contains(raw, expected) {
+		t.Error("expected the CSV to be stored in the gzip stream as it is")
+	}
+}
+
+func readGzipFile(t *testing.T, name string) string {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("error opening %v: %v", name, err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("error reading gzip header of %v: %v", name, err)
+	}
+
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("error decompressing %v: %v", name, err)
+	}
+	return string(data)
+}
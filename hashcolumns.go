@@ -0,0 +1,87 @@
+package sqltocsv
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+)
+
+// HashAlgorithm is the hash a HashSpec pseudonymizes values with.
+type HashAlgorithm int
+
+const (
+	// SHA-256, the default.
+	HashSHA256 HashAlgorithm = iota
+	// SHA-512.
+	HashSHA512
+)
+
+// HashEncoding is how a HashSpec writes the hash of a value.
+type HashEncoding int
+
+const (
+	// Lowercase hexadecimal, the default.
+	HashHex HashEncoding = iota
+	// Standard base64 encoding, as defined in RFC 4648.
+	HashBase64
+	// Unpadded alternate base64 encoding defined in RFC 4648, for tokens
+	// that go in URLs and file names.
+	HashRawURLBase64
+)
+
+// HashSpec is how the values of a column listed in HashColumns are replaced
+// by an opaque token, the same for the same value in every export made with
+// the same HashSpec, so the column can still be joined on or counted.
+type HashSpec struct {
+	Algorithm HashAlgorithm // Hash to use (default is HashSHA256)
+	Salt      []byte        // Secret key the hash is made an HMAC with, so that the tokens of a small set of values, such as user IDs, can't be found by hashing every one of them (default is none, a plain hash)
+	Encoding  HashEncoding  // How to write the hash (default is HashHex)
+	Length    int           // Number of characters to cut the token down to (default is 0, the whole of it)
+}
+
+// hashMask returns a MaskFunc writing the token spec makes for a value. The
+// error doesn't include the Salt.
+func (spec HashSpec) hashMask() (MaskFunc, error) {
+	var newHash func() hash.Hash
+	switch spec.Algorithm {
+	case HashSHA256:
+		newHash = sha256.New
+	case HashSHA512:
+		newHash = sha512.New
+	default:
+		return nil, fmt.Errorf("unknown Algorithm %d", spec.Algorithm)
+	}
+	var encode func([]byte) string
+	switch spec.Encoding {
+	case HashHex:
+		encode = hex.EncodeToString
+	case HashBase64:
+		encode = base64.StdEncoding.EncodeToString
+	case HashRawURLBase64:
+		encode = base64.RawURLEncoding.EncodeToString
+	default:
+		return nil, fmt.Errorf("unknown Encoding %d", spec.Encoding)
+	}
+	if spec.Length < 0 {
+		return nil, fmt.Errorf("negative Length %d", spec.Length)
+	}
+
+	h := newHash()
+	if spec.Salt != nil {
+		h = hmac.New(newHash, spec.Salt)
+	}
+	// a row is masked at a time, so the one hash can be reused
+	return func(value string) string {
+		h.Reset()
+		h.Write([]byte(value))
+		token := encode(h.Sum(nil))
+		if spec.Length > 0 && spec.Length < len(token) {
+			token = token[:spec.Length]
+		}
+		return token
+	}, nil
+}
@@ -0,0 +1,100 @@
+package sqltocsv_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func hashedExport(t *testing.T, spec sqltocsv.HashSpec, rows [][]any) string {
+	t.Helper()
+	converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"user_id"}, rows: rows})
+	converter.NullString = `\N`
+	converter.HashColumns = map[string]sqltocsv.HashSpec{"user_id": spec}
+	csv, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	return csv
+}
+
+func TestHashColumns(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		spec     sqltocsv.HashSpec
+		expected string
+	}{
+		{"sha256", sqltocsv.HashSpec{}, "73475cb40a568e8da8a045ced110137e159f890ac4da883b6b17dc651b3a8049"},
+		{"salted", sqltocsv.HashSpec{Salt: []byte("pepper")}, "05072a49e7c724c2ac3638d3a54eb8848d39fb952094ee45037176152e15d416"},
+		{"truncated", sqltocsv.HashSpec{Salt: []byte("pepper"), Length: 16}, "05072a49e7c724c2"},
+		{"sha512 base64", sqltocsv.HashSpec{Algorithm: sqltocsv.HashSHA512, Encoding: sqltocsv.HashBase64, Length: 12}, "Ocp86ezGn2lr"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			csv := hashedExport(t, test.spec, [][]any{{int64(42)}, {nil}})
+			assertCsvMatch(t, "user_id\n"+test.expected+"\n\\N\n", csv)
+		})
+	}
+}
+
+func TestHashColumnsDeterministic(t *testing.T) {
+	rows := func() [][]any {
+		return [][]any{{int64(1)}, {"alice"}, {[]byte("bob")}, {int64(1)}}
+	}
+	spec := sqltocsv.HashSpec{Salt: []byte("s3cret"), Encoding: sqltocsv.HashRawURLBase64}
+
+	first := hashedExport(t, spec, rows())
+	second := hashedExport(t, spec, rows())
+	if first != second {
+		t.Errorf("expected the same tokens from two exports with the same salt, got\n%s\nand\n%s", first, second)
+	}
+	lines := strings.Split(first, "\n")
+	if lines[1] != lines[4] || lines[1] == lines[2] {
+		t.Errorf("expected equal values to get equal tokens and others not, got %q", lines)
+	}
+
+	spec.Salt = []byte("other")
+	if other := hashedExport(t, spec, rows()); other == first {
+		t.Errorf("expected a different salt to give different tokens, got %q for both", other)
+	}
+}
+
+func TestHashColumnsStats(t *testing.T) {
+	converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"user_id", "email"}, rows: [][]any{{int64(1), "a@example.com"}}})
+	converter.HashColumns = map[string]sqltocsv.HashSpec{"user_id": {}}
+	converter.MaskColumns = map[string]sqltocsv.MaskFunc{"email": sqltocsv.MaskEmail}
+	_, stats, err := converter.WriteStringWithStats()
+	if err != nil {
+		t.Fatalf("error in WriteStringWithStats: %v", err)
+	}
+	if !reflect.DeepEqual(stats.HashedColumns, []string{"user_id"}) || !reflect.DeepEqual(stats.MaskedColumns, []string{"email"}) {
+		t.Errorf("expected user_id hashed and email masked, got %v and %v", stats.HashedColumns, stats.MaskedColumns)
+	}
+}
+
+func TestHashColumnsInvalid(t *testing.T) {
+	salt := "do-not-leak-me"
+	for _, spec := range []sqltocsv.HashSpec{
+		{Algorithm: 99, Salt: []byte(salt)},
+		{Encoding: 99, Salt: []byte(salt)},
+		{Length: -1, Salt: []byte(salt)},
+	} {
+		converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"user_id"}, rows: [][]any{{int64(1)}}})
+		converter.HashColumns = map[string]sqltocsv.HashSpec{"user_id": spec}
+		_, err := converter.WriteString()
+		if err == nil {
+			t.Fatal("expected an error for an invalid HashSpec")
+		}
+		if strings.Contains(err.Error(), salt) {
+			t.Errorf("expected the error not to contain the salt, got %v", err)
+		}
+	}
+
+	converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"user_id"}, rows: [][]any{{int64(1)}}})
+	converter.HashColumns = map[string]sqltocsv.HashSpec{"user_id": {}}
+	converter.MaskColumns = map[string]sqltocsv.MaskFunc{"user_id": sqltocsv.MaskAll}
+	if _, err := converter.WriteString(); err == nil {
+		t.Error("expected an error for a column in both MaskColumns and HashColumns")
+	}
+}
@@ -0,0 +1,128 @@
+package sqltocsv
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// HeaderCase is how the header row is cased when Headers isn't set.
+type HeaderCase int
+
+const (
+	// The column names as rows.Columns() returns them.
+	HeaderAsIs HeaderCase = iota
+	// all lower case
+	HeaderLower
+	// ALL UPPER CASE
+	HeaderUpper
+	// snake_case, splitting words at spaces, punctuation and camelCase
+	// humps, so "userID" and "User ID" both become "user_id"
+	HeaderSnake
+	// Title Case, splitting words the same way as HeaderSnake, so
+	// "user_id" becomes "User Id"
+	HeaderTitle
+)
+
+// makeHeaders works out the header row for the output columns, named
+// columnNames, before any extra columns are added.
+func (c Converter) makeHeaders(queryColumns, columnNames []string) ([]string, error) {
+	if len(c.Headers) > 0 && len(c.HeaderMap) > 0 {
+		return nil, errors.New("Headers and HeaderMap can't both be set")
+	}
+	if len(c.Headers) > 0 {
+		return c.Headers, nil
+	}
+
+	if c.StrictHeaderMap {
+		for _, name := range slices.Sorted(maps.Keys(c.HeaderMap)) {
+			if !slices.Contains(queryColumns, name) {
+				return nil, fmt.Errorf("HeaderMap column %q is not in the result set", name)
+			}
+		}
+	}
+
+	if len(c.HeaderMap) == 0 && c.headerTransform == nil && c.HeaderCase == HeaderAsIs {
+		return columnNames, nil
+	}
+	headers := make([]string, len(columnNames))
+	for i, name := range columnNames {
+		if header, ok := c.HeaderMap[name]; ok {
+			headers[i] = header
+			continue
+		}
+		header := c.HeaderCase.apply(name)
+		if c.headerTransform != nil {
+			header = c.headerTransform(header)
+		}
+		headers[i] = header
+	}
+	return headers, nil
+}
+
+// apply returns name in the case hc asks for.
+func (hc HeaderCase) apply(name string) string {
+	switch hc {
+	case HeaderLower:
+		return strings.ToLower(name)
+	case HeaderUpper:
+		return strings.ToUpper(name)
+	case HeaderSnake:
+		words := headerWords(name)
+		if len(words) == 0 {
+			return name
+		}
+		for i, word := range words {
+			words[i] = strings.ToLower(word)
+		}
+		return strings.Join(words, "_")
+	case HeaderTitle:
+		words := headerWords(name)
+		if len(words) == 0 {
+			return name
+		}
+		for i, word := range words {
+			runes := []rune(strings.ToLower(word))
+			runes[0] = unicode.ToUpper(runes[0])
+			words[i] = string(runes)
+		}
+		return strings.Join(words, " ")
+	}
+	return name
+}
+
+// headerWords splits a column name into words at anything other than letters
+// and digits, and where the case changes: before an upper case letter that
+// follows a lower case letter or a digit, and before the last of a run of
+// upper case letters that's followed by a lower case one, so "HTTPServer"
+// is "HTTP" and "Server".
+func headerWords(name string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = word[:0]
+			}
+			continue
+		}
+		if len(word) > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				words = append(words, string(word))
+				word = word[:0]
+			}
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
@@ -0,0 +1,93 @@
+package sqltocsv_test
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+// headersFor returns the header row written for a result set with the named
+// columns, after setup has been applied to the Converter.
+func headersFor(t *testing.T, names []string, setup func(*sqltocsv.Converter)) string {
+	columns := make([]typedColumn, len(names))
+	for i, name := range names {
+		columns[i] = typedColumn{name, "VARCHAR", reflect.TypeFor[string]()}
+	}
+	db := sql.OpenDB(typedConnector{columns: columns})
+	defer db.Close()
+	rows, err := db.Query("SELECT")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+	defer rows.Close()
+
+	converter := sqltocsv.New(rows)
+	setup(converter)
+	csv, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	return strings.TrimSuffix(csv, "\n")
+}
+
+func TestHeaderCase(t *testing.T) {
+	for _, test := range []struct {
+		name                       string
+		lower, upper, snake, title string
+	}{
+		{"userID", "userid", "USERID", "user_id", "User Id"},
+		{"HTTPServer", "httpserver", "HTTPSERVER", "http_server", "Http Server"},
+		{"First Name", "first name", "FIRST NAME", "first_name", "First Name"},
+		{"already_snake", "already_snake", "ALREADY_SNAKE", "already_snake", "Already Snake"},
+		{"ORDER_ID", "order_id", "ORDER_ID", "order_id", "Order Id"},
+		{"col1Name", "col1name", "COL1NAME", "col1_name", "Col1 Name"},
+		{"v2", "v2", "V2", "v2", "V2"},
+		{"  Total -- Amount (EUR) ", "  total -- amount (eur) ", "  TOTAL -- AMOUNT (EUR) ", "total_amount_eur", "Total Amount Eur"},
+		{"ÜberGröße", "übergröße", "ÜBERGRÖßE", "über_größe", "Über Größe"},
+		{"?", "?", "?", "?", "?"},
+	} {
+		for headerCase, expected := range map[sqltocsv.HeaderCase]string{
+			sqltocsv.HeaderAsIs:  test.name,
+			sqltocsv.HeaderLower: test.lower,
+			sqltocsv.HeaderUpper: test.upper,
+			sqltocsv.HeaderSnake: test.snake,
+			sqltocsv.HeaderTitle: test.title,
+		} {
+			actual := headersFor(t, []string{test.name}, func(c *sqltocsv.Converter) {
+				c.HeaderCase = headerCase
+			})
+			if strings.HasPrefix(expected, " ") {
+				expected = `"` + expected + `"`
+			}
+			if actual != expected {
+				t.Errorf("%q in case %d: expected %s, got %s", test.name, headerCase, expected, actual)
+			}
+		}
+	}
+}
+
+func TestHeaderCaseWithOtherHeaderOptions(t *testing.T) {
+	names := []string{"UserID", "FirstName", "LastName"}
+
+	// an explicit mapping wins, and the transform gets the cased name
+	actual := headersFor(t, names, func(c *sqltocsv.Converter) {
+		c.HeaderCase = sqltocsv.HeaderSnake
+		c.HeaderMap = map[string]string{"UserID": "ID"}
+		c.SetHeaderTransform(func(name string) string { return "x_" + name })
+	})
+	if expected := "ID,x_first_name,x_last_name"; actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+
+	// and Headers is used as it is
+	actual = headersFor(t, names, func(c *sqltocsv.Converter) {
+		c.HeaderCase = sqltocsv.HeaderUpper
+		c.Headers = []string{"a", "b", "c"}
+	})
+	if expected := "a,b,c"; actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
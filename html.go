@@ -0,0 +1,63 @@
+package sqltocsv
+
+import (
+	"bufio"
+	"context"
+	"html"
+	"io"
+)
+
+// WriteHTML writes the rows to the Writer provided as an HTML <table>, with
+// the headers in a <thead> (unless WriteHeaders is false) and the rows in a
+// <tbody>. Values go through the same conversion, column selection and
+// preprocessor as Write, and are HTML escaped. The table gets the class in
+// HTMLClass if it is set, and NULLs are written as HTMLNull if it is set.
+func (c Converter) WriteHTML(writer io.Writer) (err error) {
+	defer c.closeRows(&err)
+
+	p, err := c.newPlan()
+	if err != nil {
+		return err
+	}
+	p.keepValues = c.HTMLNull != ""
+
+	bufWriter := bufio.NewWriter(writer)
+	if c.HTMLClass != "" {
+		bufWriter.WriteString(`<table class="` + html.EscapeString(c.HTMLClass) + `">` + "\n")
+	} else {
+		bufWriter.WriteString("<table>\n")
+	}
+	if c.WriteHeaders {
+		bufWriter.WriteString("<thead>\n")
+		writeHTMLRow(bufWriter, "th", p.headers, nil, "")
+		bufWriter.WriteString("</thead>\n")
+	}
+
+	bufWriter.WriteString("<tbody>\n")
+	err = c.eachRow(context.Background(), p, func(row []string, values []any) error {
+		writeHTMLRow(bufWriter, "td", row, values, c.HTMLNull)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	bufWriter.WriteString("</tbody>\n</table>\n")
+
+	return bufWriter.Flush()
+}
+
+// writeHTMLRow writes fields as a <tr> of cells. Fields whose value is nil
+// are written as null, unescaped, if it isn't empty.
+func writeHTMLRow(w *bufio.Writer, cell string, fields []string, values []any, null string) {
+	w.WriteString("<tr>")
+	for i, field := range fields {
+		w.WriteString("<" + cell + ">")
+		if null != "" && i < len(values) && values[i] == nil {
+			w.WriteString(null)
+		} else {
+			w.WriteString(html.EscapeString(field))
+		}
+		w.WriteString("</" + cell + ">")
+	}
+	w.WriteString("</tr>\n")
+}
@@ -0,0 +1,110 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+// parseHTMLTable parses a table written by WriteHTML into its class and the
+// text of its header and body cells, row by row. A NULL written as &nbsp;
+// comes back as " ".
+func parseHTMLTable(t *testing.T, s string) (class string, head, body [][]string) {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("error parsing HTML: %v", err)
+	}
+
+	var section *[][]string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "table":
+				for _, attr := range n.Attr {
+					if attr.Key == "class" {
+						class = attr.Val
+					}
+				}
+			case "thead":
+				section = &head
+			case "tbody":
+				section = &body
+			case "tr":
+				*section = append(*section, nil)
+			case "th", "td":
+				var text strings.Builder
+				for child := n.FirstChild; child != nil; child = child.NextSibling {
+					text.WriteString(child.Data)
+				}
+				last := &(*section)[len(*section)-1]
+				*last = append(*last, text.String())
+				return
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+	return class, head, body
+}
+
+func TestWriteHTML(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "<b>Bob</b> & \"Co\"", 2, time.Unix(0, 0), "bobby")
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age,bdate,nickname|"))
+	converter.ExcludeColumns = []string{"bdate"}
+	converter.HTMLClass = `report "weekly"`
+	converter.HTMLNull = "&nbsp;"
+
+	var out bytes.Buffer
+	if err := converter.WriteHTML(&out); err != nil {
+		t.Fatalf("error in WriteHTML: %v", err)
+	}
+
+	class, head, body := parseHTMLTable(t, out.String())
+	if class != `report "weekly"` {
+		t.Errorf("expected the table class, got %q", class)
+	}
+	expectRows(t, "head", [][]string{{"name", "age", "nickname"}}, head)
+	expectRows(t, "body", [][]string{{"Alice", "1", "\u00a0"}, {"<b>Bob</b> & \"Co\"", "2", "bobby"}}, body)
+}
+
+func TestWriteHTMLPreProcessor(t *testing.T) {
+	converter := getConverter(t)
+	converter.WriteHeaders = false
+	converter.NullString = "-"
+	converter.SetRowPreProcessor(func(row []string, _ []string) (bool, []string) {
+		return true, append(row, "<extra>")
+	})
+	converter.AllowRaggedRows = true
+
+	var out bytes.Buffer
+	if err := converter.WriteHTML(&out); err != nil {
+		t.Fatalf("error in WriteHTML: %v", err)
+	}
+
+	expected := "<table>\n<tbody>\n<tr><td>Alice</td><td>1</td><td>1973-11-29T21:33:09Z</td><td>&lt;extra&gt;</td></tr>\n</tbody>\n</table>\n"
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func expectRows(t *testing.T, what string, expected, actual [][]string) {
+	t.Helper()
+	if len(expected) != len(actual) {
+		t.Fatalf("expected %d %s rows, got %d: %q", len(expected), what, len(actual), actual)
+	}
+	for i := range expected {
+		if strings.Join(expected[i], "\x00") != strings.Join(actual[i], "\x00") {
+			t.Errorf("%s row %d: expected %q, got %q", what, i, expected[i], actual[i])
+		}
+	}
+}
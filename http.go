@@ -0,0 +1,33 @@
+package sqltocsv
+
+import (
+	"database/sql"
+	"mime"
+	"net/http"
+)
+
+// serveFlushEvery is how many rows ServeCSV writes between flushes when
+// neither FlushEvery nor FlushInterval is set.
+const serveFlushEvery = 1000
+
+// ServeCSV streams the CSV (with headers) to w as a file download named
+// filename. See Converter.ServeCSV.
+func ServeCSV(w http.ResponseWriter, r *http.Request, rows *sql.Rows, filename string) error {
+	return newClosing(rows).ServeCSV(w, r, filename)
+}
+
+// ServeCSV streams the CSV to w as a file download named filename, flushing
+// along the way if w is an http.Flusher (every 1000 rows unless FlushEvery or
+// FlushInterval says otherwise). The export stops when the request's
+// context is cancelled. Once the first bytes are out the status can no longer
+// be changed, so errors are returned for the caller to log.
+func (c Converter) ServeCSV(w http.ResponseWriter, r *http.Request, filename string) error {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	if c.FlushEvery == 0 && c.FlushInterval == 0 {
+		c.FlushEvery = serveFlushEvery
+	}
+
+	return c.writeCSV(r.Context(), w)
+}
@@ -0,0 +1,60 @@
+package sqltocsv_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestServeCSV(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "/report.csv", nil)
+
+	err := sqltocsv.ServeCSV(recorder, request, getTestRows(t), "report.csv")
+	if err != nil {
+		t.Fatalf("error in ServeCSV: %v", err)
+	}
+
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "text/csv; charset=utf-8" {
+		t.Errorf("unexpected Content-Type %q", contentType)
+	}
+	if disposition := recorder.Header().Get("Content-Disposition"); disposition != "attachment; filename=report.csv" {
+		t.Errorf("unexpected Content-Disposition %q", disposition)
+	}
+	assertCsvMatch(t, "name,age,bdate\nAlice,1,1973-11-29T21:33:09Z\n", recorder.Body.String())
+}
+
+func TestServeCSVCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "/report.csv", nil).WithContext(ctx)
+
+	err := getConverter(t).ServeCSV(recorder, request, "report.csv")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestServeCSVFlushes(t *testing.T) {
+	db := setupDatabase(t)
+	for i := 0; i < 1000; i++ {
+		exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "Bob", i, time.Unix(0, 0), nil)
+	}
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "/report.csv", nil)
+
+	err := sqltocsv.ServeCSV(recorder, request, queryTestRows(t, db, "SELECT|people|name|"), "report.csv")
+	if err != nil {
+		t.Fatalf("error in ServeCSV: %v", err)
+	}
+	if !recorder.Flushed {
+		t.Error("expected response to be flushed")
+	}
+}
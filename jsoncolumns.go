@@ -0,0 +1,42 @@
+package sqltocsv
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// errInvalidJSON is returned for values of JSONColumns that aren't JSON
+// when StrictJSON is set.
+var errInvalidJSON = errors.New("invalid JSON")
+
+// jsonColumnText converts a value of one of the JSONColumns, reporting
+// false for values that aren't text, such as NULLs, to be converted as
+// usual.
+func (c Converter) jsonColumnText(v any) (string, bool, error) {
+	var data []byte
+	switch val := v.(type) {
+	case []byte:
+		data = val
+	case string:
+		if !c.CompactJSON && !c.StrictJSON {
+			return val, true, nil
+		}
+		data = []byte(val)
+	default:
+		return "", false, nil
+	}
+
+	if c.CompactJSON {
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, data); err == nil {
+			return compacted.String(), true, nil
+		}
+	} else if !c.StrictJSON || json.Valid(data) {
+		return string(data), true, nil
+	}
+	if c.StrictJSON {
+		return "", true, errInvalidJSON
+	}
+	return string(data), true, nil
+}
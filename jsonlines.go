@@ -0,0 +1,102 @@
+package sqltocsv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+// WriteJSONLines writes the rows to the Writer provided as newline delimited
+// JSON, one object per row keyed by the headers. Values go through the same
+// conversion and preprocessor as Write, but numbers and booleans that reach
+// the output unchanged are written as JSON numbers and booleans (unless
+// JSONStringsOnly is set) and NULLs are always written as null.
+func (c Converter) WriteJSONLines(writer io.Writer) (err error) {
+	defer c.closeRows(&err)
+
+	p, err := c.newPlan()
+	if err != nil {
+		return err
+	}
+	p.keepValues = true
+
+	keys := make([][]byte, len(p.headers))
+	for i, header := range p.headers {
+		if keys[i], err = marshalJSON(header); err != nil {
+			return err
+		}
+	}
+
+	bufWriter := bufio.NewWriter(writer)
+	rowNumber := 0
+	err = c.eachRow(context.Background(), p, func(row []string, values []any) error {
+		rowNumber++
+		if len(row) != len(keys) {
+			return fmt.Errorf("row %d has %d fields but there are %d headers", rowNumber, len(row), len(keys))
+		}
+
+		bufWriter.WriteByte('{')
+		for i, field := range row {
+			if i > 0 {
+				bufWriter.WriteByte(',')
+			}
+			bufWriter.Write(keys[i])
+			bufWriter.WriteByte(':')
+
+			value, err := marshalJSON(c.jsonValue(values[i], field))
+			if err != nil {
+				return fmt.Errorf("failed to encode column %q in row %d: %w", p.headers[i], rowNumber, err)
+			}
+			bufWriter.Write(value)
+		}
+		bufWriter.WriteString("}\n")
+		return nil
+	})
+
+	if flushErr := bufWriter.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// jsonValue picks what to encode for a field: the typed value for NULLs,
+// booleans and finite numbers, and the converted string for anything else.
+func (c Converter) jsonValue(value any, field string) any {
+	if value == nil {
+		return nil
+	}
+	if c.JSONStringsOnly {
+		return field
+	}
+
+	switch val := value.(type) {
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return val
+	case float32:
+		if math.IsNaN(float64(val)) || math.IsInf(float64(val), 0) {
+			return field
+		}
+		return val
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return field
+		}
+		return val
+	}
+	return field
+}
+
+// marshalJSON is json.Marshal without the HTML escaping.
+func marshalJSON(v any) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
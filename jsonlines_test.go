@@ -0,0 +1,62 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestWriteJSONLines(t *testing.T) {
+	converter := sqltocsv.New(getTestRowsByQuery(t, "SELECT|people|name,nickname,age,bdate|"))
+
+	buffer := &bytes.Buffer{}
+	if err := converter.WriteJSONLines(buffer); err != nil {
+		t.Fatalf("error in WriteJSONLines: %v", err)
+	}
+
+	expected := `{"name":"Alice","nickname":null,"age":1,"bdate":"1973-11-29T21:33:09Z"}` + "\n"
+	assertCsvMatch(t, expected, buffer.String())
+}
+
+func TestWriteJSONLinesStringsOnly(t *testing.T) {
+	converter := sqltocsv.New(getTestRowsByQuery(t, "SELECT|people|name,nickname,age|"))
+	converter.JSONStringsOnly = true
+	converter.Headers = []string{"Name", "Nickname", "Age"}
+
+	buffer := &bytes.Buffer{}
+	if err := converter.WriteJSONLines(buffer); err != nil {
+		t.Fatalf("error in WriteJSONLines: %v", err)
+	}
+
+	expected := `{"Name":"Alice","Nickname":null,"Age":"1"}` + "\n"
+	assertCsvMatch(t, expected, buffer.String())
+}
+
+func TestWriteJSONLinesPreProcessor(t *testing.T) {
+	converter := getConverter(t)
+	converter.SetRowPreProcessor(func(row []string, columnNames []string) (bool, []string) {
+		return true, []string{row[0], "X", row[2]}
+	})
+
+	buffer := &bytes.Buffer{}
+	if err := converter.WriteJSONLines(buffer); err != nil {
+		t.Fatalf("error in WriteJSONLines: %v", err)
+	}
+
+	expected := `{"name":"Alice","age":"X","bdate":"1973-11-29T21:33:09Z"}` + "\n"
+	assertCsvMatch(t, expected, buffer.String())
+}
+
+func TestWriteJSONLinesRowNumber(t *testing.T) {
+	converter := sqltocsv.New(getTestRowsByQuery(t, "SELECT|people|name,nickname,age|"))
+	converter.RowNumberHeader = "row"
+
+	buffer := &bytes.Buffer{}
+	if err := converter.WriteJSONLines(buffer); err != nil {
+		t.Fatalf("error in WriteJSONLines: %v", err)
+	}
+
+	expected := `{"row":1,"name":"Alice","nickname":null,"age":1}` + "\n"
+	assertCsvMatch(t, expected, buffer.String())
+}
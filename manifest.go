@@ -0,0 +1,97 @@
+package sqltocsv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+)
+
+// ChunkInfo describes a file written by WriteFileChunks, see Chunks.
+type ChunkInfo struct {
+	Path  string // File name, as made from the pattern
+	Rows  int64  // Data rows in the file, not counting the header
+	Bytes int64  // Size of the file
+}
+
+// ManifestFormat is the format of the manifests written by WriteManifest, in
+// Converter.ManifestFormat.
+type ManifestFormat int
+
+const (
+	// ManifestRedshift is the JSON manifest Redshift's COPY reads with
+	// the MANIFEST option, each file a mandatory entry with its
+	// content_length:
+	//
+	//	{"entries":[{"url":"s3://bucket/export-1.csv","mandatory":true,"meta":{"content_length":1024}}]}
+	ManifestRedshift ManifestFormat = iota
+	// ManifestSymlink is the symlink manifest of Athena and Hive's
+	// SymlinkTextInputFormat, a line with the location of each file.
+	ManifestSymlink
+)
+
+// Chunks returns a ChunkInfo for each file written by the most recent
+// WriteFileChunks, including any closed before an error occurred.
+func (c Converter) Chunks() []ChunkInfo {
+	if c.last == nil {
+		return nil
+	}
+	return c.last.chunks
+}
+
+// WriteManifest writes a manifest listing the files of entries, such as
+// those from Chunks, to the filename specified, in ManifestFormat. Each file
+// is listed by its Path or, with ManifestPrefix set, by ManifestPrefix
+// followed by the file's base name.
+func (c Converter) WriteManifest(manifestFileName string, entries []ChunkInfo) error {
+	var manifest []byte
+	switch c.ManifestFormat {
+	case ManifestRedshift:
+		type meta struct {
+			ContentLength int64 `json:"content_length"`
+		}
+		type entry struct {
+			URL       string `json:"url"`
+			Mandatory bool   `json:"mandatory"`
+			Meta      meta   `json:"meta"`
+		}
+		redshift := struct {
+			Entries []entry `json:"entries"`
+		}{Entries: make([]entry, len(entries))}
+		for i, chunk := range entries {
+			redshift.Entries[i] = entry{URL: c.manifestURL(chunk.Path), Mandatory: true, Meta: meta{chunk.Bytes}}
+		}
+		var err error
+		if manifest, err = json.MarshalIndent(redshift, "", "  "); err != nil {
+			return fmt.Errorf("failed to encode manifest: %w", err)
+		}
+		manifest = append(manifest, '\n')
+	case ManifestSymlink:
+		var buf bytes.Buffer
+		for _, chunk := range entries {
+			buf.WriteString(c.manifestURL(chunk.Path))
+			buf.WriteByte('\n')
+		}
+		manifest = buf.Bytes()
+	default:
+		return fmt.Errorf("unknown ManifestFormat %d", c.ManifestFormat)
+	}
+
+	f, err := c.createFile(manifestFileName)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(manifest); err != nil {
+		f.abort()
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return f.commit()
+}
+
+// manifestURL is how a manifest lists the file name.
+func (c Converter) manifestURL(name string) string {
+	if c.ManifestPrefix == "" {
+		return name
+	}
+	return c.ManifestPrefix + filepath.Base(name)
+}
@@ -0,0 +1,65 @@
+package sqltocsv_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestWriteFileChunksManifest(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=Bob,age=?,bdate=?,nickname=?", 2, time.Unix(0, 0), nil)
+	exec(t, db, "INSERT|people|name=Carol,age=?,bdate=?,nickname=?", 3, time.Unix(0, 0), nil)
+
+	dir := t.TempDir()
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age|"))
+	converter.ManifestFile = filepath.Join(dir, "export.manifest")
+	converter.ManifestPrefix = "s3://bucket/exports/"
+	names, err := converter.WriteFileChunks(filepath.Join(dir, "export-%d.csv"), 2)
+	if err != nil {
+		t.Fatalf("error in WriteFileChunks: %v", err)
+	}
+
+	expectedChunks := []sqltocsv.ChunkInfo{
+		{Path: names[0], Rows: 2, Bytes: int64(len("name,age\nAlice,1\nBob,2\n"))},
+		{Path: names[1], Rows: 1, Bytes: int64(len("name,age\nCarol,3\n"))},
+	}
+	if chunks := converter.Chunks(); !reflect.DeepEqual(chunks, expectedChunks) {
+		t.Errorf("expected chunks %+v, got %+v", expectedChunks, chunks)
+	}
+
+	data, err := os.ReadFile(converter.ManifestFile)
+	if err != nil {
+		t.Fatalf("error reading the manifest: %v", err)
+	}
+	var manifest map[string]any
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("error decoding %s: %v", data, err)
+	}
+	expected := map[string]any{"entries": []any{
+		map[string]any{"url": "s3://bucket/exports/export-1.csv", "mandatory": true, "meta": map[string]any{"content_length": 23.0}},
+		map[string]any{"url": "s3://bucket/exports/export-2.csv", "mandatory": true, "meta": map[string]any{"content_length": 17.0}},
+	}}
+	if !reflect.DeepEqual(manifest, expected) {
+		t.Errorf("expected manifest %v, got %v", expected, manifest)
+	}
+}
+
+func TestWriteManifestSymlink(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "symlink.txt")
+	converter := getConverter(t)
+	converter.ManifestFormat = sqltocsv.ManifestSymlink
+	entries := []sqltocsv.ChunkInfo{{Path: "s3://bucket/a.csv"}, {Path: "s3://bucket/b.csv"}}
+	if err := converter.WriteManifest(name, entries); err != nil {
+		t.Fatalf("error in WriteManifest: %v", err)
+	}
+
+	if contents := readFile(t, name); contents != "s3://bucket/a.csv\ns3://bucket/b.csv\n" {
+		t.Errorf("unexpected symlink manifest %q", contents)
+	}
+}
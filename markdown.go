@@ -0,0 +1,107 @@
+package sqltocsv
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+)
+
+// Alignment is how a column of a Markdown table is aligned.
+type Alignment int
+
+const (
+	// Leave the alignment to whatever renders the table (usually left).
+	AlignDefault Alignment = iota
+	// Align the column to the left.
+	AlignLeft
+	// Align the column to the right.
+	AlignRight
+	// Center the column.
+	AlignCenter
+)
+
+// markdownCellReplacer escapes the characters that would break a row of a
+// Markdown table, and backslashes so that one ending a value can't escape
+// the | after it. A \r\n pair counts as one line break.
+var markdownCellReplacer = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\r\n", "<br>", "\r", "<br>", "\n", "<br>")
+
+// WriteMarkdown writes the rows to the Writer provided as a Markdown table,
+// for pasting into an issue or a wiki. Values go through the same conversion
+// and preprocessor as Write. The header row is always written, as a Markdown
+// table can't do without one. Columns are aligned as set in MarkdownAlign,
+// and other columns holding numbers in the first row are aligned right.
+func (c Converter) WriteMarkdown(writer io.Writer) (err error) {
+	defer c.closeRows(&err)
+
+	p, err := c.newPlan()
+	if err != nil {
+		return err
+	}
+	p.keepValues = true
+
+	bufWriter := bufio.NewWriter(writer)
+	started := false
+	start := func(values []any) {
+		writeMarkdownRow(bufWriter, p.headers)
+		bufWriter.WriteByte('|')
+		for i := range p.headers {
+			switch c.markdownAlignment(p, i, values) {
+			case AlignLeft:
+				bufWriter.WriteString(" :--- |")
+			case AlignRight:
+				bufWriter.WriteString(" ---: |")
+			case AlignCenter:
+				bufWriter.WriteString(" :---: |")
+			default:
+				bufWriter.WriteString(" --- |")
+			}
+		}
+		bufWriter.WriteByte('\n')
+		started = true
+	}
+
+	err = c.eachRow(context.Background(), p, func(row []string, values []any) error {
+		if !started {
+			start(values)
+		}
+		writeMarkdownRow(bufWriter, row)
+		return nil
+	})
+	if err == nil && !started {
+		start(nil)
+	}
+
+	if flushErr := bufWriter.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// markdownAlignment works out the alignment of output column i, given the
+// typed values of the first row (nil if there are no rows).
+func (c Converter) markdownAlignment(p *plan, i int, values []any) Alignment {
+	if n := p.column(i); n >= 0 {
+		if alignment, ok := c.MarkdownAlign[p.columnNames[n]]; ok {
+			return alignment
+		}
+	}
+	if i < len(values) {
+		switch values[i].(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			return AlignRight
+		}
+	}
+	return AlignDefault
+}
+
+// writeMarkdownRow writes fields as a row of a Markdown table.
+func writeMarkdownRow(w *bufio.Writer, fields []string) {
+	w.WriteByte('|')
+	for _, field := range fields {
+		w.WriteByte(' ')
+		w.WriteString(markdownCellReplacer.Replace(field))
+		w.WriteString(" |")
+	}
+	w.WriteByte('\n')
+}
@@ -0,0 +1,75 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "Bob | Builder\nLtd", 2, time.Unix(0, 0), nil)
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age,bdate|"))
+	converter.TimeFormat = time.DateOnly
+	converter.MarkdownAlign = map[string]sqltocsv.Alignment{"bdate": sqltocsv.AlignCenter}
+
+	var out bytes.Buffer
+	if err := converter.WriteMarkdown(&out); err != nil {
+		t.Fatalf("error in WriteMarkdown: %v", err)
+	}
+
+	expected := "| name | age | bdate |\n" +
+		"| --- | ---: | :---: |\n" +
+		"| Alice | 1 | 1973-11-29 |\n" +
+		"| Bob \\| Builder<br>Ltd | 2 | 1970-01-01 |\n"
+	assertCsvMatch(t, expected, out.String())
+}
+
+func TestWriteMarkdownPreProcessor(t *testing.T) {
+	converter := getConverter(t)
+	converter.Headers = []string{"Name", "Age", "Born"}
+	converter.SetRowPreProcessor(func(row []string, _ []string) (bool, []string) {
+		row[1] = row[1] + " years"
+		return true, row
+	})
+
+	var out bytes.Buffer
+	if err := converter.WriteMarkdown(&out); err != nil {
+		t.Fatalf("error in WriteMarkdown: %v", err)
+	}
+
+	// age isn't a number any more, so it keeps the default alignment
+	expected := "| Name | Age | Born |\n| --- | --- | --- |\n| Alice | 1 years | 1973-11-29T21:33:09Z |\n"
+	assertCsvMatch(t, expected, out.String())
+}
+
+func TestWriteMarkdownBackslashes(t *testing.T) {
+	converter := sqltocsv.NewFromSource(&sliceSource{
+		columns: []string{"path", "note"},
+		rows:    [][]any{{`C:\temp\`, `a \| b`}},
+	})
+
+	var out bytes.Buffer
+	if err := converter.WriteMarkdown(&out); err != nil {
+		t.Fatalf("error in WriteMarkdown: %v", err)
+	}
+
+	// unescaped, the trailing backslash would escape the | closing the cell
+	expected := "| path | note |\n| --- | --- |\n| C:\\\\temp\\\\ | a \\\\\\| b |\n"
+	assertCsvMatch(t, expected, out.String())
+}
+
+func TestWriteMarkdownNoRows(t *testing.T) {
+	converter := sqltocsv.New(getEmptyTestRows(t))
+	converter.WriteHeaders = false
+
+	var out bytes.Buffer
+	if err := converter.WriteMarkdown(&out); err != nil {
+		t.Fatalf("error in WriteMarkdown: %v", err)
+	}
+
+	assertCsvMatch(t, "| name | age | bdate |\n| --- | --- | --- |\n", out.String())
+}
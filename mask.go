@@ -0,0 +1,92 @@
+package sqltocsv
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaskFunc replaces the value of a column listed in MaskColumns, such as
+// with one of MaskAll, MaskEmail or MaskLast4.
+type MaskFunc func(value string) string
+
+// maskText is what the built-in MaskFuncs write in place of what they hide.
+const maskText = "***"
+
+// MaskAll hides the whole value, writing *** in its place.
+func MaskAll(string) string {
+	return maskText
+}
+
+// MaskEmail hides the part of an email address before the @, keeping the
+// domain, so alice@example.com is written as ***@example.com. A value
+// without an @ is hidden completely.
+func MaskEmail(value string) string {
+	at := strings.LastIndexByte(value, '@')
+	if at < 0 {
+		return maskText
+	}
+	return maskText + value[at:]
+}
+
+// MaskLast4 hides all but the last four characters of the value, so a phone
+// number 555-123-4567 is written as ***4567. A value of four characters or
+// fewer is hidden completely.
+func MaskLast4(value string) string {
+	if utf8.RuneCountInString(value) <= 4 {
+		return maskText
+	}
+	end := len(value)
+	for range 4 {
+		_, size := utf8.DecodeLastRuneInString(value[:end])
+		end -= size
+	}
+	return maskText + value[end:]
+}
+
+// columnMasks returns the MaskFunc of each output column, by its query
+// column name, from MaskColumns or HashColumns, or nil if none have one, and
+// the names of the columns masked and hashed.
+func (c Converter) columnMasks(columnNames []string) (masks []MaskFunc, masked, hashed []string, err error) {
+	for i, name := range columnNames {
+		mask := c.MaskColumns[name]
+		if spec, ok := c.HashColumns[name]; ok {
+			if mask != nil {
+				return nil, nil, nil, fmt.Errorf("column %q is in both MaskColumns and HashColumns", name)
+			}
+			if mask, err = spec.hashMask(); err != nil {
+				return nil, nil, nil, fmt.Errorf("invalid HashColumns for column %q: %w", name, err)
+			}
+			hashed = append(hashed, name)
+		} else if mask != nil {
+			masked = append(masked, name)
+		} else {
+			continue
+		}
+		if masks == nil {
+			masks = make([]MaskFunc, len(columnNames))
+		}
+		masks[i] = mask
+	}
+	return masks, masked, hashed, nil
+}
+
+// maskFields applies the MaskColumns and HashColumns to a row about to be
+// written, and to its fieldValues if they're kept. NULLs are left as they
+// are, unless the preprocessor changed the row so that it's no longer known
+// which they are.
+func (c Converter) maskFields(p *plan, row []string, fields []any, fieldValues []any) {
+	sameShape := len(row) == len(fields)
+	for i, mask := range p.masks {
+		if mask == nil || i >= len(row) {
+			continue
+		}
+		if sameShape && unwrapNull(fields[i]) == nil && row[i] == c.NullString {
+			continue
+		}
+		row[i] = mask(row[i])
+		if fieldValues != nil {
+			fieldValues[i] = row[i]
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package sqltocsv_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestMaskFuncs(t *testing.T) {
+	for _, test := range []struct {
+		mask     sqltocsv.MaskFunc
+		value    string
+		expected string
+	}{
+		{sqltocsv.MaskAll, "alice@example.com", "***"},
+		{sqltocsv.MaskAll, "", "***"},
+		{sqltocsv.MaskEmail, "alice@example.com", "***@example.com"},
+		{sqltocsv.MaskEmail, `"a@b"@example.com`, "***@example.com"},
+		{sqltocsv.MaskEmail, "not an email", "***"},
+		{sqltocsv.MaskLast4, "555-123-4567", "***4567"},
+		{sqltocsv.MaskLast4, "ünïcödé", "***cödé"},
+		{sqltocsv.MaskLast4, "1234", "***"},
+		{sqltocsv.MaskLast4, "", "***"},
+	} {
+		if actual := test.mask(test.value); actual != test.expected {
+			t.Errorf("expected %q to be masked as %q, got %q", test.value, test.expected, actual)
+		}
+	}
+}
+
+func TestMaskColumns(t *testing.T) {
+	converter := sqltocsv.NewFromSource(&sliceSource{
+		columns: []string{"name", "email", "phone", "card"},
+		rows: [][]any{
+			{"alice", "alice@example.com", "555-123-4567", []byte("4111111111111111")},
+			{"bob", nil, "", nil},
+		},
+	})
+	converter.NullString = `\N`
+	converter.MaskColumns = map[string]sqltocsv.MaskFunc{
+		"email":   sqltocsv.MaskEmail,
+		"phone":   sqltocsv.MaskLast4,
+		"card":    func(value string) string { return strings.Repeat("X", len(value)) },
+		"missing": sqltocsv.MaskAll,
+	}
+	converter.SetRowPreProcessor(func(row []string, _ []string) (bool, []string) {
+		row[1] = strings.ToUpper(row[1])
+		return true, row
+	})
+
+	expected := "name,email,phone,card\nalice,***@EXAMPLE.COM,***4567,XXXXXXXXXXXXXXXX\nbob,\\N,***,\\N\n"
+	csv, stats, err := converter.WriteStringWithStats()
+	if err != nil {
+		t.Fatalf("error in WriteStringWithStats: %v", err)
+	}
+	assertCsvMatch(t, expected, csv)
+
+	if masked := []string{"email", "phone", "card"}; !reflect.DeepEqual(stats.MaskedColumns, masked) {
+		t.Errorf("expected MaskedColumns %v, got %v", masked, stats.MaskedColumns)
+	}
+}
+
+func TestMaskColumnsPreserveNulls(t *testing.T) {
+	converter := sqltocsv.NewFromSource(&sliceSource{
+		columns: []string{"email"},
+		rows:    [][]any{{"alice@example.com"}, {nil}, {""}},
+	})
+	converter.PreserveNullVsEmpty = true
+	converter.MaskColumns = map[string]sqltocsv.MaskFunc{"email": sqltocsv.MaskAll}
+
+	expected := "email\n***\n\n***\n"
+	csv, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	assertCsvMatch(t, expected, csv)
+}
+
+func TestMaskColumnsUnmaskedStats(t *testing.T) {
+	converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"name"}, rows: [][]any{{"alice"}}})
+	_, stats, err := converter.WriteStringWithStats()
+	if err != nil {
+		t.Fatalf("error in WriteStringWithStats: %v", err)
+	}
+	if stats.MaskedColumns != nil {
+		t.Errorf("expected no MaskedColumns, got %v", stats.MaskedColumns)
+	}
+}
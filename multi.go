@@ -0,0 +1,144 @@
+package sqltocsv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+)
+
+// WriteMulti writes the CSV to every one of the writers provided in a single
+// pass over the rows. Errors say which writer (by its position in writers)
+// failed. By default the first failure stops the export; with
+// ContinueOnError set the failed writer is dropped and the others carry on,
+// and the errors of every failed writer are returned at the end. Each writer
+// gets the CSV Write would write, with its own Encoding, BufferSize buffer
+// and footers; SetChecksum and Stats.BytesWritten cover the output sent to
+// each of them, which is the same for all.
+func (c Converter) WriteMulti(writers ...io.Writer) (err error) {
+	defer c.closeRows(&err)
+
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	p, err := c.newPlan()
+	if err != nil {
+		return err
+	}
+	p.keepValues = c.needsNulls()
+
+	start := time.Now()
+	defer func() {
+		p.last.stats.Duration = time.Since(start)
+	}()
+
+	// every writer is sent the same bytes, so the first one's are what's
+	// counted and checksummed
+	var sum hash.Hash
+	if c.checksum != nil {
+		c.checksum.Reset()
+		defer c.saveChecksum(p)
+		sum = c.checksum
+	}
+	targets := make([]*multiTarget, len(writers))
+	for i, writer := range writers {
+		targets[i] = &multiTarget{index: i}
+		targets[i].out = c.newCSVOutput(writer, &targets[i].bytes, sum)
+		sum = nil
+	}
+	if len(targets) > 0 {
+		defer func() {
+			p.last.stats.BytesWritten = targets[0].bytes
+		}()
+	}
+
+	for _, target := range targets {
+		err := c.writePreamble(target.out.writer, target.out.csvWriter, p)
+		if err = c.multiFailed(targets, target, err); err != nil {
+			return err
+		}
+	}
+
+	err = c.eachRow(context.Background(), p, func(row []string, values []any) error {
+		for _, target := range targets {
+			if target.err != nil {
+				continue
+			}
+			err := writeRecord(target.out.csvWriter, row, values)
+			if err != nil {
+				err = fmt.Errorf("failed to write data row to csv %w", err)
+			}
+			if err = c.multiFailed(targets, target, err); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	for _, target := range targets {
+		if err != nil {
+			break
+		}
+		if target.err != nil {
+			continue
+		}
+		footerErr := c.writeFooters(target.out.csvWriter, target.out.flush, func() Stats {
+			stats := p.last.stats
+			stats.BytesWritten = target.bytes
+			stats.Duration = time.Since(start)
+			return stats
+		})
+		err = c.multiFailed(targets, target, footerErr)
+	}
+
+	var errs []error
+	for _, target := range targets {
+		if target.err == nil {
+			target.fail(target.out.flush(true))
+		}
+		errs = append(errs, target.err)
+	}
+	if err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// multiTarget is one of the destinations of WriteMulti.
+type multiTarget struct {
+	index int
+	out   *csvOutput
+	bytes int64
+	err   error
+}
+
+func (t *multiTarget) fail(err error) {
+	if err != nil && t.err == nil {
+		t.err = fmt.Errorf("writer %d: %w", t.index, err)
+	}
+}
+
+// multiFailed records err (if any) against target, returning an error if
+// the export should stop: always unless ContinueOnError is set, and even
+// then once every writer has failed.
+func (c Converter) multiFailed(targets []*multiTarget, target *multiTarget, err error) error {
+	if err == nil {
+		return nil
+	}
+	target.fail(err)
+	if !c.ContinueOnError {
+		return target.err
+	}
+
+	var errs []error
+	for _, t := range targets {
+		if t.err == nil {
+			return nil
+		}
+		errs = append(errs, t.err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,120 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+var errWriterBroken = errors.New("writer broken")
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errWriterBroken
+}
+
+func TestWriteMulti(t *testing.T) {
+	first, second := &bytes.Buffer{}, &bytes.Buffer{}
+
+	err := getConverter(t).WriteMulti(first, second)
+	if err != nil {
+		t.Fatalf("error in WriteMulti: %v", err)
+	}
+
+	expected := "name,age,bdate\nAlice,1,1973-11-29T21:33:09Z\n"
+	assertCsvMatch(t, expected, first.String())
+	assertCsvMatch(t, expected, second.String())
+}
+
+func TestWriteMultiAbortsOnError(t *testing.T) {
+	converter := getConverter(t)
+	converter.WriteBOM = true
+
+	err := converter.WriteMulti(&bytes.Buffer{}, failingWriter{}, &bytes.Buffer{})
+	if !errors.Is(err, errWriterBroken) || !strings.HasPrefix(err.Error(), "writer 1: ") {
+		t.Errorf("expected error from writer 1, got %v", err)
+	}
+}
+
+func TestWriteMultiContinueOnError(t *testing.T) {
+	converter := getConverter(t)
+	converter.ContinueOnError = true
+
+	first, second := &bytes.Buffer{}, &bytes.Buffer{}
+	err := converter.WriteMulti(first, failingWriter{}, second)
+	if !errors.Is(err, errWriterBroken) || !strings.HasPrefix(err.Error(), "writer 1: ") {
+		t.Errorf("expected error from writer 1, got %v", err)
+	}
+
+	expected := "name,age,bdate\nAlice,1,1973-11-29T21:33:09Z\n"
+	assertCsvMatch(t, expected, first.String())
+	assertCsvMatch(t, expected, second.String())
+}
+
+func TestWriteMultiOutputOptions(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		setup    func(c *sqltocsv.Converter)
+		checksum bool
+		buffered bool
+		expected string
+	}{
+		{
+			name:     "Encoding",
+			setup:    func(c *sqltocsv.Converter) { c.Encoding = charmap.Windows1252 },
+			expected: "id,name\n1,Zo\xEB\n2,Zo\xEB\n",
+		},
+		{
+			name: "footers",
+			setup: func(c *sqltocsv.Converter) {
+				c.WriteRowCountFooter = true
+				c.SetFooter(func(stats sqltocsv.Stats) []string {
+					return []string{"TOTAL", strconv.FormatInt(stats.RowsWritten, 10)}
+				})
+			},
+			expected: "id,name\n1,Zoë\n2,Zoë\nTOTAL,2\n__rowcount__,2\n",
+		},
+		{
+			name:     "SetChecksum",
+			setup:    func(c *sqltocsv.Converter) { c.SetChecksum(sha256.New()) },
+			checksum: true,
+			expected: "id,name\n1,Zoë\n2,Zoë\n",
+		},
+		{
+			name:     "BufferSize",
+			setup:    func(c *sqltocsv.Converter) { c.BufferSize = 1 << 20 },
+			buffered: true,
+			expected: "id,name\n1,Zoë\n2,Zoë\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			converter := sqltocsv.NewFromSource(&sliceSource{
+				columns: []string{"id", "name"},
+				rows:    [][]any{{1, "Zoë"}, {2, "Zoë"}},
+			})
+			test.setup(converter)
+
+			first, second := &writeCounter{}, &writeCounter{}
+			if err := converter.WriteMulti(first, second); err != nil {
+				t.Fatalf("error in WriteMulti: %v", err)
+			}
+			assertCsvMatch(t, test.expected, first.String())
+			assertCsvMatch(t, test.expected, second.String())
+
+			if digest := sha256.Sum256(first.Bytes()); test.checksum && !bytes.Equal(converter.Checksum(), digest[:]) {
+				t.Errorf("expected the checksum of the output, got %x", converter.Checksum())
+			}
+			if test.buffered && (first.writes != 1 || second.writes != 1) {
+				t.Errorf("expected a single write to each writer with BufferSize, got %d and %d", first.writes, second.writes)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package sqltocsv
+
+import (
+	"io"
+	"slices"
+	"strings"
+)
+
+// mysqlBinaryTypes are the database type names of MySQL's binary columns.
+var mysqlBinaryTypes = []string{"BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB"}
+
+// WriteMySQLDump writes the rows the way MySQL's SELECT ... INTO OUTFILE
+// does, for LOAD DATA INFILE to read back with its default options: tab
+// separated, without a header row or quoting, NULLs as \N and backslash
+// escapes (see EscapeMode). Times are written as DATETIME literals and bools
+// as 1 and 0, unless TimeFormat, TrueString and FalseString say otherwise.
+//
+// Binary columns are written as hex when the rows have ColumnTypes and
+// name them, unless BinaryConverter or BinaryConverters are set, so load
+// them with something like
+//
+//	LOAD DATA INFILE 'export.txt' INTO TABLE t (id, @data) SET data = UNHEX(@data)
+//
+// Other options apply as they do to Write. As the format has a layout of
+// its own, a Dialect set with ApplyDialect is dropped along with the options
+// it set, such as WriteBOM.
+func (c Converter) WriteMySQLDump(w io.Writer) error {
+	if c.dialect != 0 {
+		c.clearDialect()
+	}
+	c.Delimiter = '\t'
+	c.DelimiterString = ""
+	c.EscapeMode = true
+	c.EscapeChar = '\\'
+	c.NullString = `\N`
+	c.WriteHeaders = false
+	c.UseCRLF = false
+	if c.TimeFormat == "" {
+		c.TimeFormat = "2006-01-02 15:04:05.999999"
+	}
+	if c.TrueString == "" && c.FalseString == "" {
+		c.TrueString, c.FalseString = "1", "0"
+	}
+	if c.BinaryConverter == String {
+		c.BinaryConverters = c.mysqlBinaryColumns()
+	}
+	return c.Write(w)
+}
+
+// mysqlBinaryColumns returns BinaryConverters with Hex added for the binary
+// columns of the rows, if they have column types to tell.
+func (c Converter) mysqlBinaryColumns() map[string]BinaryConverter {
+	typer, ok := c.rows.(columnTyper)
+	if !ok {
+		return c.BinaryConverters
+	}
+	columnTypes, err := typer.ColumnTypes()
+	if err != nil {
+		return c.BinaryConverters // for Write to fail on
+	}
+
+	converters := make(map[string]BinaryConverter, len(c.BinaryConverters))
+	for name, converter := range c.BinaryConverters {
+		converters[name] = converter
+	}
+	for _, columnType := range columnTypes {
+		databaseType := strings.ToUpper(columnType.DatabaseTypeName())
+		if _, set := converters[columnType.Name()]; !set && slices.Contains(mysqlBinaryTypes, databaseType) {
+			converters[columnType.Name()] = Hex
+		}
+	}
+	return converters
+}
@@ -0,0 +1,73 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestWriteMySQLDump(t *testing.T) {
+	db := sql.OpenDB(typedConnector{
+		columns: []typedColumn{
+			{"id", "BIGINT", reflect.TypeFor[int64]()},
+			{"name", "VARCHAR", reflect.TypeFor[sql.RawBytes]()},
+			{"data", "BLOB", reflect.TypeFor[sql.RawBytes]()},
+			{"active", "TINYINT", reflect.TypeFor[bool]()},
+			{"created", "DATETIME", reflect.TypeFor[time.Time]()},
+		},
+		rows: [][]driver.Value{
+			{int64(1), []byte("tab\there\\ and\nnewline"), []byte{0xde, 0xad}, true, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+			{int64(2), []byte(`\N`), nil, false, nil},
+			{int64(3), nil, []byte{}, nil, time.Date(2024, 1, 2, 3, 4, 5, 500000000, time.UTC)},
+		},
+	})
+	t.Cleanup(func() { db.Close() })
+	rows, err := db.Query("SELECT")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+
+	var out bytes.Buffer
+	converter := sqltocsv.New(rows)
+	converter.UseColumnTypes = true
+	if err := converter.WriteMySQLDump(&out); err != nil {
+		t.Fatalf("error in WriteMySQLDump: %v", err)
+	}
+
+	str := func(s string) *string { return &s }
+	expected := [][]*string{
+		{str("1"), str("tab\there\\ and\nnewline"), str("dead"), str("1"), str("2024-01-02 03:04:05")},
+		{str("2"), str(`\N`), nil, str("0"), nil},
+		{str("3"), nil, str(""), nil, str("2024-01-02 03:04:05.5")},
+	}
+	records := parseEscaped(t, out.String(), '\t', '\\')
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("unexpected records from %q", out.String())
+		for i, record := range records {
+			for j, field := range record {
+				if field == nil {
+					t.Logf("%d,%d: NULL", i, j)
+				} else {
+					t.Logf("%d,%d: %q", i, j, *field)
+				}
+			}
+		}
+	}
+}
+
+func TestWriteMySQLDumpBinaryConverter(t *testing.T) {
+	source := &sliceSource{columns: []string{"data"}, rows: [][]any{{[]byte("hi")}}}
+	converter := sqltocsv.NewFromSource(source)
+	converter.BinaryConverter = sqltocsv.StdBase64
+
+	var out bytes.Buffer
+	if err := converter.WriteMySQLDump(&out); err != nil {
+		t.Fatalf("error in WriteMySQLDump: %v", err)
+	}
+	assertCsvMatch(t, "aGk=\n", out.String())
+}
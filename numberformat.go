@@ -0,0 +1,129 @@
+package sqltocsv
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// NumberFormat is how the integer and float values of a column are written
+// when it's listed in NumberFormats, such as
+//
+//	NumberFormat{GroupSeparator: ",", Decimals: 2}
+//
+// for 1,234,567.89. NaN and infinite floats are written as they would be
+// without it.
+type NumberFormat struct {
+	GroupSeparator   string // Put between each group of three digits of the whole part, such as "," or " " (default is none)
+	DecimalSeparator string // Put in place of the decimal point (default is the Converter's DecimalSeparator, or ".")
+	Decimals         int    // Number of decimal places, rounding floats and padding integers with zeros, or -1 for as many as a float needs (default is 0)
+}
+
+// appendNumber appends v to dst as the column's NumberFormat says, reporting
+// false, leaving dst as it is, if there isn't one or v isn't a number it
+// applies to.
+func (c Converter) appendNumber(dst []byte, v any) ([]byte, bool) {
+	if c.numberFormat == nil {
+		return dst, false
+	}
+	var digits []byte
+	var scratch [64]byte
+	switch val := v.(type) {
+	case int:
+		digits = strconv.AppendInt(scratch[:0], int64(val), 10)
+	case int8:
+		digits = strconv.AppendInt(scratch[:0], int64(val), 10)
+	case int16:
+		digits = strconv.AppendInt(scratch[:0], int64(val), 10)
+	case int32:
+		digits = strconv.AppendInt(scratch[:0], int64(val), 10)
+	case int64:
+		digits = strconv.AppendInt(scratch[:0], val, 10)
+	case uint:
+		digits = strconv.AppendUint(scratch[:0], uint64(val), 10)
+	case uint8:
+		digits = strconv.AppendUint(scratch[:0], uint64(val), 10)
+	case uint16:
+		digits = strconv.AppendUint(scratch[:0], uint64(val), 10)
+	case uint32:
+		digits = strconv.AppendUint(scratch[:0], uint64(val), 10)
+	case uint64:
+		digits = strconv.AppendUint(scratch[:0], val, 10)
+	case float32:
+		if math.IsNaN(float64(val)) || math.IsInf(float64(val), 0) {
+			return dst, false
+		}
+		digits = strconv.AppendFloat(scratch[:0], float64(val), 'f', c.numberFormat.Decimals, 32)
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return dst, false
+		}
+		digits = strconv.AppendFloat(scratch[:0], val, 'f', c.numberFormat.Decimals, 64)
+	default:
+		return dst, false
+	}
+	return c.numberFormat.append(dst, digits, c.DecimalSeparator), true
+}
+
+// append appends the number formatted by strconv in digits to dst, grouping
+// the digits of its whole part and padding it to Decimals. decimal is the
+// Converter's DecimalSeparator, used if the NumberFormat doesn't have one.
+// The minus sign is dropped from a value that rounds to zero, such as -0.001
+// with 2 Decimals.
+func (f *NumberFormat) append(dst, digits []byte, decimal rune) []byte {
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+		if bytes.ContainsFunc(digits, func(r rune) bool { return r != '0' && r != '.' }) {
+			dst = append(dst, '-')
+		}
+	}
+	whole, fraction := digits, []byte(nil)
+	for i, ch := range digits {
+		if ch == '.' {
+			whole, fraction = digits[:i], digits[i+1:]
+			break
+		}
+	}
+
+	for i, ch := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			dst = append(dst, f.GroupSeparator...)
+		}
+		dst = append(dst, ch)
+	}
+
+	if len(fraction) == 0 && f.Decimals <= 0 {
+		return dst
+	}
+	switch {
+	case f.DecimalSeparator != "":
+		dst = append(dst, f.DecimalSeparator...)
+	case decimal != 0:
+		dst = append(dst, string(decimal)...)
+	default:
+		dst = append(dst, '.')
+	}
+	dst = append(dst, fraction...)
+	for i := len(fraction); i < f.Decimals; i++ {
+		dst = append(dst, '0')
+	}
+	return dst
+}
+
+// plainNumber returns field, the text written for an integer or finite
+// float value, if it's a plain number, or else the value formatted as one,
+// for the writers such as WriteSQLInserts that need a number where
+// NumberFormats or the DecimalSeparator may have put separators in it.
+func plainNumber(value any, field string) string {
+	if isNumericText(field) {
+		return field
+	}
+	switch v := value.(type) {
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,74 @@
+package sqltocsv_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestNumberFormats(t *testing.T) {
+	grouped := sqltocsv.NumberFormat{GroupSeparator: ",", Decimals: 2}
+	for _, test := range []struct {
+		name     string
+		format   sqltocsv.NumberFormat
+		value    any
+		expected string
+	}{
+		{"float", grouped, 1234567.891, "1,234,567.89"},
+		{"negative float", grouped, -1234567.891, "-1,234,567.89"},
+		{"under a thousand", grouped, 999.5, "999.50"},
+		{"negative under a thousand", grouped, -12.0, "-12.00"},
+		{"exactly a thousand", grouped, 1000.0, "1,000.00"},
+		{"fraction", grouped, 0.125, "0.12"},
+		{"float32", grouped, float32(2500.25), "2,500.25"},
+		{"int", grouped, int64(1234567), "1,234,567.00"},
+		{"negative int", grouped, int64(-100000), "-100,000.00"},
+		{"negative rounding to zero", grouped, -0.001, "0.00"},
+		{"negative zero", grouped, math.Copysign(0, -1), "0.00"},
+		{"negative rounding to zero without decimals", sqltocsv.NumberFormat{GroupSeparator: ","}, -0.4, "0"},
+		{"small int", grouped, 7, "7.00"},
+		{"min int64", sqltocsv.NumberFormat{GroupSeparator: ","}, int64(math.MinInt64), "-9,223,372,036,854,775,808"},
+		{"max uint64", sqltocsv.NumberFormat{GroupSeparator: ","}, uint64(math.MaxUint64), "18,446,744,073,709,551,615"},
+		{"no decimals", sqltocsv.NumberFormat{GroupSeparator: ","}, 1234.6, "1,235"},
+		{"shortest decimals", sqltocsv.NumberFormat{GroupSeparator: ",", Decimals: -1}, 1234.5678, "1,234.5678"},
+		{"shortest decimals of an int", sqltocsv.NumberFormat{GroupSeparator: ",", Decimals: -1}, 1234, "1,234"},
+		{"european", sqltocsv.NumberFormat{GroupSeparator: ".", DecimalSeparator: ",", Decimals: 2}, -9876543.21, "-9.876.543,21"},
+		{"multi-byte group", sqltocsv.NumberFormat{GroupSeparator: " ", Decimals: 1}, 12345.0, "12 345.0"},
+		{"no grouping", sqltocsv.NumberFormat{Decimals: 3}, 1234.5, "1234.500"},
+		{"NaN", grouped, math.NaN(), "NaN"},
+		{"Inf", grouped, math.Inf(-1), "-Inf"},
+		{"text", grouped, "1234.5", "1234.5"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"amount"}, rows: [][]any{{test.value}}})
+			converter.Delimiter = ';'
+			converter.NumberFormats = map[string]sqltocsv.NumberFormat{"amount": test.format}
+			csv, err := converter.WriteString()
+			if err != nil {
+				t.Fatalf("error in WriteString: %v", err)
+			}
+			assertCsvMatch(t, "amount\n"+test.expected+"\n", csv)
+		})
+	}
+}
+
+func TestNumberFormatsOtherColumns(t *testing.T) {
+	converter := sqltocsv.NewFromSource(&sliceSource{
+		columns: []string{"amount", "count", "ratio"},
+		rows:    [][]any{{1234.5, int64(1234), 1234.5}},
+	})
+	converter.Delimiter = ';'
+	converter.FloatFormat = "%.1f"
+	converter.DecimalSeparator = ','
+	converter.NumberFormats = map[string]sqltocsv.NumberFormat{
+		"amount": {GroupSeparator: " ", Decimals: 2},
+	}
+
+	expected := "amount;count;ratio\n1 234,50;1234;1234,5\n"
+	csv, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	assertCsvMatch(t, expected, csv)
+}
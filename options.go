@@ -0,0 +1,150 @@
+package sqltocsv
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Option configures a Converter made by NewWithOptions, checking what it's
+// given as it does. Options set the same fields as you would by hand.
+type Option func(c *Converter) error
+
+// NewWithOptions returns a Converter for rows as New does, with the options
+// applied in order. If any of them are invalid it returns an error saying
+// what's wrong with each of them, and no Converter.
+func NewWithOptions(rows *sql.Rows, opts ...Option) (*Converter, error) {
+	c := New(rows)
+	var errs []error
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// WithDelimiter sets the Delimiter. It can't be the quote character, a
+// carriage return or newline, NUL or an invalid rune.
+func WithDelimiter(r rune) Option {
+	return func(c *Converter) error {
+		if err := checkDelimiter(r, c.quote()); err != nil {
+			return err
+		}
+		c.Delimiter = r
+		return nil
+	}
+}
+
+// WithHeaders sets the Headers to write in place of the query's column
+// names. There has to be at least one; see WithoutHeaders for none.
+func WithHeaders(headers []string) Option {
+	return func(c *Converter) error {
+		if len(headers) == 0 {
+			return errors.New("WithHeaders needs at least one header, use WithoutHeaders to write none")
+		}
+		c.Headers = headers
+		return nil
+	}
+}
+
+// WithoutHeaders turns off WriteHeaders, so that there's no header row.
+func WithoutHeaders() Option {
+	return func(c *Converter) error {
+		c.WriteHeaders = false
+		return nil
+	}
+}
+
+// WithTimeFormat sets the TimeFormat, a layout as time.Format takes.
+func WithTimeFormat(layout string) Option {
+	return func(c *Converter) error {
+		if layout == "" {
+			return errors.New("WithTimeFormat needs a layout")
+		}
+		c.TimeFormat = layout
+		return nil
+	}
+}
+
+// WithFloatFormat sets the FloatFormat, a fmt verb for a float64 such as
+// "%.2f".
+func WithFloatFormat(format string) Option {
+	return func(c *Converter) error {
+		if format == "" || strings.Contains(fmt.Sprintf(format, 1.0), "%!") {
+			return fmt.Errorf("float format %q must contain a single verb for the value", format)
+		}
+		c.FloatFormat = format
+		return nil
+	}
+}
+
+// WithNullString sets the NullString written for NULL values.
+func WithNullString(s string) Option {
+	return func(c *Converter) error {
+		if !utf8.ValidString(s) {
+			return fmt.Errorf("null string %q isn't valid UTF-8", s)
+		}
+		c.NullString = s
+		return nil
+	}
+}
+
+// WithBOM turns on WriteBOM.
+func WithBOM() Option {
+	return func(c *Converter) error {
+		c.WriteBOM = true
+		return nil
+	}
+}
+
+// WithCRLF turns on UseCRLF.
+func WithCRLF() Option {
+	return func(c *Converter) error {
+		c.UseCRLF = true
+		return nil
+	}
+}
+
+// WithMaxRows sets MaxRows, which has to be positive.
+func WithMaxRows(n int64) Option {
+	return func(c *Converter) error {
+		if n <= 0 {
+			return fmt.Errorf("max rows must be positive, got %d", n)
+		}
+		c.MaxRows = n
+		return nil
+	}
+}
+
+// WithRowPreProcessor sets the CsvPreProcessorFunc, see SetRowPreProcessor.
+func WithRowPreProcessor(fn CsvPreProcessorFunc) Option {
+	return func(c *Converter) error {
+		if fn == nil {
+			return errors.New("WithRowPreProcessor needs a function")
+		}
+		c.SetRowPreProcessor(fn)
+		return nil
+	}
+}
+
+// checkDelimiter returns an error if r can't separate the fields of a CSV
+// quoted with quote.
+func checkDelimiter(r, quote rune) error {
+	switch {
+	case r == quote:
+		return fmt.Errorf("delimiter %q can't be the quote character", r)
+	case r == '\r' || r == '\n':
+		return fmt.Errorf("delimiter %q can't be a carriage return or newline", r)
+	case r == 0:
+		return errors.New("delimiter can't be NUL")
+	case !utf8.ValidRune(r) || r == utf8.RuneError:
+		return fmt.Errorf("delimiter %U isn't a valid rune", r)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package sqltocsv_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	converter, err := sqltocsv.NewWithOptions(getTestRows(t),
+		sqltocsv.WithDelimiter(';'),
+		sqltocsv.WithHeaders([]string{"Name", "Age", "Birthday"}),
+		sqltocsv.WithTimeFormat("2006-01-02"),
+		sqltocsv.WithCRLF(),
+	)
+	if err != nil {
+		t.Fatalf("error in NewWithOptions: %v", err)
+	}
+	assertCsvMatch(t, "Name;Age;Birthday\r\nAlice;1;1973-11-29\r\n", converter.String())
+}
+
+func TestNewWithOptionsWithoutHeaders(t *testing.T) {
+	converter, err := sqltocsv.NewWithOptions(getTestRows(t), sqltocsv.WithoutHeaders(), sqltocsv.WithNullString("NULL"))
+	if err != nil {
+		t.Fatalf("error in NewWithOptions: %v", err)
+	}
+	assertCsvMatch(t, "Alice,1,1973-11-29T21:33:09Z\n", converter.String())
+}
+
+func TestNewWithOptionsInvalid(t *testing.T) {
+	converter, err := sqltocsv.NewWithOptions(getTestRows(t),
+		sqltocsv.WithDelimiter('\n'),
+		sqltocsv.WithHeaders(nil),
+		sqltocsv.WithMaxRows(0),
+		sqltocsv.WithFloatFormat("%.2f%s"),
+		sqltocsv.WithNullString("NULL"),
+	)
+	if err == nil {
+		t.Fatal("expected an error for the invalid options")
+	}
+	if converter != nil {
+		t.Error("expected no Converter with the error")
+	}
+	for _, expected := range []string{"delimiter", "WithHeaders", "max rows", "float format"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected the error to mention %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestWithDelimiterInvalid(t *testing.T) {
+	for _, r := range []rune{'"', '\r', '\n', 0, 0xD800} {
+		if _, err := sqltocsv.NewWithOptions(getTestRows(t), sqltocsv.WithDelimiter(r)); err == nil {
+			t.Errorf("expected an error for delimiter %q", r)
+		}
+	}
+}
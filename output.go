@@ -0,0 +1,64 @@
+package sqltocsv
+
+import (
+	"bufio"
+	"fmt"
+	"hash"
+	"io"
+
+	"golang.org/x/text/transform"
+)
+
+// csvOutput is the stack of writers the CSV goes through on its way to a
+// destination: the byte count, the SetChecksum hash, the BufferSize buffer
+// and the Encoding, with the recordWriter on top. Write, WriteFileChunks
+// (once per file) and WriteMulti (once per writer) all build one, so the
+// options apply the same way to each of them.
+type csvOutput struct {
+	writer    io.Writer // what the preamble and anything else outside the records is written to
+	csvWriter recordWriter
+	encoder   *transform.Writer
+	bufWriter *bufio.Writer
+}
+
+// newCSVOutput builds the stack onto dest, adding the bytes written to dest
+// to n and passing them through sum unless it's nil.
+func (c Converter) newCSVOutput(dest io.Writer, n *int64, sum hash.Hash) *csvOutput {
+	out := &csvOutput{}
+	var writer io.Writer = &countingWriter{w: dest, n: n}
+	if sum != nil {
+		writer = io.MultiWriter(writer, sum)
+	}
+	if _, buffered := dest.(*bufio.Writer); c.BufferSize > 0 && !buffered {
+		out.bufWriter = bufio.NewWriterSize(writer, c.BufferSize)
+		writer = out.bufWriter
+	}
+	if out.encoder = c.encodingWriter(writer); out.encoder != nil {
+		writer = out.encoder
+	}
+	out.writer = writer
+	out.csvWriter = c.newCSVWriter(writer)
+	return out
+}
+
+// flush pushes everything written so far through to the destination. The
+// final flush also closes the encoder, so nothing can be written after it.
+func (out *csvOutput) flush(final bool) error {
+	out.csvWriter.Flush()
+	if err := out.csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed to flush csv %w", err)
+	}
+	// the encoder only holds on to a partly written rune between writes,
+	// so it needn't be closed until the end
+	if final && out.encoder != nil {
+		if err := out.encoder.Close(); err != nil {
+			return fmt.Errorf("failed to encode csv %w", err)
+		}
+	}
+	if out.bufWriter != nil {
+		if err := out.bufWriter.Flush(); err != nil {
+			return fmt.Errorf("failed to flush csv %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package sqltocsv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress/gzip"
+)
+
+// defaultParquetRowGroupSize is how many rows WriteParquetFile puts in each
+// row group when ParquetRowGroupSize isn't set.
+const defaultParquetRowGroupSize = 100000
+
+// WriteParquetFile writes the rows to the filename specified as a Parquet
+// file, return an error if problem. Columns are typed from rows.ColumnTypes()
+// as with UseColumnTypes: integers become INT64, floats DOUBLE, booleans
+// BOOLEAN and times TIMESTAMP_MICROS, and everything else (DECIMAL and
+// NUMERIC included) a UTF8 string holding the converted field. Every column
+// is optional, so NULLs stay NULL whatever NullString is.
+//
+// Rows are written in row groups of ParquetRowGroupSize rows, and only the
+// current row group is held in memory. Pages are gzip compressed at
+// GzipLevel, or gzipped without being compressed with GzipUncompressed.
+func (c Converter) WriteParquetFile(parquetFileName string) (err error) {
+	defer c.closeRows(&err)
+
+	f, err := c.createFile(parquetFileName)
+	if err != nil {
+		return err
+	}
+
+	err = c.writeParquet(f)
+	if err != nil {
+		f.abort() // close, but only return/handle the write error
+		return err
+	}
+
+	return f.commit()
+}
+
+func (c Converter) writeParquet(writer io.Writer) error {
+	c.UseColumnTypes = true
+	p, err := c.newPlan()
+	if err != nil {
+		return err
+	}
+	p.keepValues = true
+
+	kinds := make([]parquetKind, len(p.headers))
+	group := parquet.Group{}
+	for i, header := range p.headers {
+		var scanType reflect.Type
+		if n := p.column(i); n >= 0 {
+			scanType = p.scanTypes[p.selected[n]]
+		}
+		if _, ok := group[header]; ok {
+			return fmt.Errorf("parquet column %q appears more than once", header)
+		}
+		kinds[i] = parquetKindOf(scanType)
+		group[header] = parquet.Optional(kinds[i].node())
+	}
+
+	groupSize := c.ParquetRowGroupSize
+	if groupSize < 1 {
+		groupSize = defaultParquetRowGroupSize
+	}
+
+	pw := parquet.NewWriter(writer,
+		parquet.NewSchema("schema", newParquetGroup(group, p.headers)),
+		parquet.Compression(&gzip.Codec{Level: c.gzipLevel()}),
+		parquet.MaxRowsPerRowGroup(int64(groupSize)),
+		parquet.CreatedBy("github.com/armantarkhanian/sqltocsv", "", ""),
+	)
+	row := make(parquet.Row, len(p.headers))
+	rowNumber := 0
+	err = c.eachRow(context.Background(), p, func(fields []string, values []any) error {
+		rowNumber++
+		if len(fields) != len(kinds) {
+			return fmt.Errorf("row %d has %d fields but there are %d headers", rowNumber, len(fields), len(kinds))
+		}
+
+		for i, kind := range kinds {
+			value, err := kind.value(values[i], fields[i])
+			if err != nil {
+				return fmt.Errorf("failed to encode column %q in row %d: %w", p.headers[i], rowNumber, err)
+			}
+			definition := 1 // 0 for NULL
+			if value.IsNull() {
+				definition = 0
+			}
+			row[i] = value.Level(0, definition, i)
+		}
+		_, err := pw.WriteRows([]parquet.Row{row})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return pw.Close()
+}
+
+// parquetGroup is a parquet.Group that keeps its fields in the order of the
+// columns, where parquet.Group sorts them by name.
+type parquetGroup struct {
+	parquet.Group
+	fields []parquet.Field
+}
+
+func newParquetGroup(group parquet.Group, order []string) parquetGroup {
+	byName := map[string]parquet.Field{}
+	for _, field := range group.Fields() {
+		byName[field.Name()] = field
+	}
+	fields := make([]parquet.Field, len(order))
+	for i, name := range order {
+		fields[i] = byName[name]
+	}
+	return parquetGroup{Group: group, fields: fields}
+}
+
+func (g parquetGroup) Fields() []parquet.Field { return g.fields }
+
+// parquetKind is the Parquet type of a column.
+type parquetKind int
+
+const (
+	parquetString parquetKind = iota
+	parquetInt64
+	parquetDouble
+	parquetBoolean
+	parquetTimestamp // an INT64 of microseconds since the epoch
+)
+
+// parquetKindOf picks the Parquet type of a column from what
+// UseColumnTypes scans it into.
+func parquetKindOf(scanType reflect.Type) parquetKind {
+	switch scanType {
+	case nullInt64Type, nullUint64Type:
+		return parquetInt64
+	case nullFloat64Type:
+		return parquetDouble
+	case nullBoolType:
+		return parquetBoolean
+	case nullTimeType:
+		return parquetTimestamp
+	}
+	return parquetString
+}
+
+func (kind parquetKind) node() parquet.Node {
+	switch kind {
+	case parquetInt64:
+		return parquet.Leaf(parquet.Int64Type)
+	case parquetDouble:
+		return parquet.Leaf(parquet.DoubleType)
+	case parquetBoolean:
+		return parquet.Leaf(parquet.BooleanType)
+	case parquetTimestamp:
+		return parquet.Timestamp(parquet.Microsecond)
+	}
+	return parquet.String()
+}
+
+// value converts a row's value for the column. Values that a column
+// formatter or the preprocessor turned into strings are parsed back from
+// field, and are NULL if they are empty, except in string columns.
+func (kind parquetKind) value(value any, field string) (parquet.Value, error) {
+	if s, ok := value.(string); ok && s == "" && kind != parquetString {
+		value = nil
+	}
+	if value == nil {
+		return parquet.NullValue(), nil
+	}
+
+	switch kind {
+	case parquetTimestamp:
+		t, ok := value.(time.Time)
+		if !ok {
+			return parquet.Value{}, fmt.Errorf("%q is not a time", field)
+		}
+		return parquet.Int64Value(t.UnixMicro()), nil
+	case parquetInt64:
+		n, err := parquetInt(value, field)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.Int64Value(n), nil
+	case parquetDouble:
+		f, err := parquetFloat(value, field)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.DoubleValue(f), nil
+	case parquetBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			var err error
+			if b, err = strconv.ParseBool(field); err != nil {
+				return parquet.Value{}, err
+			}
+		}
+		return parquet.BooleanValue(b), nil
+	}
+	return parquet.ByteArrayValue([]byte(field)), nil
+}
+
+func parquetInt(value any, field string) (int64, error) {
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return 0, fmt.Errorf("%d is too big for an INT64", v.Uint())
+		}
+		return int64(v.Uint()), nil
+	}
+	return strconv.ParseInt(field, 10, 64)
+}
+
+func parquetFloat(value any, field string) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	}
+	return strconv.ParseFloat(field, 64)
+}
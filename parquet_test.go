@@ -0,0 +1,301 @@
+package sqltocsv_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+// readParquetFile opens a Parquet file and passes each of its rows to fn.
+func readParquetFile(t *testing.T, name string, fn func(row parquet.Row)) *parquet.File {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("error opening %s: %v", name, err)
+	}
+	t.Cleanup(func() { f.Close() })
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatalf("error opening %s: %v", name, err)
+	}
+
+	file, err := parquet.OpenFile(f, info.Size())
+	if err != nil {
+		t.Fatalf("error reading %s: %v", name, err)
+	}
+	for _, rowGroup := range file.RowGroups() {
+		rows := rowGroup.Rows()
+		buf := make([]parquet.Row, 100)
+		for {
+			n, err := rows.ReadRows(buf)
+			for _, row := range buf[:n] {
+				fn(row)
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("error reading rows of %s: %v", name, err)
+			}
+		}
+		rows.Close()
+	}
+	return file
+}
+
+// parquetValues renders a row read back from a Parquet file like "NULL",
+// "int64:1" or "string:a".
+func parquetValues(row parquet.Row) []string {
+	out := make([]string, len(row))
+	for _, value := range row {
+		var s string
+		switch {
+		case value.IsNull():
+			s = "NULL"
+		case value.Kind() == parquet.Boolean:
+			s = "bool:" + strconv.FormatBool(value.Boolean())
+		case value.Kind() == parquet.Int64:
+			s = "int64:" + strconv.FormatInt(value.Int64(), 10)
+		case value.Kind() == parquet.Double:
+			s = "double:" + strconv.FormatFloat(value.Double(), 'f', -1, 64)
+		default:
+			s = "string:" + string(value.ByteArray())
+		}
+		out[value.Column()] = s
+	}
+	return out
+}
+
+func TestWriteParquetFile(t *testing.T) {
+	converter := sqltocsv.New(queryTypedRows(t))
+	converter.NullString = "NULL!"
+	converter.TimeFormat = time.Kitchen // doesn't apply to TIMESTAMP columns
+	converter.SetColumnFormatter("score", func(value any) (string, error) {
+		if value == nil {
+			return "", nil
+		}
+		return fmt.Sprint(value.(int64) * 2), nil
+	})
+
+	name := filepath.Join(t.TempDir(), "test.parquet")
+	if err := converter.WriteParquetFile(name); err != nil {
+		t.Fatalf("error in WriteParquetFile: %v", err)
+	}
+
+	var rows [][]string
+	file := readParquetFile(t, name, func(row parquet.Row) {
+		rows = append(rows, parquetValues(row))
+	})
+
+	var types []string
+	for _, field := range file.Schema().Fields() {
+		types = append(types, field.Name()+":"+field.Type().String())
+		if !field.Optional() {
+			t.Errorf("expected %s to be optional", field.Name())
+		}
+	}
+	expectedTypes := []string{"id:INT(64,true)", "ratio:DOUBLE", "name:STRING", "price:STRING", "active:BOOLEAN", "created:TIMESTAMP(isAdjustedToUTC=true,unit=MICROS)", "score:INT(64,true)"}
+	if !reflect.DeepEqual(types, expectedTypes) {
+		t.Errorf("expected schema %q, got %q", expectedTypes, types)
+	}
+
+	expected := [][]string{
+		{"int64:1", "double:0.5", "string:Alice", "string:12345678901234567890.123456789", "bool:true", "int64:0", "int64:20"},
+		{"int64:2", "NULL", "NULL", "NULL", "NULL", "NULL", "NULL"},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected rows %q, got %q", expected, rows)
+	}
+}
+
+func TestWriteParquetFileWithoutColumnTypes(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "Bob", 2, time.Unix(0, 0), "bobby")
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age,nickname|"))
+	converter.SetRowPreProcessor(func(row []string, _ []string) (bool, []string) {
+		return row[0] != "Bob", row
+	})
+
+	name := filepath.Join(t.TempDir(), "test.parquet")
+	if err := converter.WriteParquetFile(name); err != nil {
+		t.Fatalf("error in WriteParquetFile: %v", err)
+	}
+
+	var rows [][]string
+	readParquetFile(t, name, func(row parquet.Row) {
+		rows = append(rows, parquetValues(row))
+	})
+
+	// the fake driver has no column types, so everything is a string
+	expected := [][]string{{"string:Alice", "string:1", "NULL"}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected rows %q, got %q", expected, rows)
+	}
+}
+
+func TestWriteParquetFileUnsigned(t *testing.T) {
+	queryUnsigned := func(t *testing.T, values ...driver.Value) *sql.Rows {
+		connector := typedConnector{columns: []typedColumn{{"id", "BIGINT UNSIGNED", reflect.TypeFor[uint64]()}}}
+		for _, value := range values {
+			connector.rows = append(connector.rows, []driver.Value{value})
+		}
+		db := sql.OpenDB(connector)
+		t.Cleanup(func() { db.Close() })
+		rows, err := db.Query("SELECT")
+		if err != nil {
+			t.Fatalf("error querying: %v", err)
+		}
+		t.Cleanup(func() { rows.Close() })
+		return rows
+	}
+
+	name := filepath.Join(t.TempDir(), "test.parquet")
+	if err := sqltocsv.New(queryUnsigned(t, []byte("42"), nil)).WriteParquetFile(name); err != nil {
+		t.Fatalf("error in WriteParquetFile: %v", err)
+	}
+	var rows [][]string
+	file := readParquetFile(t, name, func(row parquet.Row) {
+		rows = append(rows, parquetValues(row))
+	})
+	if expected := [][]string{{"int64:42"}, {"NULL"}}; !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected rows %q, got %q", expected, rows)
+	}
+	if kind := file.Schema().Fields()[0].Type().Kind(); kind != parquet.Int64 {
+		t.Errorf("expected an INT64 column, got %v", kind)
+	}
+
+	err := sqltocsv.New(queryUnsigned(t, []byte("18446744073709551615"))).WriteParquetFile(name)
+	if err == nil || !strings.Contains(err.Error(), "too big") {
+		t.Errorf("expected an error for a value above math.MaxInt64, got %v", err)
+	}
+}
+
+func TestWriteParquetFileGzipUncompressed(t *testing.T) {
+	value := strings.Repeat("Bob", 100)
+	write := func(uncompressed bool) int64 {
+		db := sql.OpenDB(typedConnector{
+			columns: []typedColumn{{"name", "VARCHAR", reflect.TypeFor[sql.RawBytes]()}},
+			count:   100,
+			generate: func(_ int, dest []driver.Value) {
+				dest[0] = []byte(value)
+			},
+		})
+		t.Cleanup(func() { db.Close() })
+		rows, err := db.Query("SELECT")
+		if err != nil {
+			t.Fatalf("error querying: %v", err)
+		}
+		t.Cleanup(func() { rows.Close() })
+
+		converter := sqltocsv.New(rows)
+		converter.GzipUncompressed = uncompressed
+		name := filepath.Join(t.TempDir(), "test.parquet")
+		if err := converter.WriteParquetFile(name); err != nil {
+			t.Fatalf("error in WriteParquetFile: %v", err)
+		}
+		read := 0
+		readParquetFile(t, name, func(row parquet.Row) {
+			if values := parquetValues(row); !reflect.DeepEqual(values, []string{"string:" + value}) {
+				t.Fatalf("expected %q, got %q", value, values)
+			}
+			read++
+		})
+		if read != 100 {
+			t.Errorf("expected 100 rows, read %d", read)
+		}
+		return int64(len(readFile(t, name)))
+	}
+
+	if compressed, uncompressed := write(false), write(true); uncompressed < 100*int64(len(value)) || compressed*2 > uncompressed {
+		t.Errorf("expected the pages to be stored without compression, got %d bytes (and %d compressed)", uncompressed, compressed)
+	}
+}
+
+func TestWriteParquetFileLarge(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large export in short mode")
+	}
+
+	const count = 500000
+	db := sql.OpenDB(typedConnector{
+		columns: []typedColumn{
+			{"id", "BIGINT", reflect.TypeFor[int64]()},
+			{"label", "VARCHAR", reflect.TypeFor[sql.RawBytes]()},
+			{"even", "BOOL", reflect.TypeFor[bool]()},
+		},
+		count: count,
+		generate: func(i int, dest []driver.Value) {
+			dest[0] = int64(i)
+			dest[1] = fmt.Sprintf("row %d of a result set too big to hold in memory", i)
+			dest[2] = nil
+			if i%3 != 0 {
+				dest[2] = i%2 == 0
+			}
+		},
+	})
+	defer db.Close()
+	rows, err := db.Query("SELECT")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+	defer rows.Close()
+
+	var first, peak uint64
+	converter := sqltocsv.New(rows)
+	converter.ParquetRowGroupSize = 50000
+	converter.SetProgressCallback(25000, func(rowsWritten int64) {
+		var stats runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+		if first == 0 {
+			first = stats.HeapAlloc
+		}
+		peak = max(peak, stats.HeapAlloc)
+	})
+
+	name := filepath.Join(t.TempDir(), "large.parquet")
+	if err := converter.WriteParquetFile(name); err != nil {
+		t.Fatalf("error in WriteParquetFile: %v", err)
+	}
+
+	// the values come to over 30MB, so holding them all would show up well
+	// above this
+	if growth := peak - first; growth > 16<<20 {
+		t.Errorf("expected memory to stay bounded by the row group, grew by %d bytes", growth)
+	}
+
+	read := 0
+	file := readParquetFile(t, name, func(row parquet.Row) {
+		values := parquetValues(row)
+		if values[0] != "int64:"+strconv.Itoa(read) {
+			t.Fatalf("expected row %d, got %q", read, values)
+		}
+		even := "NULL"
+		if read%3 != 0 {
+			even = "bool:" + strconv.FormatBool(read%2 == 0)
+		}
+		if values[2] != even {
+			t.Fatalf("row %d: expected %s, got %q", read, even, values)
+		}
+		read++
+	})
+	if read != count {
+		t.Errorf("expected %d rows, read %d", count, read)
+	}
+	if groups := len(file.RowGroups()); groups != count/50000 {
+		t.Errorf("expected %d row groups, got %d", count/50000, groups)
+	}
+}
@@ -0,0 +1,153 @@
+// Package pgxsqltocsv adapts pgx's native rows for sqltocsv, so that
+// queries run through pgx rather than database/sql can be written as CSV
+// with all of the same options.
+package pgxsqltocsv
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+// New returns a sqltocsv.Converter for pgx rows, with the same defaults as
+// sqltocsv.New. Closing the rows is up to the caller, as it is for
+// database/sql rows.
+func New(rows pgx.Rows) *sqltocsv.Converter {
+	return sqltocsv.NewFromSource(Source{rows})
+}
+
+// Source is a sqltocsv.RowSource reading from pgx rows.
+type Source struct {
+	Rows pgx.Rows
+}
+
+// Columns returns the names of the columns of the rows.
+func (s Source) Columns() ([]string, error) {
+	fields := s.Rows.FieldDescriptions()
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = field.Name
+	}
+	return columns, nil
+}
+
+// Next prepares the next row for Scan.
+func (s Source) Next() bool {
+	return s.Rows.Next()
+}
+
+// Scan reads the values of the current row into dest. Values scanned into
+// an any are passed through Value.
+func (s Source) Scan(dest ...any) error {
+	// so that a NULL is never left holding the previous row's value, which
+	// not every pgx.Rows makes sure of
+	for _, d := range dest {
+		if ptr, ok := d.(*any); ok {
+			*ptr = nil
+		}
+	}
+	if err := s.Rows.Scan(dest...); err != nil {
+		return err
+	}
+
+	fields := s.Rows.FieldDescriptions()
+	for i, d := range dest {
+		if ptr, ok := d.(*any); ok && i < len(fields) {
+			*ptr = Value(fields[i].DataTypeOID, *ptr)
+		}
+	}
+	return nil
+}
+
+// Err returns any error that occurred reading the rows.
+func (s Source) Err() error {
+	return s.Rows.Err()
+}
+
+// Value turns what pgx decodes a value of the PostgreSQL type oid into,
+// when scanning into an any, into one sqltocsv converts the way PostgreSQL
+// would show it:
+//
+//   - NULL pgtype values, such as an invalid pgtype.Numeric, become nil.
+//   - pgtype.Numeric becomes its exact decimal string, like "123.45" or
+//     "NaN", rather than going through a float.
+//   - pgtype.Date, pgtype.Timestamp and pgtype.Timestamptz become a
+//     time.Time for TimeFormat, or "infinity" or "-infinity".
+//   - pgtype.Int2, Int4, Int8, Float4, Float8, Bool and Text become the
+//     plain Go value they hold.
+//   - A uuid, which pgx decodes into a [16]byte, becomes its usual
+//     hyphenated string.
+//   - pgtype.Interval, pgtype.Time and the other types implementing
+//     driver.Valuer become the value that returns, which for those two is
+//     the text PostgreSQL itself would output.
+//
+// Anything else is returned as it is, for sqltocsv to convert as usual.
+func Value(oid uint32, v any) any {
+	switch val := v.(type) {
+	case [16]byte:
+		if oid == pgtype.UUIDOID {
+			return pgtype.UUID{Bytes: val, Valid: true}.String()
+		}
+		return val
+	case pgtype.Numeric:
+		return valuerValue(val)
+	case pgtype.Date:
+		return timeValue(val.Time, val.InfinityModifier, val.Valid)
+	case pgtype.Timestamp:
+		return timeValue(val.Time, val.InfinityModifier, val.Valid)
+	case pgtype.Timestamptz:
+		return timeValue(val.Time, val.InfinityModifier, val.Valid)
+	case pgtype.Int2:
+		return nullable(val.Int16, val.Valid)
+	case pgtype.Int4:
+		return nullable(val.Int32, val.Valid)
+	case pgtype.Int8:
+		return nullable(val.Int64, val.Valid)
+	case pgtype.Float4:
+		return nullable(val.Float32, val.Valid)
+	case pgtype.Float8:
+		return nullable(val.Float64, val.Valid)
+	case pgtype.Bool:
+		return nullable(val.Bool, val.Valid)
+	case pgtype.Text:
+		return nullable(val.String, val.Valid)
+	case pgtype.UUID:
+		return nullable(val.String(), val.Valid)
+	case driver.Valuer:
+		return valuerValue(val)
+	}
+	return v
+}
+
+// valuerValue returns the value of a pgtype implementing driver.Valuer, or
+// the pgtype itself if that fails for sqltocsv to report.
+func valuerValue(v driver.Valuer) any {
+	value, err := v.Value()
+	if err != nil {
+		return v
+	}
+	return value
+}
+
+func timeValue(t time.Time, infinity pgtype.InfinityModifier, valid bool) any {
+	switch {
+	case !valid:
+		return nil
+	case infinity == pgtype.Infinity:
+		return "infinity"
+	case infinity == pgtype.NegativeInfinity:
+		return "-infinity"
+	}
+	return t
+}
+
+func nullable[T any](v T, valid bool) any {
+	if !valid {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,124 @@
+package pgxsqltocsv_test
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pashagolub/pgxmock/v4"
+
+	"github.com/armantarkhanian/sqltocsv/pgxsqltocsv"
+)
+
+func TestNew(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("error creating mock: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	mock.ExpectQuery("SELECT id, name FROM people").WillReturnRows(
+		pgxmock.NewRows([]string{"id", "name"}).AddRow(int32(1), "Alice").AddRow(int32(2), "Bob"))
+	rows, err := mock.Query(context.Background(), "SELECT id, name FROM people")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+	defer rows.Close()
+
+	converter := pgxsqltocsv.New(rows)
+	converter.Delimiter = ';'
+	csv, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+
+	expected := "id;name\n1;Alice\n2;Bob\n"
+	if csv != expected {
+		t.Errorf("expected CSV:\n%s\ngot:\n%s", expected, csv)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPgtypes(t *testing.T) {
+	columns := []pgconn.FieldDescription{
+		{Name: "id", DataTypeOID: pgtype.Int4OID},
+		{Name: "price", DataTypeOID: pgtype.NumericOID},
+		{Name: "created", DataTypeOID: pgtype.TimestamptzOID},
+		{Name: "due", DataTypeOID: pgtype.DateOID},
+		{Name: "took", DataTypeOID: pgtype.IntervalOID},
+		{Name: "token", DataTypeOID: pgtype.UUIDOID},
+		{Name: "note", DataTypeOID: pgtype.TextOID},
+	}
+	token := [16]byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0}
+	rows := pgxmock.NewRowsWithColumnDefinition(columns...).
+		AddRow(
+			pgtype.Int4{Int32: 1, Valid: true},
+			pgtype.Numeric{Int: big.NewInt(1234500), Exp: -4, Valid: true},
+			time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			pgtype.Date{Time: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+			pgtype.Interval{Days: 1, Microseconds: 90 * 60e6, Valid: true},
+			token,
+			"first",
+		).
+		AddRow(
+			pgtype.Int4{Int32: 2, Valid: true},
+			pgtype.Numeric{NaN: true, Valid: true},
+			pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true},
+			pgtype.Date{},
+			nil,
+			nil,
+			pgtype.Text{},
+		).
+		Kind()
+
+	converter := pgxsqltocsv.New(rows)
+	converter.Headers = []string{"ID", "Price", "Created", "Due", "Took", "Token", "Note"}
+	converter.TimeFormat = time.DateOnly
+	converter.Delimiter = '\t'
+	converter.NullString = "-"
+	converter.SetRowPreProcessor(func(row []string, columnNames []string) (bool, []string) {
+		row[len(row)-1] = strings.ToUpper(row[len(row)-1])
+		return true, row
+	})
+
+	csv, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+
+	expected := "ID\tPrice\tCreated\tDue\tTook\tToken\tNote\n" +
+		"1\t123.4500\t2024-01-02\t2024-02-01\t1 day 01:30:00\t12345678-9abc-def0-1234-56789abcdef0\tFIRST\n" +
+		"2\tNaN\tinfinity\t-\t-\t-\t-\n"
+	if csv != expected {
+		t.Errorf("expected CSV:\n%s\ngot:\n%s", expected, csv)
+	}
+}
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		oid      uint32
+		value    any
+		expected any
+	}{
+		{pgtype.Int8OID, pgtype.Int8{Int64: 7, Valid: true}, int64(7)},
+		{pgtype.Float8OID, pgtype.Float8{Float64: 0.5, Valid: true}, 0.5},
+		{pgtype.BoolOID, pgtype.Bool{}, nil},
+		{pgtype.NumericOID, pgtype.Numeric{}, nil},
+		{pgtype.TimestampOID, pgtype.Timestamp{InfinityModifier: pgtype.NegativeInfinity, Valid: true}, "-infinity"},
+		{pgtype.TimeOID, pgtype.Time{Microseconds: 13 * 3600e6, Valid: true}, "13:00:00.000000"},
+		{pgtype.ByteaOID, [16]byte{1}, [16]byte{1}},
+		{pgtype.TextOID, "as is", "as is"},
+	}
+	for _, test := range tests {
+		if got := pgxsqltocsv.Value(test.oid, test.value); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Value(%d, %#v): expected %#v, got %#v", test.oid, test.value, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package sqltocsv
+
+import (
+	"context"
+	"fmt"
+)
+
+// pipelineDepth is how many scanned rows a Pipelined export lets pile up
+// ahead of the writer.
+const pipelineDepth = 256
+
+// scannedRow is a row on its way from the scanning goroutine to the writer.
+type scannedRow struct {
+	number int
+	values []any
+	err    error    // from scanning it, for the writer to hand to rowFailed
+	fields []string // what could be made of it, from scannedFields
+}
+
+// scanRowsPipelined is scanRows with the scanning done in a goroutine of its
+// own, so that the driver can be fetching the next rows while fn formats and
+// writes the earlier ones. Rows reach fn in order. If fn fails or stops early
+// the scanning goroutine is stopped and waited for before returning, so
+// c.rows is never in use once this returns.
+func (c Converter) scanRowsPipelined(ctx context.Context, p *plan, fn scanRowFunc) (bool, error) {
+	scanned := make(chan scannedRow, pipelineDepth)
+	stop := make(chan struct{})
+	finished := make(chan struct{})
+
+	// only read once finished is closed
+	var (
+		scanErr error
+		more    bool // whether a row was read but never sent
+	)
+
+	go func() {
+		defer close(finished)
+		defer close(scanned)
+
+		rows := c.rows
+		rowNumber := 0
+		for {
+			select {
+			case <-stop:
+				more = rows.Next()
+				return
+			default:
+			}
+
+			if !rows.Next() {
+				scanErr = rows.Err()
+				return
+			}
+			rowNumber++
+			if err := ctx.Err(); err != nil {
+				scanErr = fmt.Errorf("export cancelled: %w", err)
+				return
+			}
+
+			// a fresh slice per row, as the writer may still be
+			// using the last one
+			values := make([]any, len(p.queryColumns))
+			valuePtrs := make([]any, len(p.queryColumns))
+			p.scanPointers(values, valuePtrs)
+			row := scannedRow{number: rowNumber, values: values}
+			if row.err = rows.Scan(valuePtrs...); row.err == nil {
+				if int64(rowNumber) <= c.SkipRows {
+					continue
+				}
+				p.collectScanned(values, valuePtrs)
+			} else {
+				row.fields = c.scannedFields(p)
+			}
+
+			select {
+			case scanned <- row:
+			case <-stop:
+				more = true
+				return
+			}
+		}
+	}()
+
+	halt := func() {
+		close(stop)
+		<-finished
+	}
+
+	for row := range scanned {
+		if err := ctx.Err(); err != nil {
+			halt()
+			return false, fmt.Errorf("export cancelled: %w", err)
+		}
+		if row.err != nil {
+			if err := c.rowFailed(p, row.number, row.fields, row.err); err != nil {
+				halt()
+				return false, err
+			}
+			continue
+		}
+
+		done, err := fn(row.number, row.values)
+		if err != nil {
+			halt()
+			return false, err
+		}
+		if done {
+			halt()
+			return more || len(scanned) > 0, nil
+		}
+	}
+
+	<-finished
+	return false, scanErr
+}
@@ -0,0 +1,83 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestPipelined(t *testing.T) {
+	db := setupDatabase(t)
+	for i := 0; i < 1000; i++ {
+		exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", fmt.Sprintf("person %d", i), i, time.Unix(int64(i), 0), nil)
+	}
+
+	for _, setup := range []func(*sqltocsv.Converter){
+		func(*sqltocsv.Converter) {},
+		func(c *sqltocsv.Converter) { c.SkipRows = 10 },
+		func(c *sqltocsv.Converter) { c.MaxRows = 300 },
+		func(c *sqltocsv.Converter) { c.MaxRows = 1001 }, // all of them
+	} {
+		sequential := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age,bdate|"))
+		setup(sequential)
+		expected, err := sequential.WriteString()
+		if err != nil {
+			t.Fatalf("error in WriteString: %v", err)
+		}
+
+		pipelined := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age,bdate|"))
+		pipelined.Pipelined = true
+		setup(pipelined)
+		actual, err := pipelined.WriteString()
+		if err != nil {
+			t.Fatalf("error in pipelined WriteString: %v", err)
+		}
+
+		assertCsvMatch(t, expected, actual)
+		if pipelined.Truncated() != sequential.Truncated() {
+			t.Errorf("expected Truncated %v, got %v", sequential.Truncated(), pipelined.Truncated())
+		}
+	}
+}
+
+func TestPipelinedError(t *testing.T) {
+	db := setupDatabase(t)
+	for i := 0; i < 1000; i++ {
+		exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "Bob", i, time.Unix(0, 0), nil)
+	}
+	errBadAge := errors.New("bad age")
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age|"))
+	converter.Pipelined = true
+	converter.SetColumnFormatter("age", func(value any) (string, error) {
+		if value.(int64) == 500 {
+			return "", errBadAge
+		}
+		return fmt.Sprint(value), nil
+	})
+
+	err := converter.Write(&bytes.Buffer{})
+	if !errors.Is(err, errBadAge) {
+		t.Errorf("expected the formatter's error, got %v", err)
+	}
+	if converter.RowsWritten() != 501 {
+		t.Errorf("expected 501 rows written before the error, got %d", converter.RowsWritten())
+	}
+}
+
+func TestPipelinedCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	converter := getConverter(t)
+	converter.Pipelined = true
+	err := converter.WriteContext(ctx, &bytes.Buffer{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package sqltocsv
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// RowValidatorFunc checks a row as it's about to be written, after the
+// preprocessor and options like MaxFieldLength have been applied. Returning
+// an error rejects the row, see SetRowValidator.
+type RowValidatorFunc func(row []string, columnNames []string) error
+
+// SetRowValidator lets you specify a RowValidatorFunc to reject rows that
+// shouldn't be written. A rejected row fails like one that couldn't be
+// scanned: it aborts the export unless it's quarantined by
+// SetQuarantineWriter or skipped by SetErrorHandler.
+func (c *Converter) SetRowValidator(fn RowValidatorFunc) {
+	c.rowValidator = fn
+}
+
+// SetQuarantineWriter lets you specify a writer that rows which fail, to
+// scan or convert or by a RowValidatorFunc, are written to as CSV so they
+// can be repaired later, while the export carries on without them. The
+// quarantine has the same delimiter and line endings as the export and the
+// same column headers (written before its first row, if WriteHeaders is
+// set) with two more at the end: _error, saying what went wrong, and
+// _row_number, the row's 1-based position in the result set. Rows that
+// failed before they were converted are written with a plain conversion of
+// their scanned values, or empty fields if they couldn't be scanned at all.
+//
+// Quarantined rows count towards Stats.RowsFailed and MaxErrors, and a
+// SetErrorHandler handler is still asked about each of them first and can
+// abort the export by returning false. An error writing to the quarantine
+// aborts the export too.
+func (c *Converter) SetQuarantineWriter(w io.Writer) {
+	c.quarantineWriter = w
+}
+
+// quarantine writes the failed rows of an export to the quarantine writer.
+type quarantine struct {
+	csvWriter *csv.Writer
+	headers   []string // nil once written
+	width     int
+}
+
+func (c Converter) newQuarantine(headers []string) *quarantine {
+	csvWriter := csv.NewWriter(c.quarantineWriter)
+	csvWriter.Comma = c.comma()
+	csvWriter.UseCRLF = c.UseCRLF
+
+	q := &quarantine{csvWriter: csvWriter, width: len(headers)}
+	if c.WriteHeaders {
+		q.headers = append(append([]string(nil), headers...), "_error", "_row_number")
+	}
+	return q
+}
+
+// write quarantines a row, with the error it failed with. fields may be nil
+// if nothing is known of the row.
+func (q *quarantine) write(fields []string, rowNumber int, rowErr error) error {
+	if q.headers != nil {
+		if err := q.csvWriter.Write(q.headers); err != nil {
+			return fmt.Errorf("failed to write quarantine headers: %w", err)
+		}
+		q.headers = nil
+	}
+
+	if fields == nil {
+		fields = make([]string, q.width)
+	}
+	record := append(append([]string(nil), fields...), rowErr.Error(), strconv.Itoa(rowNumber))
+	if err := q.csvWriter.Write(record); err != nil {
+		return fmt.Errorf("failed to write row %d to quarantine: %w", rowNumber, err)
+	}
+	return nil
+}
+
+func (q *quarantine) flush() error {
+	q.csvWriter.Flush()
+	if err := q.csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed to flush quarantine: %w", err)
+	}
+	return nil
+}
+
+// rawFields renders the scanned values of a row, in output column order,
+// for the quarantine, without the formatters and converters that may have
+// been what failed.
+func (c Converter) rawFields(fields []any) []string {
+	row := make([]string, len(fields))
+	for i, field := range fields {
+		row[i] = c.toString(field)
+	}
+	return row
+}
+
+// scannedFields is rawFields for a row that failed to scan into values,
+// scanning it again into plain values to get what the driver returned. It
+// returns nil if the quarantine isn't in use, or that fails too.
+func (c Converter) scannedFields(p *plan) []string {
+	if p.quarantine == nil {
+		return nil
+	}
+	values := make([]any, len(p.queryColumns))
+	valuePtrs := make([]any, len(values))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	if err := c.rows.Scan(valuePtrs...); err != nil {
+		return nil
+	}
+
+	fields := make([]any, len(p.selected))
+	for i, idx := range p.selected {
+		fields[i] = values[idx]
+	}
+	return c.rawFields(fields)
+}
@@ -0,0 +1,137 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+// readQuarantine parses the records written to a quarantine.
+func readQuarantine(t *testing.T, data string, comma rune) [][]string {
+	t.Helper()
+	reader := csv.NewReader(strings.NewReader(data))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("error reading the quarantine %q: %v", data, err)
+	}
+	return records
+}
+
+func TestQuarantineScanError(t *testing.T) {
+	for _, pipelined := range []bool{false, true} {
+		converter := sqltocsv.New(queryMangledRows(t))
+		converter.UseColumnTypes = true
+		converter.Pipelined = pipelined
+		var quarantined bytes.Buffer
+		converter.SetQuarantineWriter(&quarantined)
+
+		csv, stats, err := converter.WriteStringWithStats()
+		if err != nil {
+			t.Fatalf("pipelined %v: error in WriteString: %v", pipelined, err)
+		}
+		assertCsvMatch(t, "id,created\n1,1970-01-01T00:00:00Z\n3,1970-01-01T00:01:00Z\n", csv)
+		if stats.RowsFailed != 1 {
+			t.Errorf("pipelined %v: expected 1 failed row, got %d", pipelined, stats.RowsFailed)
+		}
+
+		records := readQuarantine(t, quarantined.String(), ',')
+		if len(records) != 2 {
+			t.Fatalf("pipelined %v: expected a header and 1 row in the quarantine, got %q", pipelined, records)
+		}
+		if expected := []string{"id", "created", "_error", "_row_number"}; !reflect.DeepEqual(records[0], expected) {
+			t.Errorf("pipelined %v: expected quarantine headers %q, got %q", pipelined, expected, records[0])
+		}
+		row := records[1]
+		if row[0] != "2" || row[1] != "0000-00-00 99:99" || row[3] != "2" {
+			t.Errorf("pipelined %v: expected the scanned values and row number, got %q", pipelined, row)
+		}
+		if !strings.Contains(row[2], `name "created"`) {
+			t.Errorf("pipelined %v: expected the scan error, got %q", pipelined, row[2])
+		}
+	}
+}
+
+func TestQuarantineValidator(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=Bob,age=?", -5)
+	exec(t, db, "INSERT|people|name=Carol,age=?", 3)
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age|"))
+	converter.Delimiter = ';'
+	converter.UseCRLF = true
+	converter.SetRowValidator(func(row []string, columnNames []string) error {
+		if strings.HasPrefix(row[1], "-") {
+			return errors.New("age is negative; must be at least 0")
+		}
+		return nil
+	})
+	var quarantined bytes.Buffer
+	converter.SetQuarantineWriter(&quarantined)
+
+	assertCsvMatch(t, "name;age\r\nAlice;1\r\nCarol;3\r\n", converter.String())
+
+	expected := "name;age;_error;_row_number\r\n" +
+		"Bob;-5;\"row 2 rejected: age is negative; must be at least 0\";2\r\n"
+	if quarantined.String() != expected {
+		t.Errorf("expected quarantine:\n%s\ngot:\n%s", expected, quarantined.String())
+	}
+}
+
+func TestQuarantineFormatterError(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=Bob,age=?", 2)
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age|"))
+	converter.WriteHeaders = false
+	converter.SetColumnFormatter("age", func(value any) (string, error) {
+		if value.(int64) == 2 {
+			return "", errors.New("bad age")
+		}
+		return "one", nil
+	})
+	var quarantined bytes.Buffer
+	converter.SetQuarantineWriter(&quarantined)
+
+	assertCsvMatch(t, "Alice,one\n", converter.String())
+
+	// the formatter isn't used for the quarantine
+	expected := "Bob,2,\"failed to format column \"\"age\"\" in row 2: bad age\",2\n"
+	if quarantined.String() != expected {
+		t.Errorf("expected quarantine:\n%s\ngot:\n%s", expected, quarantined.String())
+	}
+}
+
+func TestQuarantineErrorHandlerAborts(t *testing.T) {
+	converter := sqltocsv.New(queryMangledRows(t))
+	converter.UseColumnTypes = true
+	var quarantined bytes.Buffer
+	converter.SetQuarantineWriter(&quarantined)
+	converter.SetErrorHandler(func(int64, error) bool { return false })
+
+	if _, err := converter.WriteString(); err == nil {
+		t.Fatal("expected the handler to abort the export")
+	}
+	if quarantined.Len() != 0 {
+		t.Errorf("expected nothing quarantined, got %q", quarantined.String())
+	}
+}
+
+func TestQuarantineEmpty(t *testing.T) {
+	converter := getConverter(t)
+	var quarantined bytes.Buffer
+	converter.SetQuarantineWriter(&quarantined)
+
+	if _, err := converter.WriteString(); err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	if quarantined.Len() != 0 {
+		t.Errorf("expected an empty quarantine, got %q", quarantined.String())
+	}
+}
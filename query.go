@@ -0,0 +1,28 @@
+package sqltocsv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Queryer runs a query for NewFromQuery. *sql.DB, *sql.Tx and *sql.Conn all
+// satisfy it.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// NewFromQuery runs query with args on db and returns a Converter for the
+// rows, with the same defaults as New. The Converter owns the rows: the
+// first of its Write methods to be called closes them when it returns,
+// whether or not it succeeded, so there is no rows.Close to forget.
+func NewFromQuery(ctx context.Context, db Queryer, query string, args ...any) (*Converter, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run query: %w", err)
+	}
+
+	c := New(rows)
+	c.ownsRows = true
+	return c, nil
+}
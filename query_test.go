@@ -0,0 +1,88 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+// assertRowsClosed fails the test if a query on db still has its connection,
+// as it does until its rows are closed.
+func assertRowsClosed(t *testing.T, db *sql.DB) {
+	t.Helper()
+	if inUse := db.Stats().InUse; inUse != 0 {
+		t.Errorf("expected the rows to be closed, %d connections still in use", inUse)
+	}
+}
+
+func TestNewFromQuery(t *testing.T) {
+	db := setupDatabase(t)
+
+	converter, err := sqltocsv.NewFromQuery(context.Background(), db, "SELECT|people|name,age,bdate|")
+	if err != nil {
+		t.Fatalf("error in NewFromQuery: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := converter.Write(&out); err != nil {
+		t.Fatalf("error in Write: %v", err)
+	}
+	assertCsvMatch(t, "name,age,bdate\nAlice,1,1973-11-29T21:33:09Z\n", out.String())
+	assertRowsClosed(t, db)
+}
+
+func TestNewFromQueryArgs(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=Bob,age=?", 2)
+
+	converter, err := sqltocsv.NewFromQuery(context.Background(), db, "SELECT|people|name|age=?", 2)
+	if err != nil {
+		t.Fatalf("error in NewFromQuery: %v", err)
+	}
+	assertCsvMatch(t, "name\nBob\n", converter.String())
+	assertRowsClosed(t, db)
+}
+
+func TestNewFromQueryClosesOnError(t *testing.T) {
+	db := setupDatabase(t)
+
+	converter, err := sqltocsv.NewFromQuery(context.Background(), db, "SELECT|people|name,age,bdate|")
+	if err != nil {
+		t.Fatalf("error in NewFromQuery: %v", err)
+	}
+
+	// fails before a single row is read
+	name := filepath.Join(t.TempDir(), "missing", "test.csv")
+	if err := converter.WriteFile(name); err == nil {
+		t.Fatal("expected an error writing to a missing directory")
+	}
+	assertRowsClosed(t, db)
+}
+
+func TestNewFromQueryTx(t *testing.T) {
+	db := setupDatabase(t)
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("error beginning transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	converter, err := sqltocsv.NewFromQuery(context.Background(), tx, "SELECT|people|name|")
+	if err != nil {
+		t.Fatalf("error in NewFromQuery: %v", err)
+	}
+	assertCsvMatch(t, "name\nAlice\n", converter.String())
+}
+
+func TestNewFromQueryError(t *testing.T) {
+	db := setupDatabase(t)
+
+	if _, err := sqltocsv.NewFromQuery(context.Background(), db, "SELECT|nosuchtable|name|"); err == nil {
+		t.Fatal("expected an error querying a missing table")
+	}
+	assertRowsClosed(t, db)
+}
@@ -0,0 +1,62 @@
+package sqltocsv
+
+import (
+	"context"
+	"database/sql"
+	"io"
+)
+
+// NewReader returns an io.ReadCloser producing the CSV (with headers) of
+// rows. See Converter.Reader.
+func NewReader(rows *sql.Rows) io.ReadCloser {
+	return newClosing(rows).Reader()
+}
+
+// Reader returns the CSV as an io.ReadCloser, for APIs such as uploaders
+// and http.Post that pull their data rather than being written to. The
+// export runs in a goroutine of its own, writing through an io.Pipe only as
+// fast as the bytes are read, and an error from it is returned by Read in
+// place of io.EOF. Close stops an export that hasn't finished and waits for
+// the goroutine to exit, so always close the reader, even after reading it
+// to the end; it returns the export's error if there was one before it was
+// closed.
+func (c Converter) Reader() io.ReadCloser {
+	pr, pw := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &reader{pr: pr, cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		defer close(r.done)
+		r.err = c.WriteContext(ctx, pw)
+		pw.CloseWithError(r.err) // io.EOF for Read if it's nil
+	}()
+	return r
+}
+
+// reader reads the CSV a Reader goroutine writes.
+type reader struct {
+	pr     *io.PipeReader
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error // only read once done is closed
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	return r.pr.Read(p)
+}
+
+func (r *reader) Close() error {
+	select {
+	case <-r.done:
+		r.cancel()
+		return r.err
+	default:
+	}
+
+	// the export fails on its next write or row, and that's no error of
+	// the caller's
+	r.cancel()
+	r.pr.Close()
+	<-r.done
+	return nil
+}
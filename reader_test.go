@@ -0,0 +1,86 @@
+package sqltocsv_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestReader(t *testing.T) {
+	reader := sqltocsv.NewReader(getTestRows(t))
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("error reading: %v", err)
+	}
+	if err := reader.Close(); err != nil {
+		t.Errorf("error closing: %v", err)
+	}
+	assertCsvMatch(t, "name,age,bdate\nAlice,1,1973-11-29T21:33:09Z\n", string(data))
+}
+
+func TestReaderError(t *testing.T) {
+	converter := getConverter(t)
+	failure := errors.New("can't format")
+	converter.SetColumnFormatter("age", func(any) (string, error) { return "", failure })
+
+	reader := converter.Reader()
+	if _, err := io.ReadAll(reader); !errors.Is(err, failure) {
+		t.Errorf("expected the formatter error from Read, got %v", err)
+	}
+	if err := reader.Close(); !errors.Is(err, failure) {
+		t.Errorf("expected the formatter error from Close, got %v", err)
+	}
+}
+
+func TestReaderCloseEarly(t *testing.T) {
+	db := sql.OpenDB(typedConnector{
+		columns: []typedColumn{{"id", "BIGINT", reflect.TypeFor[int64]()}},
+		count:   1000000,
+		generate: func(i int, dest []driver.Value) {
+			dest[0] = int64(i)
+		},
+	})
+	defer db.Close()
+	rows, err := db.Query("SELECT")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+	defer rows.Close()
+
+	before := runtime.NumGoroutine()
+	reader := sqltocsv.New(rows).Reader() // NewReader would close the rows
+	buf := make([]byte, 10)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		t.Fatalf("error reading: %v", err)
+	}
+	if string(buf) != "id\n0\n1\n2\n3" {
+		t.Errorf("unexpected start of the CSV %q", buf)
+	}
+	if err := reader.Close(); err != nil {
+		t.Errorf("expected no error closing early, got %v", err)
+	}
+	if _, err := reader.Read(buf); err == nil {
+		t.Error("expected an error reading after Close")
+	}
+
+	// Close waits for the export's goroutine, so it should be gone already,
+	// but give the runtime a moment to notice
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		buf := make([]byte, 1<<16)
+		t.Errorf("expected %d goroutines after Close, got %d:\n%s", before, after, buf[:runtime.Stack(buf, true)])
+	}
+	if !rows.Next() {
+		t.Error("expected Close to stop the export before the end of the rows")
+	}
+}
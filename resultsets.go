@@ -0,0 +1,145 @@
+package sqltocsv
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ResultSetNameFunc names the file WriteResultSetFiles writes a result set
+// to, given its 1-based number and its query columns.
+type ResultSetNameFunc func(set int, columns []string) string
+
+// writeResultSets writes the result sets that follow the one p was made
+// for, once writeCSV has written that one. Each set is written as it would
+// be on its own, after startSet has written the blank line and header row
+// it starts with, so they can have different columns. The stats of each set
+// are kept for ResultSetStats and the totals left in their place.
+func (c Converter) writeResultSets(ctx context.Context, p *plan, start time.Time, flush func(final bool) error,
+	writeRow func(row []string, values []any) error, startSet func(next *plan) error) error {
+	var sets []Stats
+	truncated := false
+	for {
+		// flushing makes BytesWritten the set's own
+		if err := flush(false); err != nil {
+			return err
+		}
+		stats := p.last.stats
+		stats.Duration = time.Since(start)
+		sets = append(sets, stats)
+		truncated = truncated || p.last.truncated
+
+		if !c.nextResultSet() {
+			break
+		}
+		start = time.Now()
+
+		var err error
+		if p, err = c.newPlan(); err != nil {
+			return fmt.Errorf("failed to read result set %d: %w", len(sets)+1, err)
+		}
+		p.keepValues = c.needsNulls()
+		if err := startSet(p); err != nil {
+			return err
+		}
+		if err := c.eachRow(ctx, p, writeRow); err != nil {
+			return err
+		}
+	}
+	if err := c.rows.Err(); err != nil {
+		return err
+	}
+
+	p.last.stats = totalStats(sets)
+	p.last.truncated = truncated
+	p.last.resultSets = sets
+	return nil
+}
+
+// WriteResultSetFiles writes each result set from rows.NextResultSet to a
+// file of its own, named by name, e.g.
+//
+//	func(set int, _ []string) string { return fmt.Sprintf("export-%d.csv", set) }
+//
+// Every file is written as WriteFile would write it. It returns the names
+// of the files created, including any created before an error occurred.
+// With EmptySkipFile there is no file for a result set without rows.
+func (c Converter) WriteResultSetFiles(name ResultSetNameFunc) (names []string, err error) {
+	defer c.closeRows(&err)
+
+	if c.last == nil {
+		c.last = &lastRun{}
+	}
+	c.AllResultSets = false
+	c.ownsRows, c.CloseRows = false, false // or WriteFile would close them after the first set
+
+	var sets []Stats
+	truncated := false
+	for {
+		columns, err := c.rows.Columns()
+		if err != nil {
+			return names, fmt.Errorf("failed to read result set %d: %w", len(sets)+1, err)
+		}
+		fileName := name(len(sets)+1, columns)
+		if err := c.WriteFile(fileName); err != nil {
+			return names, err
+		}
+		if c.EmptyMode == EmptyWriteHeader || c.last.stats.RowsWritten > 0 {
+			names = append(names, fileName)
+		}
+		sets = append(sets, c.last.stats)
+		truncated = truncated || c.last.truncated
+
+		if !c.nextResultSet() {
+			break
+		}
+	}
+	if err := c.rows.Err(); err != nil {
+		return names, err
+	}
+
+	c.last.stats = totalStats(sets)
+	c.last.truncated = truncated
+	c.last.resultSets = sets
+	return names, nil
+}
+
+// ResultSetStats returns the Stats of each result set written by the most
+// recent export, when it was made with AllResultSets or
+// WriteResultSetFiles. WriteWithStats and the like give their totals, with
+// Columns the most any set had.
+func (c Converter) ResultSetStats() []Stats {
+	if c.last == nil {
+		return nil
+	}
+	return c.last.resultSets
+}
+
+// totalStats adds up the stats of the result sets of an export.
+func totalStats(sets []Stats) Stats {
+	var total Stats
+	for _, s := range sets {
+		total.RowsWritten += s.RowsWritten
+		total.RowsSkipped += s.RowsSkipped
+		total.RowsFailed += s.RowsFailed
+		total.BytesWritten += s.BytesWritten
+		total.Columns = max(total.Columns, s.Columns)
+		total.Duration += s.Duration
+		total.FieldsTruncated += s.FieldsTruncated
+		total.DuplicateRows += s.DuplicateRows
+		total.MaskedColumns = appendNew(total.MaskedColumns, s.MaskedColumns)
+		total.HashedColumns = appendNew(total.HashedColumns, s.HashedColumns)
+	}
+	return total
+}
+
+// appendNew appends the names that aren't already in names to it.
+func appendNew(names, more []string) []string {
+	for _, name := range more {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,141 @@
+package sqltocsv_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+// queryResultSets returns rows with two result sets of different shapes,
+// the way a stored procedure can.
+func queryResultSets(t *testing.T) *sql.Rows {
+	db := sql.OpenDB(typedConnector{
+		columns: []typedColumn{
+			{"id", "BIGINT", reflect.TypeFor[int64]()},
+			{"name", "VARCHAR", reflect.TypeFor[sql.RawBytes]()},
+		},
+		rows: [][]driver.Value{
+			{int64(1), []byte("Alice")},
+			{int64(2), []byte("Bob")},
+		},
+		resultSets: []typedConnector{{
+			columns: []typedColumn{
+				{"total", "BIGINT", reflect.TypeFor[int64]()},
+				{"average", "DOUBLE", reflect.TypeFor[float64]()},
+				{"label", "VARCHAR", reflect.TypeFor[sql.RawBytes]()},
+			},
+			rows: [][]driver.Value{{int64(3), 1.5, []byte("people")}},
+		}},
+	})
+	t.Cleanup(func() { db.Close() })
+
+	rows, err := db.Query("EXEC")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+	t.Cleanup(func() { rows.Close() })
+	return rows
+}
+
+func TestAllResultSetsPerSetOptions(t *testing.T) {
+	converter := sqltocsv.New(queryResultSets(t))
+	converter.AllResultSets = true
+	converter.MaxRows = 1
+	converter.RowNumberHeader = "n"
+
+	csv, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+
+	// the second set is numbered, and limited, from its own first row
+	expected := "n,id,name\n1,1,Alice\n\nn,total,average,label\n1,3,1.5,people\n"
+	assertCsvMatch(t, expected, csv)
+	if !converter.Truncated() {
+		t.Error("expected the first set to be truncated")
+	}
+}
+
+func TestAllResultSets(t *testing.T) {
+	converter := sqltocsv.New(queryResultSets(t))
+	converter.AllResultSets = true
+
+	csv, stats, err := converter.WriteStringWithStats()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+
+	expected := "id,name\n1,Alice\n2,Bob\n\ntotal,average,label\n3,1.5,people\n"
+	assertCsvMatch(t, expected, csv)
+
+	sets := converter.ResultSetStats()
+	if len(sets) != 2 {
+		t.Fatalf("expected stats for 2 result sets, got %d", len(sets))
+	}
+	first, second := sets[0], sets[1]
+	if first.RowsWritten != 2 || first.Columns != 2 || first.BytesWritten != int64(len("id,name\n1,Alice\n2,Bob\n")) {
+		t.Errorf("unexpected stats for the first result set: %+v", first)
+	}
+	if second.RowsWritten != 1 || second.Columns != 3 || second.BytesWritten != int64(len("\ntotal,average,label\n3,1.5,people\n")) {
+		t.Errorf("unexpected stats for the second result set: %+v", second)
+	}
+	if stats.RowsWritten != 3 || stats.Columns != 3 || stats.BytesWritten != int64(len(expected)) {
+		t.Errorf("expected the stats to be the totals, got %+v", stats)
+	}
+}
+
+func TestAllResultSetsOff(t *testing.T) {
+	converter := sqltocsv.New(queryResultSets(t))
+
+	assertCsvMatch(t, "id,name\n1,Alice\n2,Bob\n", converter.String())
+	if sets := converter.ResultSetStats(); sets != nil {
+		t.Errorf("expected no result set stats, got %+v", sets)
+	}
+}
+
+func TestAllResultSetsWithoutHeaders(t *testing.T) {
+	converter := sqltocsv.New(queryResultSets(t))
+	converter.AllResultSets = true
+	converter.WriteHeaders = false
+	converter.UseCRLF = true
+
+	assertCsvMatch(t, "1,Alice\r\n2,Bob\r\n\r\n3,1.5,people\r\n", converter.String())
+}
+
+func TestWriteResultSetFiles(t *testing.T) {
+	dir := t.TempDir()
+	converter := sqltocsv.New(queryResultSets(t))
+
+	names, err := converter.WriteResultSetFiles(func(set int, columns []string) string {
+		return filepath.Join(dir, fmt.Sprintf("%d-%s.csv", set, columns[0]))
+	})
+	if err != nil {
+		t.Fatalf("error in WriteResultSetFiles: %v", err)
+	}
+
+	expectedNames := []string{filepath.Join(dir, "1-id.csv"), filepath.Join(dir, "2-total.csv")}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Fatalf("expected files %q, got %q", expectedNames, names)
+	}
+	for i, expected := range []string{"id,name\n1,Alice\n2,Bob\n", "total,average,label\n3,1.5,people\n"} {
+		data, err := os.ReadFile(names[i])
+		if err != nil {
+			t.Fatalf("error reading %s: %v", names[i], err)
+		}
+		assertCsvMatch(t, expected, string(data))
+	}
+
+	sets := converter.ResultSetStats()
+	if len(sets) != 2 || sets[0].RowsWritten != 2 || sets[1].RowsWritten != 1 {
+		t.Errorf("unexpected result set stats: %+v", sets)
+	}
+	if converter.RowsWritten() != 3 {
+		t.Errorf("expected 3 rows written in all, got %d", converter.RowsWritten())
+	}
+}
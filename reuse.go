@@ -0,0 +1,41 @@
+package sqltocsv
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// ErrRowsConsumed is returned by an export from a Converter whose rows an
+// earlier export has already read, as they can only be read the once. Run
+// the query again and Reset the Converter to export it again.
+var ErrRowsConsumed = errors.New("the rows have already been read by an earlier export")
+
+// Reset points the Converter at a fresh set of rows, keeping its
+// configuration, so that it can export them as it did the last ones. Copies
+// of the Converter made before Reset keep the old rows.
+func (c *Converter) Reset(rows *sql.Rows) {
+	c.ResetSource(rows)
+}
+
+// ResetSource is Reset for rows from any RowSource, see NewFromSource.
+func (c *Converter) ResetSource(rows RowSource) {
+	c.rows = rows
+	c.ownsRows = false
+	c.last = &lastRun{}
+}
+
+// LastError returns the error the most recent CSV export failed with, or nil
+// if it succeeded, such as the error String can't return.
+func (c Converter) LastError() error {
+	if c.last == nil {
+		return nil
+	}
+	return c.last.err
+}
+
+// recordError records the error an export returns in err for LastError.
+func (c Converter) recordError(err *error) {
+	if c.last != nil {
+		c.last.err = *err
+	}
+}
@@ -0,0 +1,52 @@
+package sqltocsv_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestErrRowsConsumed(t *testing.T) {
+	source := &sliceSource{columns: []string{"id"}, rows: [][]any{{int64(1)}}}
+	converter := sqltocsv.NewFromSource(source)
+
+	if _, err := converter.WriteString(); err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	err := converter.WriteFile(filepath.Join(t.TempDir(), "again.csv"))
+	if !errors.Is(err, sqltocsv.ErrRowsConsumed) {
+		t.Fatalf("expected ErrRowsConsumed, got %v", err)
+	}
+	if csv := converter.String(); csv != "" {
+		t.Errorf("expected nothing from String, got %q", csv)
+	}
+	if err := converter.LastError(); !errors.Is(err, sqltocsv.ErrRowsConsumed) {
+		t.Errorf("expected LastError to be ErrRowsConsumed, got %v", err)
+	}
+}
+
+func TestReset(t *testing.T) {
+	db := setupDatabase(t)
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age|"))
+	converter.Delimiter = ';'
+	assertCsvMatch(t, "name;age\nAlice;1\n", converter.String())
+
+	converter.Reset(queryTestRows(t, db, "SELECT|people|name,age|"))
+	assertCsvMatch(t, "name;age\nAlice;1\n", converter.String())
+	if err := converter.LastError(); err != nil {
+		t.Errorf("expected no error after Reset, got %v", err)
+	}
+}
+
+func TestLastError(t *testing.T) {
+	converter := getConverter(t)
+	converter.Headers = []string{"too", "few"}
+	if csv := converter.String(); csv != "" {
+		t.Errorf("expected nothing from String, got %q", csv)
+	}
+	if converter.LastError() == nil {
+		t.Error("expected the error String hid from LastError")
+	}
+}
@@ -0,0 +1,44 @@
+package sqltocsv
+
+import "fmt"
+
+// ErrorHandlerFunc is called with the 1-based position in the result set of
+// a row that couldn't be scanned or converted, and the error. Return true to
+// skip the row and carry on, or false to abort the export with the error.
+type ErrorHandlerFunc func(rowIndex int64, err error) bool
+
+// SetErrorHandler lets you specify an ErrorHandlerFunc for rows that fail,
+// such as with a scan error on a mangled timestamp or an error from a
+// ColumnFormatterFunc, so that one bad row needn't abort a long export.
+// Rows it skips are counted in Stats.RowsFailed, and once more than
+// MaxErrors have been skipped the export is aborted regardless. Errors
+// writing the output or from rows.Err always abort it. Without a handler
+// or SetQuarantineWriter the first error does.
+func (c *Converter) SetErrorHandler(fn ErrorHandlerFunc) {
+	c.errorHandler = fn
+}
+
+// rowFailed decides what becomes of a row that couldn't be scanned or
+// converted, or was rejected: it returns nil if the error handler skips it
+// or it's quarantined with fields, or else the error to abort the export
+// with.
+func (c Converter) rowFailed(p *plan, rowNumber int, fields []string, err error) error {
+	if c.errorHandler != nil {
+		if !c.errorHandler(int64(rowNumber), err) {
+			return err
+		}
+	} else if p.quarantine == nil {
+		return err
+	}
+
+	if p.quarantine != nil {
+		if err := p.quarantine.write(fields, rowNumber, err); err != nil {
+			return err
+		}
+	}
+	p.last.stats.RowsFailed++
+	if c.MaxErrors > 0 && p.last.stats.RowsFailed > c.MaxErrors {
+		return fmt.Errorf("more than %d rows failed, the last with: %w", c.MaxErrors, err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package sqltocsv_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+// queryMangledRows returns rows whose second row has a timestamp that can't
+// be scanned into a time.Time.
+func queryMangledRows(t *testing.T) *sql.Rows {
+	db := sql.OpenDB(typedConnector{
+		columns: []typedColumn{
+			{"id", "BIGINT", reflect.TypeFor[int64]()},
+			{"created", "DATETIME", reflect.TypeFor[time.Time]()},
+		},
+		rows: [][]driver.Value{
+			{int64(1), time.Unix(0, 0).UTC()},
+			{int64(2), []byte("0000-00-00 99:99")},
+			{int64(3), time.Unix(60, 0).UTC()},
+		},
+	})
+	t.Cleanup(func() { db.Close() })
+
+	rows, err := db.Query("SELECT")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+	t.Cleanup(func() { rows.Close() })
+	return rows
+}
+
+func TestErrorHandlerScanError(t *testing.T) {
+	for _, pipelined := range []bool{false, true} {
+		converter := sqltocsv.New(queryMangledRows(t))
+		converter.UseColumnTypes = true
+		converter.Pipelined = pipelined
+
+		var failed []int64
+		converter.SetErrorHandler(func(rowIndex int64, err error) bool {
+			failed = append(failed, rowIndex)
+			return true
+		})
+
+		csv, stats, err := converter.WriteStringWithStats()
+		if err != nil {
+			t.Fatalf("pipelined %v: error in WriteString: %v", pipelined, err)
+		}
+		assertCsvMatch(t, "id,created\n1,1970-01-01T00:00:00Z\n3,1970-01-01T00:01:00Z\n", csv)
+		if !reflect.DeepEqual(failed, []int64{2}) {
+			t.Errorf("pipelined %v: expected the handler to get row 2, got %v", pipelined, failed)
+		}
+		if stats.RowsFailed != 1 || stats.RowsWritten != 2 || stats.RowsSkipped != 0 {
+			t.Errorf("pipelined %v: unexpected stats %+v", pipelined, stats)
+		}
+	}
+}
+
+func TestErrorHandlerAbort(t *testing.T) {
+	converter := sqltocsv.New(queryMangledRows(t))
+	converter.UseColumnTypes = true
+	converter.SetErrorHandler(func(int64, error) bool { return false })
+
+	if _, err := converter.WriteString(); err == nil {
+		t.Fatal("expected the scan error when the handler returns false")
+	}
+}
+
+func TestErrorHandlerUnset(t *testing.T) {
+	converter := sqltocsv.New(queryMangledRows(t))
+	converter.UseColumnTypes = true
+
+	if _, err := converter.WriteString(); err == nil {
+		t.Fatal("expected the scan error without a handler")
+	}
+}
+
+func TestErrorHandlerFormatter(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=Bob,age=?", 2)
+	exec(t, db, "INSERT|people|name=Carol,age=?", 3)
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age|"))
+	converter.SetColumnFormatter("name", func(value any) (string, error) {
+		if string(value.([]byte)) == "Bob" {
+			return "", errors.New("no Bobs")
+		}
+		return string(value.([]byte)), nil
+	})
+	var reported error
+	converter.SetErrorHandler(func(rowIndex int64, err error) bool {
+		reported = err
+		return true
+	})
+
+	csv, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	if strings.Contains(csv, "Bob") || !strings.Contains(csv, "Carol") {
+		t.Errorf("expected only Bob's row to be left out, got:\n%s", csv)
+	}
+	if reported == nil || !strings.Contains(reported.Error(), `column "name" in row 2`) {
+		t.Errorf("expected the handler to get the formatter error, got %v", reported)
+	}
+}
+
+func TestMaxErrors(t *testing.T) {
+	db := setupDatabase(t)
+	for _, name := range []string{"Bob", "Carol", "Dave"} {
+		exec(t, db, "INSERT|people|name=?,age=?", name, 2)
+	}
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age|"))
+	converter.MaxErrors = 2
+	converter.SetColumnFormatter("age", func(value any) (string, error) {
+		if value.(int64) == 2 {
+			return "", errors.New("bad age")
+		}
+		return "1", nil
+	})
+	converter.SetErrorHandler(func(int64, error) bool { return true })
+
+	_, stats, err := converter.WriteStringWithStats()
+	if err == nil || !strings.Contains(err.Error(), "more than 2 rows failed") {
+		t.Fatalf("expected the export to stop after 2 errors, got %v", err)
+	}
+	if stats.RowsFailed != 3 || stats.RowsWritten != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
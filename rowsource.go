@@ -0,0 +1,85 @@
+package sqltocsv
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// RowSource is what a Converter reads its rows from. *sql.Rows is one, and
+// New takes it as it is; NewFromSource takes anything else with the same
+// methods, such as a driver's native rows or a fake in a test. Scan is only
+// ever called with *any destinations, unless UseColumnTypes is set.
+//
+// A RowSource can do more by also having the methods of *sql.Rows these
+// need: ColumnTypes() ([]*sql.ColumnType, error) for UseColumnTypes,
+// NextResultSet() bool for AllResultSets and WriteResultSetFiles, and
+// Close() error for a Converter from NewFromQuery or with CloseRows.
+type RowSource interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+}
+
+// columnTyper is a RowSource with column types, like *sql.Rows.
+type columnTyper interface {
+	ColumnTypes() ([]*sql.ColumnType, error)
+}
+
+// resultSetter is a RowSource that can have more than one result set, like
+// *sql.Rows.
+type resultSetter interface {
+	NextResultSet() bool
+}
+
+// NewFromSource returns a Converter for rows from any RowSource, with the
+// same defaults as New.
+func NewFromSource(rows RowSource) *Converter {
+	return &Converter{
+		rows:         rows,
+		WriteHeaders: true,
+		Delimiter:    ',',
+		last:         &lastRun{},
+	}
+}
+
+// columnTypes returns the column types of the rows for UseColumnTypes.
+func (c Converter) columnTypes() ([]*sql.ColumnType, error) {
+	typer, ok := c.rows.(columnTyper)
+	if !ok {
+		return nil, errors.New("UseColumnTypes needs rows with ColumnTypes, such as *sql.Rows")
+	}
+	return typer.ColumnTypes()
+}
+
+// nextResultSet moves on to the next result set, reporting whether there is
+// one. A RowSource without NextResultSet only ever has the one.
+func (c Converter) nextResultSet() bool {
+	setter, ok := c.rows.(resultSetter)
+	if !ok || !setter.NextResultSet() {
+		return false
+	}
+	if c.last != nil {
+		c.last.consumed = false
+	}
+	return true
+}
+
+// closeOwnedRows closes the rows if the Converter came from NewFromQuery or
+// has CloseRows set, for the Write methods to defer.
+func (c Converter) closeOwnedRows() error {
+	if closer, ok := c.rows.(io.Closer); ok && (c.ownsRows || c.CloseRows) {
+		return closer.Close()
+	}
+	return nil
+}
+
+// closeRows is closeOwnedRows for the CSV writers to defer, adding any error
+// closing the rows to the one they return in err.
+func (c Converter) closeRows(err *error) {
+	if closeErr := c.closeOwnedRows(); closeErr != nil {
+		*err = errors.Join(*err, fmt.Errorf("failed to close rows: %w", closeErr))
+	}
+}
@@ -0,0 +1,85 @@
+package sqltocsv_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+// sliceSource is a RowSource over rows held in memory, failing with err
+// once they run out if it's set.
+type sliceSource struct {
+	columns []string
+	rows    [][]any
+	err     error
+	next    int
+}
+
+func (s *sliceSource) Columns() ([]string, error) { return s.columns, nil }
+func (s *sliceSource) Err() error                 { return s.err }
+
+func (s *sliceSource) Next() bool {
+	if s.next == len(s.rows) {
+		return false
+	}
+	s.next++
+	return true
+}
+
+func (s *sliceSource) Scan(dest ...any) error {
+	row := s.rows[s.next-1]
+	if len(dest) != len(row) {
+		return errors.New("wrong number of values")
+	}
+	for i, value := range row {
+		*dest[i].(*any) = value
+	}
+	return nil
+}
+
+func TestNewFromSource(t *testing.T) {
+	source := &sliceSource{
+		columns: []string{"id", "name", "score"},
+		rows: [][]any{
+			{int64(1), "Alice", 1.5},
+			{int64(2), nil, nil},
+		},
+	}
+
+	converter := sqltocsv.NewFromSource(source)
+	converter.NullString = "NULL"
+	converter.FloatFormat = "%.2f"
+
+	assertCsvMatch(t, "id,name,score\n1,Alice,1.50\n2,NULL,NULL\n", converter.String())
+}
+
+func TestNewFromSourceError(t *testing.T) {
+	failure := errors.New("connection lost")
+	source := &sliceSource{
+		columns: []string{"id"},
+		rows:    [][]any{{int64(1)}},
+		err:     failure,
+	}
+
+	if _, err := sqltocsv.NewFromSource(source).WriteString(); !errors.Is(err, failure) {
+		t.Errorf("expected the source's error, got %v", err)
+	}
+}
+
+func TestNewFromSourceWithoutColumnTypes(t *testing.T) {
+	converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"id"}})
+	converter.UseColumnTypes = true
+
+	if _, err := converter.WriteString(); err == nil {
+		t.Error("expected an error using column types the source doesn't have")
+	}
+}
+
+func TestNewFromSourceAllResultSets(t *testing.T) {
+	converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"id"}, rows: [][]any{{int64(1)}}})
+	converter.AllResultSets = true
+
+	// a source without NextResultSet just has the one
+	assertCsvMatch(t, "id\n1\n", converter.String())
+}
@@ -0,0 +1,128 @@
+package sqltocsv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SchemaColumn describes a column of the CSV, see Schema.
+type SchemaColumn struct {
+	Position     int    `json:"position"`                // 1-based position in the CSV
+	Name         string `json:"name"`                    // Header, after HeaderMap and any SetHeaderTransform
+	Column       string `json:"column,omitempty"`        // Query column name, none for RowNumberHeader, StaticColumns and computed columns
+	DatabaseType string `json:"database_type,omitempty"` // As the driver names it, such as "VARCHAR"
+	Nullable     *bool  `json:"nullable,omitempty"`      // Whether the column can be NULL, if the driver knows
+	Length       *int64 `json:"length,omitempty"`        // Length of variable length types, if the driver knows
+	Precision    *int64 `json:"precision,omitempty"`     // Precision of decimal types, if the driver knows
+	Scale        *int64 `json:"scale,omitempty"`         // Scale of decimal types, if the driver knows
+}
+
+// Schema describes the columns of the CSV an export would write, in order,
+// from rows.ColumnTypes() and the column options. It only reads the columns,
+// not the rows, so it can be called before writing them.
+func (c Converter) Schema() ([]SchemaColumn, error) {
+	typer, ok := c.rows.(columnTyper)
+	if !ok {
+		return nil, errors.New("the schema needs rows with ColumnTypes, such as *sql.Rows")
+	}
+	columnTypes, err := typer.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read column types: %w", err)
+	}
+
+	c.last = nil // or newPlan would reset the stats of the last export
+	p, err := c.newPlan()
+	if err != nil {
+		return nil, err
+	}
+
+	schema := make([]SchemaColumn, len(p.headers))
+	for i, header := range p.headers {
+		schema[i] = SchemaColumn{Position: i + 1, Name: header}
+	}
+	for i, idx := range p.selected {
+		column := &schema[len(p.before)+i]
+		columnType := columnTypes[idx]
+		column.Column = p.queryColumns[idx]
+		column.DatabaseType = columnType.DatabaseTypeName()
+		if nullable, ok := columnType.Nullable(); ok {
+			column.Nullable = &nullable
+		}
+		if length, ok := columnType.Length(); ok {
+			column.Length = &length
+		}
+		if precision, scale, ok := columnType.DecimalSize(); ok {
+			column.Precision, column.Scale = &precision, &scale
+		}
+	}
+	return schema, nil
+}
+
+// WriteSchemaFile writes the Schema to the filename specified as JSON, an
+// object with the columns in "columns". The rows are left to be written
+// afterwards; see SchemaSidecar to write both at once with WriteFile.
+func (c Converter) WriteSchemaFile(schemaFileName string) error {
+	schema, err := c.schemaJSON()
+	if err != nil {
+		return err
+	}
+	return c.writeSchema(schemaFileName, schema)
+}
+
+// SchemaFileName returns the name of the SchemaSidecar file WriteFile writes
+// along with csvFileName, which is its name without the extension followed
+// by ".schema.json", e.g. "export.schema.json" for "export.csv".
+func SchemaFileName(csvFileName string) string {
+	return strings.TrimSuffix(csvFileName, filepath.Ext(csvFileName)) + ".schema.json"
+}
+
+func (c Converter) schemaJSON() ([]byte, error) {
+	columns, err := c.Schema()
+	if err != nil {
+		return nil, err
+	}
+	schema, err := json.MarshalIndent(struct {
+		Columns []SchemaColumn `json:"columns"`
+	}{columns}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode schema: %w", err)
+	}
+	return append(schema, '\n'), nil
+}
+
+func (c Converter) writeSchema(schemaFileName string, schema []byte) error {
+	f, err := c.createFile(schemaFileName)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(schema); err != nil {
+		f.abort()
+		return fmt.Errorf("failed to write schema: %w", err)
+	}
+	return f.commit()
+}
+
+// writeFileWithSchema is WriteFile for SchemaSidecar. The schema is worked
+// out first, while the column types are still there to read, but only
+// written once the CSV has been.
+func (c Converter) writeFileWithSchema(ctx context.Context, csvFileName string) error {
+	schema, err := c.schemaJSON()
+	if err != nil {
+		return err
+	}
+	if c.last == nil {
+		c.last = &lastRun{}
+	}
+
+	if err := c.writeFile(ctx, csvFileName); err != nil {
+		return err
+	}
+	if c.EmptyMode != EmptyWriteHeader && c.last.stats.RowsWritten == 0 {
+		return nil // there's no CSV to go with it
+	}
+	return c.writeSchema(SchemaFileName(csvFileName), schema)
+}
@@ -0,0 +1,172 @@
+package sqltocsv_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+// sizedConnector is a typedConnector whose rows also report if their
+// columns are nullable and what size they are.
+type sizedConnector struct {
+	typedConnector
+	nullable  []bool
+	length    []int64
+	precision []int64 // with a scale of 2 for each
+}
+
+func (c sizedConnector) Connect(context.Context) (driver.Conn, error) { return sizedConn{c}, nil }
+
+type sizedConn struct{ sizedConnector }
+
+func (c sizedConn) Prepare(string) (driver.Stmt, error) { return sizedStmt{c.sizedConnector}, nil }
+func (c sizedConn) Close() error                        { return nil }
+func (c sizedConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+type sizedStmt struct{ sizedConnector }
+
+func (s sizedStmt) Close() error                               { return nil }
+func (s sizedStmt) NumInput() int                              { return 0 }
+func (s sizedStmt) Exec([]driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (s sizedStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &sizedRows{typedRows{typedConnector: s.typedConnector}, s.sizedConnector}, nil
+}
+
+type sizedRows struct {
+	typedRows
+	sizes sizedConnector
+}
+
+func (r *sizedRows) ColumnTypeNullable(i int) (bool, bool) { return r.sizes.nullable[i], true }
+func (r *sizedRows) ColumnTypeLength(i int) (int64, bool) {
+	return r.sizes.length[i], r.sizes.length[i] > 0
+}
+func (r *sizedRows) ColumnTypePrecisionScale(i int) (int64, int64, bool) {
+	return r.sizes.precision[i], 2, r.sizes.precision[i] > 0
+}
+
+func querySizedRows(t *testing.T) *sql.Rows {
+	db := sql.OpenDB(sizedConnector{
+		typedConnector: typedConnector{
+			columns: []typedColumn{
+				{"id", "BIGINT", reflect.TypeFor[int64]()},
+				{"name", "VARCHAR", reflect.TypeFor[sql.RawBytes]()},
+				{"price", "DECIMAL", reflect.TypeFor[sql.RawBytes]()},
+				{"created", "DATETIME", reflect.TypeFor[time.Time]()},
+			},
+			rows: [][]driver.Value{
+				{int64(1), []byte("Alice"), []byte("9.99"), time.Unix(0, 0).UTC()},
+			},
+		},
+		nullable:  []bool{false, true, true, false},
+		length:    []int64{0, 255, 0, 0},
+		precision: []int64{0, 0, 10, 0},
+	})
+	t.Cleanup(func() { db.Close() })
+
+	rows, err := db.Query("SELECT")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+	t.Cleanup(func() { rows.Close() })
+	return rows
+}
+
+func TestSchema(t *testing.T) {
+	converter := sqltocsv.New(querySizedRows(t))
+	converter.ExcludeColumns = []string{"created"}
+	converter.ColumnOrder = []string{"name"}
+	converter.HeaderMap = map[string]string{"name": "Name"}
+	converter.SetHeaderTransform(strings.ToUpper)
+	converter.RowNumberHeader = "n"
+
+	schema, err := converter.Schema()
+	if err != nil {
+		t.Fatalf("error in Schema: %v", err)
+	}
+	yes, no := true, false
+	length, precision, scale := int64(255), int64(10), int64(2)
+	expected := []sqltocsv.SchemaColumn{
+		{Position: 1, Name: "n"},
+		{Position: 2, Name: "Name", Column: "name", DatabaseType: "VARCHAR", Nullable: &yes, Length: &length},
+		{Position: 3, Name: "ID", Column: "id", DatabaseType: "BIGINT", Nullable: &no},
+		{Position: 4, Name: "PRICE", Column: "price", DatabaseType: "DECIMAL", Nullable: &yes, Precision: &precision, Scale: &scale},
+	}
+	if !reflect.DeepEqual(schema, expected) {
+		t.Errorf("expected schema %+v, got %+v", expected, schema)
+	}
+
+	// the rows are still there to write
+	assertCsvMatch(t, "n,Name,ID,PRICE\n1,Alice,1,9.99\n", converter.String())
+}
+
+func TestSchemaNeedsColumnTypes(t *testing.T) {
+	converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"id"}})
+	if _, err := converter.Schema(); err == nil {
+		t.Error("expected an error for a source without ColumnTypes")
+	}
+}
+
+func TestSchemaSidecar(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "export.csv")
+	converter := sqltocsv.New(querySizedRows(t))
+	converter.IncludeColumns = []string{"id", "name"}
+	converter.SchemaSidecar = true
+	if err := converter.WriteFile(name); err != nil {
+		t.Fatalf("error in WriteFile: %v", err)
+	}
+
+	contents, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("error reading %v: %v", name, err)
+	}
+	assertCsvMatch(t, "id,name\n1,Alice\n", string(contents))
+
+	schemaFile := sqltocsv.SchemaFileName(name)
+	if schemaFile != filepath.Join(dir, "export.schema.json") {
+		t.Errorf("unexpected schema file name %q", schemaFile)
+	}
+	data, err := os.ReadFile(schemaFile)
+	if err != nil {
+		t.Fatalf("error reading %v: %v", schemaFile, err)
+	}
+	var schema struct {
+		Columns []map[string]any `json:"columns"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("error decoding %s: %v", data, err)
+	}
+	expected := []map[string]any{
+		{"position": 1.0, "name": "id", "column": "id", "database_type": "BIGINT", "nullable": false},
+		{"position": 2.0, "name": "name", "column": "name", "database_type": "VARCHAR", "nullable": true, "length": 255.0},
+	}
+	if !reflect.DeepEqual(schema.Columns, expected) {
+		t.Errorf("expected schema %v, got %v", expected, schema.Columns)
+	}
+}
+
+func TestWriteSchemaFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "people.schema.json")
+	converter := getConverter(t)
+	if err := converter.WriteSchemaFile(name); err != nil {
+		t.Fatalf("error in WriteSchemaFile: %v", err)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("error reading %v: %v", name, err)
+	}
+	if !strings.Contains(string(data), `"name": "bdate"`) {
+		t.Errorf("expected the bdate column in the schema, got %s", data)
+	}
+	assertCsvMatch(t, "name,age,bdate\nAlice,1,1973-11-29T21:33:09Z\n", converter.String())
+}
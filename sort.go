@@ -0,0 +1,165 @@
+package sqltocsv
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// defaultMaxSortRows is how many rows SortBy holds in memory if MaxSortRows
+// isn't set.
+const defaultMaxSortRows = 1000000
+
+// sortKey is a column added by SortBy.
+type sortKey struct {
+	column     string
+	descending bool
+	numeric    bool
+}
+
+// SortBy has the rows written in order of the named query column, which
+// must be one of the output columns, by comparing the converted fields as
+// strings or, with numeric set, as numbers. Fields that aren't numbers (NULL
+// included) go after all of the numbers in either direction. Calling it
+// again adds another column, which orders rows the earlier ones find equal;
+// rows equal in every column keep their order in the result set.
+//
+// Sorting means holding every row in memory until the last one has been
+// read, so Write fails once there are more than MaxSortRows of them. Rows
+// are numbered for RowNumberHeader and counted against MaxRows once sorted,
+// but SkipDuplicateRows compares them as read.
+func (c *Converter) SortBy(column string, descending, numeric bool) {
+	c.sortKeys = append(c.sortKeys, sortKey{column: column, descending: descending, numeric: numeric})
+}
+
+// rowSorter holds the rows of an export using SortBy until they can be
+// sorted and written.
+type rowSorter struct {
+	keys    []sortKey
+	indexes []int // of the keys' fields in each row
+	limit   int
+	rows    []sortedRow
+}
+
+// sortedRow is a row waiting to be sorted, with its sort keys worked out.
+type sortedRow struct {
+	fields []string
+	values []any
+	keys   []sortValue
+}
+
+// sortValue is a field of a sort key column.
+type sortValue struct {
+	text     string
+	number   float64
+	isNumber bool
+}
+
+// newRowSorter returns a rowSorter for the Converter's SortBy columns, or nil
+// if there aren't any.
+func (c Converter) newRowSorter(p *plan) (*rowSorter, error) {
+	if len(c.sortKeys) == 0 {
+		return nil, nil
+	}
+
+	s := &rowSorter{keys: c.sortKeys, limit: c.MaxSortRows}
+	if s.limit <= 0 {
+		s.limit = defaultMaxSortRows
+	}
+	for _, key := range c.sortKeys {
+		i := slices.Index(p.columnNames, key.column)
+		if i < 0 {
+			return nil, fmt.Errorf("sort column %q is not in the output", key.column)
+		}
+		s.indexes = append(s.indexes, len(p.before)+i)
+	}
+	return s, nil
+}
+
+// add copies a row into the sorter. rowNumber is its position in the result
+// set, for the error if there are too many rows.
+func (s *rowSorter) add(fields []string, values []any, rowNumber int) error {
+	if len(s.rows) == s.limit {
+		return fmt.Errorf("row %d is more than the %d rows MaxSortRows allows to be sorted", rowNumber, s.limit)
+	}
+
+	row := sortedRow{fields: slices.Clone(fields), values: slices.Clone(values), keys: make([]sortValue, len(s.keys))}
+	for k, key := range s.keys {
+		var text string
+		if s.indexes[k] < len(fields) {
+			text = fields[s.indexes[k]]
+		}
+		row.keys[k].text = text
+		if key.numeric {
+			number, err := strconv.ParseFloat(text, 64)
+			row.keys[k].number, row.keys[k].isNumber = number, err == nil
+		}
+	}
+	s.rows = append(s.rows, row)
+	return nil
+}
+
+// sort puts the rows in order.
+func (s *rowSorter) sort() {
+	slices.SortStableFunc(s.rows, func(a, b sortedRow) int {
+		for k, key := range s.keys {
+			if n := key.compare(a.keys[k], b.keys[k]); n != 0 {
+				return n
+			}
+		}
+		return 0
+	})
+}
+
+// writeSorted sorts the rows and passes them to write, numbering them for
+// RowNumberHeader now they're in order. It reports whether write stopped
+// before the last of them.
+func (c Converter) writeSorted(s *rowSorter, write func(row []string, values []any) (bool, error)) (bool, error) {
+	s.sort()
+	for i, row := range s.rows {
+		if c.RowNumberHeader != "" {
+			pos := 0
+			if c.RowNumberLast {
+				pos = len(row.fields) - 1
+			}
+			number := int64(i + 1)
+			row.fields[pos] = strconv.FormatInt(number, 10)
+			if row.values != nil {
+				row.values[pos] = number
+			}
+		}
+
+		done, err := write(row.fields, row.values)
+		if err != nil {
+			return false, err
+		}
+		if done {
+			return i+1 < len(s.rows), nil
+		}
+	}
+	return false, nil
+}
+
+// compare orders two fields of the key's column.
+func (key sortKey) compare(a, b sortValue) int {
+	var n int
+	switch {
+	case !key.numeric:
+		n = cmp.Compare(a.text, b.text)
+	case a.isNumber && b.isNumber:
+		n = cmp.Compare(a.number, b.number)
+	case a.isNumber != b.isNumber:
+		// numbers first, whichever the direction
+		if a.isNumber {
+			return -1
+		}
+		return 1
+	default:
+		n = cmp.Compare(a.text, b.text)
+	}
+	if key.descending {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,109 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func getSortConverter(t *testing.T) *sqltocsv.Converter {
+	db := setupDatabase(t)
+	for i, person := range []struct {
+		name string
+		age  int
+	}{{"Carol", 10}, {"Bob", 9}, {"Dave", 10}, {"Erin", 100}, {"Bob", 2}} {
+		exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", person.name, person.age, time.Unix(int64(i), 0), nil)
+	}
+	return sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age|"))
+}
+
+func TestSortBy(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		setup    func(*sqltocsv.Converter)
+		expected string
+	}{
+		{"strings", func(c *sqltocsv.Converter) {
+			c.SortBy("age", false, false)
+		}, "name,age\nAlice,1\nCarol,10\nDave,10\nErin,100\nBob,2\nBob,9\n"},
+		{"numbers", func(c *sqltocsv.Converter) {
+			c.SortBy("age", false, true)
+		}, "name,age\nAlice,1\nBob,2\nBob,9\nCarol,10\nDave,10\nErin,100\n"},
+		{"descending and stable", func(c *sqltocsv.Converter) {
+			c.SortBy("age", true, true)
+		}, "name,age\nErin,100\nCarol,10\nDave,10\nBob,9\nBob,2\nAlice,1\n"},
+		{"multiple keys", func(c *sqltocsv.Converter) {
+			c.SortBy("name", false, false)
+			c.SortBy("age", true, true)
+		}, "name,age\nAlice,1\nBob,9\nBob,2\nCarol,10\nDave,10\nErin,100\n"},
+		{"row numbers and max rows", func(c *sqltocsv.Converter) {
+			c.SortBy("age", true, true)
+			c.RowNumberHeader = "#"
+			c.MaxRows = 2
+		}, "#,name,age\n1,Erin,100\n2,Carol,10\n"},
+	} {
+		converter := getSortConverter(t)
+		test.setup(converter)
+
+		actual, err := converter.WriteString()
+		if err != nil {
+			t.Fatalf("%s: error in WriteString: %v", test.name, err)
+		}
+		if actual != test.expected {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestSortByNonNumbers(t *testing.T) {
+	converter := getSortConverter(t)
+	converter.SetRowPreProcessor(func(row []string, _ []string) (bool, []string) {
+		if row[0] == "Bob" {
+			row[1] = "n/a"
+		}
+		return true, row
+	})
+	converter.SortBy("age", true, true)
+	converter.SortBy("name", false, false)
+
+	expected := "name,age\nErin,100\nCarol,10\nDave,10\nAlice,1\nBob,n/a\nBob,n/a\n"
+	assertCsvMatch(t, expected, converter.String())
+}
+
+func TestSortByTruncated(t *testing.T) {
+	converter := getSortConverter(t)
+	converter.SortBy("name", false, false)
+	converter.MaxRows = 5
+	converter.TruncateError = true
+
+	buffer := &bytes.Buffer{}
+	if err := converter.Write(buffer); !errors.Is(err, sqltocsv.ErrTruncated) {
+		t.Errorf("expected ErrTruncated, got %v", err)
+	}
+	if !strings.HasSuffix(buffer.String(), "Dave,10\n") {
+		t.Errorf("expected the first five sorted rows, got:\n%s", buffer.String())
+	}
+}
+
+func TestSortByErrors(t *testing.T) {
+	converter := getSortConverter(t)
+	converter.SortBy("bdate", false, false)
+	if _, err := converter.WriteString(); err == nil {
+		t.Error("expected error for a sort column that isn't in the output")
+	}
+
+	converter = getSortConverter(t)
+	converter.SortBy("name", false, false)
+	converter.MaxSortRows = 5
+	actual, err := converter.WriteString()
+	if err == nil || !strings.Contains(err.Error(), "MaxSortRows") {
+		t.Errorf("expected error for too many rows to sort, got %v", err)
+	}
+	if actual != "name,age\n" {
+		t.Errorf("expected no rows to be written, got:\n%s", actual)
+	}
+}
@@ -0,0 +1,136 @@
+package sqltocsv
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// IdentifierQuoting is how WriteSQLInserts quotes table and column names.
+type IdentifierQuoting int
+
+const (
+	// Standard SQL "double quotes", as used by PostgreSQL, SQLite and Oracle.
+	DoubleQuoteIdentifiers IdentifierQuoting = iota
+	// MySQL style `backticks`.
+	BacktickIdentifiers
+	// SQL Server style [brackets].
+	BracketIdentifiers
+)
+
+// WriteSQLInserts writes the rows to the Writer provided as INSERT statements
+// into tableName, with the headers as the column names, for moving them to
+// another database. Values go through the same conversion and preprocessor as
+// Write. NULLs are written as NULL, numbers and booleans that reach the
+// output unchanged as they are, and everything else (including times, in
+// TimeFormat) as a string literal with single quotes doubled. Backslashes
+// are left alone, so MySQL needs NO_BACKSLASH_ESCAPES to read them back.
+//
+// Each statement inserts InsertBatchSize rows, or one if it isn't set. Names
+// are quoted as set by IdentifierQuoting, and a tableName containing dots is
+// quoted part by part, so "public.people" becomes "public"."people".
+func (c Converter) WriteSQLInserts(writer io.Writer, tableName string) (err error) {
+	defer c.closeRows(&err)
+
+	p, err := c.newPlan()
+	if err != nil {
+		return err
+	}
+	p.keepValues = true
+
+	names := strings.Split(tableName, ".")
+	for i, name := range names {
+		names[i] = c.quoteIdentifier(name)
+	}
+	columns := make([]string, len(p.headers))
+	for i, header := range p.headers {
+		columns[i] = c.quoteIdentifier(header)
+	}
+	insert := "INSERT INTO " + strings.Join(names, ".") + " (" + strings.Join(columns, ", ") + ") VALUES"
+
+	batchSize := c.InsertBatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	bufWriter := bufio.NewWriter(writer)
+	rowNumber, inBatch := 0, 0
+	err = c.eachRow(context.Background(), p, func(row []string, values []any) error {
+		rowNumber++
+		if len(row) != len(columns) {
+			return fmt.Errorf("row %d has %d fields but there are %d headers", rowNumber, len(row), len(columns))
+		}
+
+		switch {
+		case inBatch == 0 && batchSize == 1:
+			bufWriter.WriteString(insert + " (")
+		case inBatch == 0:
+			bufWriter.WriteString(insert + "\n(")
+		default:
+			bufWriter.WriteString(",\n(")
+		}
+		for i, field := range row {
+			if i > 0 {
+				bufWriter.WriteString(", ")
+			}
+			bufWriter.WriteString(sqlLiteral(values[i], field))
+		}
+		bufWriter.WriteByte(')')
+
+		inBatch++
+		if inBatch == batchSize {
+			bufWriter.WriteString(";\n")
+			inBatch = 0
+		}
+		return nil
+	})
+	if err == nil && inBatch > 0 {
+		bufWriter.WriteString(";\n")
+	}
+
+	if flushErr := bufWriter.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// quoteIdentifier quotes a table or column name as set by IdentifierQuoting,
+// doubling any closing quote character inside it.
+func (c Converter) quoteIdentifier(name string) string {
+	switch c.IdentifierQuoting {
+	case BacktickIdentifiers:
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	case BracketIdentifiers:
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sqlLiteral picks how to write a field in a VALUES list: NULL for NULLs,
+// the field as it is for booleans and finite numbers, unless it isn't a plain
+// number, and a quoted string for anything else.
+func sqlLiteral(value any, field string) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return plainNumber(value, field)
+	case float32:
+		if !math.IsInf(float64(v), 0) && !math.IsNaN(float64(v)) {
+			return plainNumber(value, field)
+		}
+	case float64:
+		if !math.IsInf(v, 0) && !math.IsNaN(v) {
+			return plainNumber(value, field)
+		}
+	}
+	return "'" + strings.ReplaceAll(field, "'", "''") + "'"
+}
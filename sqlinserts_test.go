@@ -0,0 +1,108 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestWriteSQLInserts(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "O'Brien", 2, time.Unix(0, 0), "ob")
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age,bdate,nickname|"))
+	converter.TimeFormat = time.DateTime
+
+	var out bytes.Buffer
+	if err := converter.WriteSQLInserts(&out, "public.people"); err != nil {
+		t.Fatalf("error in WriteSQLInserts: %v", err)
+	}
+
+	expected := `INSERT INTO "public"."people" ("name", "age", "bdate", "nickname") VALUES ('Alice', 1, '1973-11-29 21:33:09', NULL);` + "\n" +
+		`INSERT INTO "public"."people" ("name", "age", "bdate", "nickname") VALUES ('O''Brien', 2, '1970-01-01 00:00:00', 'ob');` + "\n"
+	assertCsvMatch(t, expected, out.String())
+}
+
+func TestWriteSQLInsertsBatches(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "CREATE|flags|id=int32,on=bool,ratio=float64")
+	for i := 1; i <= 3; i++ {
+		exec(t, db, "INSERT|flags|id=?,on=?,ratio=?", i, i%2 == 0, float64(i)/4)
+	}
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|flags|id,on,ratio|"))
+	converter.InsertBatchSize = 2
+	converter.IdentifierQuoting = sqltocsv.BacktickIdentifiers
+	converter.Headers = []string{"id", "is`on", "ratio"}
+
+	var out bytes.Buffer
+	if err := converter.WriteSQLInserts(&out, "flags"); err != nil {
+		t.Fatalf("error in WriteSQLInserts: %v", err)
+	}
+
+	expected := "INSERT INTO `flags` (`id`, `is``on`, `ratio`) VALUES\n(1, FALSE, 0.25),\n(2, TRUE, 0.5);\n" +
+		"INSERT INTO `flags` (`id`, `is``on`, `ratio`) VALUES\n(3, FALSE, 0.75);\n"
+	assertCsvMatch(t, expected, out.String())
+}
+
+func TestWriteSQLInsertsPreProcessor(t *testing.T) {
+	converter := getConverter(t)
+	converter.IdentifierQuoting = sqltocsv.BracketIdentifiers
+	converter.SetRowPreProcessor(func(row []string, _ []string) (bool, []string) {
+		row[1] = "ten"
+		return true, row
+	})
+
+	var out bytes.Buffer
+	if err := converter.WriteSQLInserts(&out, "people"); err != nil {
+		t.Fatalf("error in WriteSQLInserts: %v", err)
+	}
+
+	// a number changed by the preprocessor is just a string
+	expected := "INSERT INTO [people] ([name], [age], [bdate]) VALUES ('Alice', 'ten', '1973-11-29T21:33:09Z');\n"
+	assertCsvMatch(t, expected, out.String())
+}
+
+func TestWriteSQLInsertsNoRows(t *testing.T) {
+	var out bytes.Buffer
+	if err := sqltocsv.New(getEmptyTestRows(t)).WriteSQLInserts(&out, "people"); err != nil {
+		t.Fatalf("error in WriteSQLInserts: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no output, got %q", out.String())
+	}
+}
+
+func TestWriteSQLInsertsNumberFormats(t *testing.T) {
+	converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"id", "amt"}, rows: [][]any{{int64(1234), 3.5}}})
+	converter.NumberFormats = map[string]sqltocsv.NumberFormat{
+		"id":  {GroupSeparator: ","},
+		"amt": {GroupSeparator: ",", DecimalSeparator: ",", Decimals: 2},
+	}
+
+	var out bytes.Buffer
+	if err := converter.WriteSQLInserts(&out, "t"); err != nil {
+		t.Fatalf("error in WriteSQLInserts: %v", err)
+	}
+
+	// the separators would split the numbers in two
+	expected := `INSERT INTO "t" ("id", "amt") VALUES (1234, 3.5);` + "\n"
+	assertCsvMatch(t, expected, out.String())
+}
+
+func TestWriteSQLInsertsDecimalSeparator(t *testing.T) {
+	converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"id", "amt", "ratio"}, rows: [][]any{{int64(1), 3.5, float32(0.25)}}})
+	converter.Delimiter = ';'
+	converter.DecimalSeparator = ','
+	converter.FloatFormats = map[string]string{"ratio": "%.3f"}
+
+	var out bytes.Buffer
+	if err := converter.WriteSQLInserts(&out, "t"); err != nil {
+		t.Fatalf("error in WriteSQLInserts: %v", err)
+	}
+
+	expected := `INSERT INTO "t" ("id", "amt", "ratio") VALUES (1, 3.5, 0.25);` + "\n"
+	assertCsvMatch(t, expected, out.String())
+}
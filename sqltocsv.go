@@ -1,41 +1,65 @@
 // sqltocsv is a package to make it dead easy to turn arbitrary database query
-// results (in the form of database/sql Rows) into CSV output.
+// results (in the form of database/sql Rows, or any other RowSource) into CSV
+// output.
 //
 // Source and README at https://github.com/joho/sqltocsv
 package sqltocsv
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/base64"
 	"encoding/csv"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"net/http"
 	"os"
+	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
 )
 
 // WriteFile will write a CSV file to the file name specified (with headers)
 // based on whatever is in the sql.Rows you pass in. It calls WriteCsvToWriter under
 // the hood.
 func WriteFile(csvFileName string, rows *sql.Rows) error {
-	return New(rows).WriteFile(csvFileName)
+	return newClosing(rows).WriteFile(csvFileName)
+}
+
+// AppendFile will append CSV rows to the file name specified, creating it
+// (with headers) if it doesn't exist. See Converter.AppendFile.
+func AppendFile(csvFileName string, rows *sql.Rows) error {
+	return newClosing(rows).AppendFile(csvFileName)
 }
 
 // WriteString will return a string of the CSV. Don't use this unless you've
 // got a small data set or a lot of memory
 func WriteString(rows *sql.Rows) (string, error) {
-	return New(rows).WriteString()
+	return newClosing(rows).WriteString()
 }
 
 // Write will write a CSV file to the writer passed in (with headers)
 // based on whatever is in the sql.Rows you pass in.
 func Write(writer io.Writer, rows *sql.Rows) error {
-	return New(rows).Write(writer)
+	return newClosing(rows).Write(writer)
+}
+
+// WriteContext is like Write but stops early with the context's error
+// (wrapped) if ctx is cancelled or times out during the export.
+func WriteContext(ctx context.Context, writer io.Writer, rows *sql.Rows) error {
+	return newClosing(rows).WriteContext(ctx, writer)
 }
 
 // CsvPreprocessorFunc is a function type for preprocessing your CSV.
@@ -46,6 +70,34 @@ func Write(writer io.Writer, rows *sql.Rows) error {
 // return the processed Row slice as you want it written to the CSV.
 type CsvPreProcessorFunc func(row []string, columnNames []string) (outputRow bool, processedRow []string)
 
+// HeaderTransformFunc turns a query column name into the header written
+// for it.
+type HeaderTransformFunc func(columnName string) string
+
+// ProgressFunc is called with the number of data rows written so far.
+type ProgressFunc func(rowsWritten int64)
+
+// RawPreProcessorFunc is a function type for preprocessing rows before any
+// conversion to strings. It gets the scanned values of the output columns.
+//
+// Return an outputRow of false if you want the row skipped otherwise return
+// the values (one per column) to be converted and written.
+type RawPreProcessorFunc func(values []any, columnNames []string) (outputRow bool, processedValues []any)
+
+// ColumnFormatterFunc converts the raw scanned value of a single column
+// into the string written to the CSV. Returning an error aborts the export.
+type ColumnFormatterFunc func(value any) (string, error)
+
+// ComputedColumnFunc works out the value of a computed column from a row's
+// values, converted to strings and keyed by query column name. Returning an
+// error aborts the export.
+type ComputedColumnFunc func(values map[string]string) (string, error)
+
+// ValueConverterFunc converts the raw scanned value of the named column into
+// the string written to the CSV. Return handled as false to fall back to the
+// built-in conversion, or an error to abort the export.
+type ValueConverterFunc func(columnName string, value any) (text string, handled bool, err error)
+
 // BinaryConverter allows you to specify the algorithm for converting binary data into a string.
 type BinaryConverter int
 
@@ -66,29 +118,381 @@ const (
 	RawURLBase64
 	// Hexadecimal encoding of []byte.
 	Hex
+	// Canonical xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx form of 16 byte UUIDs.
+	// Values of any other length, such as a UUID the driver already
+	// returns as text, are converted with string([]byte). Set it in
+	// BinaryConverters for just the UUID columns to leave other 16 byte
+	// values alone.
+	UUIDCanonical
 )
 
+// utf8BOM is written at the start of the output when Converter.WriteBOM is set.
+const utf8BOM = "\xEF\xBB\xBF"
+
 // Converter does the actual work of converting the rows to CSV.
 // There are a few settings you can override if you want to do
 // some fancy stuff to your CSV.
 type Converter struct {
-	Headers         []string        // Column headers to use (default is rows.Columns())
-	WriteHeaders    bool            // Flag to output headers in your CSV (default is true)
-	TimeFormat      string          // Format string for any time.Time values (default is time's default)
-	FloatFormat     string          // Format string for any float64 and float32 values (default is %v)
-	Delimiter       rune            // Delimiter to use in your CSV (default is comma)
-	BinaryConverter BinaryConverter // How to convert []byte. By default string([]byte{})
+	Headers          []string        // Column headers to use (default is rows.Columns())
+	StrictHeaderMap  bool            // Flag to return an error for HeaderMap columns that aren't in the result set (default is false, they're ignored)
+	HeaderCase       HeaderCase      // How to case the column names in the header row when Headers isn't set, before any SetHeaderTransform (default is HeaderAsIs)
+	WriteHeaders     bool            // Flag to output headers in your CSV (default is true)
+	TimeFormat       string          // Format string for any time.Time values, or EpochSeconds, EpochMillis or EpochMicros (default is time's default)
+	FloatFormat      string          // Format string for any float64 and float32 values (default is %v)
+	DecimalColumns   []string        // Query columns holding numbers as text, such as DECIMAL columns scanned as []byte, whose decimal point DecimalSeparator replaces too (default is none)
+	Delimiter        rune            // Delimiter to use in your CSV, which Validate checks can be (default is comma, set by New)
+	BinaryConverter  BinaryConverter // How to convert []byte. By default string([]byte{})
+	WriteBOM         bool            // Flag to output a UTF-8 byte order mark before anything else (default is false)
+	PrologueLines    []string        // Lines written as they are before the header row, such as "# source: billing", which can't contain line breaks (default is none)
+	WriteSepHint     bool            // Flag to start with a sep= line giving Excel the delimiter, in place of the WriteBOM byte order mark as Excel ignores it after the line (default is false)
+	UseCRLF          bool            // Flag to terminate lines with \r\n instead of \n (default is false)
+	NullString       string          // String to output for NULL values (default is "")
+	IncludeColumns   []string        // Only output these columns, by query column name (default is all)
+	ExcludeColumns   []string        // Output every column except these, by query column name (default is none)
+	ColumnOrder      []string        // Output columns in this order, by query column name (default is query order)
+	DropUnordered    bool            // Flag to drop columns missing from ColumnOrder instead of appending them (default is false)
+	GzipLevel        int             // Compression level for WriteGzipFile (default is gzip.DefaultCompression, see GzipUncompressed for none)
+	ZstdLevel        int             // Compression level for WriteZstdFile, from 1 to 22 (default is 3)
+	ZipLevel         int             // Deflate level for WriteZipFile, from -2 to 9 as in compress/flate (default is flate.DefaultCompression, see ZipStore for none)
+	ZipStore         bool            // Flag for WriteZipFile to store the CSV uncompressed rather than deflate it (default is false)
+	WriteEmptyChunk  bool            // Flag for WriteFileChunks to create a header-only file when there are no rows (default is false)
+	JSONStringsOnly  bool            // Flag for WriteJSONLines to output every non-NULL value as a JSON string (default is false)
+	AllowRaggedRows  bool            // Flag to allow rows with a different number of fields to the headers (default is false)
+	DurationFormat   DurationFormat  // How to format time.Duration values (default is DurationString, such as 1h23m45s)
+	TimeLocation     *time.Location  // Location to convert time.Time values to before formatting (default is to leave them as is)
+	TrueString       string          // String to output for true bool values (default is "true")
+	FalseString      string          // String to output for false bool values (default is "false")
+	QuoteAll         bool            // Flag to quote every field, not just those that need it (default is false)
+	EscapeMode       bool            // Flag to escape the delimiter, line breaks, NUL and the escape character itself with EscapeChar instead of quoting fields, as MySQL's LOAD DATA expects (default is false)
+	EscapeChar       rune            // Escape character for EscapeMode (default is \)
+	QuoteChar        rune            // Character to quote fields with, doubled inside them (default is ")
+	UnquotedOutput   bool            // Flag to write fields as they are, never quoted, handling values that would need it by UnquotedStrategy (default is false)
+	TrimSpace        bool            // Flag to trim leading and trailing white space from text values (default is false)
+	TrimColumns      []string        // Query columns to trim as TrimSpace does, when it isn't set for every column (default is none)
+	JSONColumns      []string        // Query columns holding JSON text, written as it is whatever BinaryConverter or TrimSpace say, see CompactJSON (default is none)
+	CompactJSON      bool            // Flag to strip the insignificant white space from valid JSON in JSONColumns (default is false)
+	StrictJSON       bool            // Flag to fail the row when a value in JSONColumns isn't valid JSON, rather than write it as it is (default is false)
+	MaxFieldLength   int             // Longest a data field can be in runes, TruncateMarker included, before it's cut short (default is 0, no limit)
+	TruncateMarker   string          // Appended to fields cut short by MaxFieldLength, such as "…" (default is none)
+	RowNumberHeader  string          // Header of a column numbering the rows written from 1, before the others, starting again at 1 in each result set with AllResultSets (default is none, no such column)
+	RowNumberLast    bool            // Flag to put the RowNumberHeader column after the others instead (default is false)
+	StaticColumns    []StaticColumn  // Columns with a fixed value added to every row, after the others (default is none)
+	SkipEmptyRows    bool            // Flag to drop rows where every field is an empty string after preprocessing (default is false)
+	DedupAllRows     bool            // Flag to drop every row the same as any written before it, see SkipDuplicateRows, holding a 32 byte hash of every distinct row written in memory (default is false)
+	MaxRows          int64           // Stop after writing this many data rows, from each result set with AllResultSets (default is 0, no limit)
+	MaxSortRows      int             // Most rows SortBy will hold in memory to sort before Write fails (default is 1000000)
+	MaxErrors        int64           // Most rows the SetErrorHandler handler can skip before the export is aborted anyway (default is 0, no limit)
+	TruncateError    bool            // Flag to return ErrTruncated when MaxRows stops the export early (default is false)
+	SkipRows         int64           // Discard this many rows from the start of the result set, each of them with AllResultSets (default is 0)
+	ContinueOnError  bool            // Flag for WriteMulti to keep writing to the other writers when one fails (default is false)
+	BufferSize       int             // Size of the buffer Write puts in front of the writer, unless it's a *bufio.Writer (default is 0, csv's own buffering only)
+	FlushEvery       int             // Number of rows Write writes between flushes of the output, including http.Flusher (default is 0, flush only at the end)
+	FlushInterval    time.Duration   // Longest time Write lets rows sit in the buffer, checked as each row is written (default is 0, no time limit)
+	Pipelined        bool            // Flag to scan rows in their own goroutine while earlier rows are formatted and written (default is false)
+	UseColumnTypes   bool            // Flag to scan each column into a Go type picked from rows.ColumnTypes() instead of any (default is false)
+	HTMLClass        string          // CSS class for the table written by WriteHTML (default is none)
+	HTMLNull         string          // HTML written as is by WriteHTML for NULL values, such as "&nbsp;" or "&mdash;" (default is NullString, escaped)
+	InsertBatchSize  int             // Number of rows in each INSERT statement written by WriteSQLInserts (default is 1)
+	SanitizeFormulas bool            // Flag to prefix text values that a spreadsheet would run as a formula, see FormulaPrefix (default is false)
+	FormulaPrefix    string          // Prefix SanitizeFormulas puts in front of those values (default is "'")
+	AppendHeaders    bool            // Flag for AppendFile to write the headers even when the file isn't empty (default is false)
+	FileMode         os.FileMode     // Permissions WriteFile and the other file writers create files with, before the umask (default is 0666, or 0600 with Atomic)
+	Sync             bool            // Flag for WriteFile and the other file writers to sync the file to disk before closing it (default is false, always done with Atomic)
+	ManifestFile     string          // Name of a manifest WriteFileChunks writes listing the files it created, see ManifestFormat (default is none)
+	ManifestFormat   ManifestFormat  // Format of the manifests written by WriteManifest (default is ManifestRedshift)
+	ManifestPrefix   string          // Put in place of the directory of each file in a manifest, such as "s3://bucket/exports/" (default is none, the names as they are)
+	CloseRows        bool            // Flag for the Write methods to close the rows once done, whether or not they fail, as the package's Write functions do (default is false)
+	EmptyMode        EmptyMode       // What Write and WriteFile do when there are no data rows to write (default is EmptyWriteHeader)
+	SchemaSidecar    bool            // Flag for WriteFile to also write the Schema to the SchemaFileName of the file, as WriteSchemaFile does (default is false)
+
+	// HeaderMap renames the headers of individual columns, keyed by the
+	// query column name, leaving the others as they are. It can't be used
+	// together with Headers.
+	HeaderMap map[string]string
+
+	// TimeFormats overrides TimeFormat for individual columns, keyed by the
+	// query column name. Names that don't match a column are ignored.
+	TimeFormats map[string]string
+
+	// FloatFormats overrides FloatFormat for individual columns, keyed by
+	// the query column name. Names that don't match a column are ignored.
+	FloatFormats map[string]string
+
+	// NumberFormats sets how the integer and float values of individual
+	// columns are written, with grouping separators and a fixed number of
+	// decimal places, keyed by the query column name, in place of
+	// FloatFormat. Names that don't match a column are ignored.
+	NumberFormats map[string]NumberFormat
+
+	// MaskColumns hides the values of individual columns, such as email
+	// addresses and phone numbers, keyed by the query column name, with
+	// MaskAll, MaskEmail, MaskLast4 or a MaskFunc of your own. Masking is the
+	// last thing done to a row before it's written, after the preprocessor
+	// and the RowValidatorFunc, so rows are deduplicated by what they held
+	// but sorted by SortBy as written. NULLs stay NULL. A quarantined row is
+	// written unmasked. The names of the columns masked are in
+	// Stats.MaskedColumns. Names that don't match a column are ignored.
+	MaskColumns map[string]MaskFunc
+
+	// HashColumns pseudonymizes the values of individual columns, such as
+	// user IDs, keyed by the query column name, replacing each with a token
+	// made by hashing it as its HashSpec says, so that the same value gets
+	// the same token in every export. It's done where MaskColumns are, and
+	// a column can't be in both. NULLs stay NULL. The names of the columns
+	// hashed are in Stats.HashedColumns. Names that don't match a column are
+	// ignored.
+	HashColumns map[string]HashSpec
+
+	// BinaryConverters overrides BinaryConverter for individual columns,
+	// keyed by the query column name. Names that don't match a column are
+	// ignored.
+	BinaryConverters map[string]BinaryConverter
+
+	// IdentifierQuoting is how WriteSQLInserts quotes table and column
+	// names. The default is standard SQL "double quotes".
+	IdentifierQuoting IdentifierQuoting
+
+	// ParquetRowGroupSize is the number of rows in each row group written
+	// by WriteParquetFile, which bounds how much of the result set is held
+	// in memory. The default is 100000.
+	ParquetRowGroupSize int
+
+	// MarkdownAlign sets the alignment of columns in WriteMarkdown, keyed by
+	// the query column name. Names that don't match a column are ignored.
+	MarkdownAlign map[string]Alignment
+
+	// NaNString, when set, is written for NaN float values in place of
+	// "NaN", whatever the FloatFormat, such as "" or `\N`.
+	NaNString *string
+
+	// InfString, when set, is written for +Inf float values in place of
+	// "+Inf", and with a minus in front of it for -Inf unless NegInfString
+	// is set too, whatever the FloatFormat.
+	InfString *string
 
-	rows            *sql.Rows
-	rowPreProcessor CsvPreProcessorFunc
+	// NegInfString, when set, is written for -Inf float values in place of
+	// "-Inf", or InfString with a minus in front of it.
+	NegInfString *string
+
+	// TabNewlineReplacement, when set, replaces every tab, carriage return
+	// and newline inside data values (a \r\n pair counts as one) so naive
+	// consumers can split on them. The header row is left untouched.
+	TabNewlineReplacement *string
+
+	// NewlineReplacement, when set, replaces every carriage return and
+	// newline inside data values (a \r\n pair counts as one), such as with
+	// " " or `\n`, to keep each record on one line. It's applied after the
+	// preprocessor and overrides TabNewlineReplacement for line breaks. The
+	// header row is left untouched.
+	NewlineReplacement *string
+
+	// Encoding transcodes everything Write writes, headers included, from
+	// UTF-8 into another character set such as charmap.Windows1251 from
+	// golang.org/x/text/encoding. Runes it can't represent fail the export
+	// unless ReplaceUnmappable is set. A WriteBOM byte order mark is
+	// encoded too, so only combine the two with Unicode encodings such as
+	// UTF16LE. The default is to write UTF-8.
+	Encoding encoding.Encoding
+
+	// ReplaceUnmappable writes a '?' in place of each rune Encoding can't
+	// represent, rather than failing.
+	ReplaceUnmappable bool
+
+	// UnquotedStrategy is what UnquotedOutput does with values containing
+	// the delimiter or a line break. It has to be set for UnquotedOutput
+	// to run at all.
+	UnquotedStrategy UnquotedStrategy
+
+	// UnquotedReplacement is what UnquotedReplace puts in place of each
+	// delimiter and line break. It can't contain either itself.
+	UnquotedReplacement string
+
+	// StaticColumnsFirst puts the StaticColumns before the query's columns
+	// rather than after them. A RowNumberHeader column stays outermost.
+	StaticColumnsFirst bool
+
+	// ComputedColumnsFirst puts the columns added by AddComputedColumn
+	// before the query's columns rather than after them, next to them
+	// whatever the StaticColumns and RowNumberHeader columns are.
+	ComputedColumnsFirst bool
+
+	// SkipDuplicateRows drops rows whose fields are the same as those of
+	// the row written just before them, as they are once the preprocessor
+	// and options like NewlineReplacement have been applied but leaving out
+	// any extra columns like RowNumberHeader's. Only the one row is kept.
+	SkipDuplicateRows bool
+
+	// PreserveNullVsEmpty quotes empty strings, and any other value that's
+	// the same as NullString, while leaving NULLs unquoted, so that a reader
+	// like PostgreSQL's COPY ... CSV can tell the two apart. QuoteAll still
+	// leaves NULLs unquoted with it set.
+	PreserveNullVsEmpty bool
+
+	// WriteRowCountFooter writes a last record of __rowcount__ and the
+	// number of data rows written, after any SetFooter record.
+	WriteRowCountFooter bool
+
+	// DecimalSeparator is written in place of the decimal point of float
+	// values, such as ',' for Excel in comma-decimal locales. Validate checks
+	// it isn't part of the delimiter unless QuoteAll is set. WriteSQLInserts
+	// and WriteXlsxFile still write numbers with a point. The default is '.'.
+	DecimalSeparator rune
+
+	// GzipUncompressed makes WriteGzipFile write the gzip stream without
+	// compressing it. It's needed because a GzipLevel of gzip.NoCompression
+	// is 0 and so means the default level.
+	GzipUncompressed bool
+
+	// DelimiterString is a delimiter of any length, such as "||", used in
+	// place of Delimiter. The quarantine still uses Delimiter.
+	DelimiterString string
+
+	// Atomic makes WriteFile and the other file writers, including each
+	// file of WriteFileChunks, write to a temporary file and rename it into
+	// place once it's complete. AppendFile ignores it.
+	Atomic bool
+
+	// AllResultSets makes Write and the other CSV writers go on to each
+	// further result set from rows.NextResultSet. Each set is written as it
+	// would be on its own, so MaxRows, SkipRows and RowNumberHeader apply
+	// per set. See ResultSetStats. The default is the first set only.
+	AllResultSets bool
+
+	rows               RowSource
+	ownsRows           bool
+	rowPreProcessor    CsvPreProcessorFunc
+	rawRowPreProcessor RawPreProcessorFunc
+	columnFormatters   map[string]ColumnFormatterFunc
+	valueConverter     ValueConverterFunc
+	computedColumns    []computedColumn
+	sortKeys           []sortKey
+	progressEvery      int
+	progress           ProgressFunc
+	headerTransform    HeaderTransformFunc
+	footer             FooterFunc
+	errorHandler       ErrorHandlerFunc
+	rowValidator       RowValidatorFunc
+	quarantineWriter   io.Writer
+	checksum           hash.Hash
+	last               *lastRun
+	isJSON             bool          // set by forColumn for JSONColumns
+	isDecimal          bool          // set by forColumn for DecimalColumns
+	numberFormat       *NumberFormat // set by forColumn for NumberFormats
+	copyText           bool          // set by WriteCopyText
+	dialect            Dialect       // set by ApplyDialect
+}
+
+// lastRun records what happened during the most recent export so it can
+// still be inspected after a Write method (with its value receiver) returns.
+type lastRun struct {
+	stats      Stats
+	truncated  bool
+	checksum   []byte
+	resultSets []Stats
+	chunks     []ChunkInfo
+	consumed   bool  // the rows of the current result set have been read, see ErrRowsConsumed
+	err        error // for LastError
 }
 
+// ErrTruncated is returned when MaxRows stopped an export before the end of
+// the rows and Converter.TruncateError is set.
+var ErrTruncated = errors.New("output truncated at MaxRows")
+
 // SetRowPreProcessor lets you specify a CsvPreprocessorFunc for this conversion
 func (c *Converter) SetRowPreProcessor(processor CsvPreProcessorFunc) {
 	c.rowPreProcessor = processor
 }
 
-// String returns the CSV as a string in an fmt package friendly way
+// SetRawRowPreProcessor lets you specify a RawPreProcessorFunc for this
+// conversion. It runs straight after each row is scanned, before the values
+// are converted to strings and passed to any CsvPreProcessorFunc.
+func (c *Converter) SetRawRowPreProcessor(processor RawPreProcessorFunc) {
+	c.rawRowPreProcessor = processor
+}
+
+// SetColumnFormatter lets you specify a ColumnFormatterFunc used instead of
+// the built-in conversion for the named column. Names that don't match any
+// column of the result set are ignored.
+func (c *Converter) SetColumnFormatter(columnName string, formatter ColumnFormatterFunc) {
+	if c.columnFormatters == nil {
+		c.columnFormatters = make(map[string]ColumnFormatterFunc)
+	}
+	c.columnFormatters[columnName] = formatter
+}
+
+// SetValueConverter lets you specify a ValueConverterFunc that is tried on
+// every value before the built-in conversion, for types the built-ins don't
+// know about. Columns with a ColumnFormatterFunc don't use it.
+func (c *Converter) SetValueConverter(converter ValueConverterFunc) {
+	c.valueConverter = converter
+}
+
+// AddComputedColumn adds a column with the given header whose value is
+// worked out by fn from the other values of each row, such as a full_name
+// made from first_name and last_name. fn gets every query column, including
+// those IncludeColumns or ExcludeColumns leave out, converted as they would
+// be written but before the preprocessor runs; the map is reused for the
+// next row once fn returns. Computed columns come after the query's columns
+// (before them with ComputedColumnsFirst) in the order they were added, and
+// are only computed for rows the preprocessor keeps.
+func (c *Converter) AddComputedColumn(header string, fn ComputedColumnFunc) {
+	c.computedColumns = append(c.computedColumns, computedColumn{header: header, fn: fn})
+}
+
+// SetHeaderTransform lets you specify a HeaderTransformFunc applied to each
+// column name to make the header row. It only affects the header row, and
+// is ignored when Headers is set and for columns renamed by HeaderMap.
+func (c *Converter) SetHeaderTransform(transform HeaderTransformFunc) {
+	c.headerTransform = transform
+}
+
+// SetProgressCallback lets you specify a ProgressFunc that is called after
+// every `every` rows written and once more when the export completes. Rows
+// skipped by the preprocessor don't count. If every is zero or less only the
+// final call is made. The callback runs synchronously in the goroutine doing
+// the export, so a slow callback slows down the export.
+func (c *Converter) SetProgressCallback(every int, fn ProgressFunc) {
+	c.progressEvery = every
+	c.progress = fn
+}
+
+// SetChecksum lets you specify a hash.Hash that every byte written by Write
+// (including any BOM and the header row) is passed through. The hash is
+// reset at the start of each export; read the result with Checksum.
+func (c *Converter) SetChecksum(h hash.Hash) {
+	c.checksum = h
+}
+
+// Checksum returns the digest of the output of the most recent export, or
+// nil if no hash was set with SetChecksum
+func (c Converter) Checksum() []byte {
+	if c.last == nil {
+		return nil
+	}
+	return c.last.checksum
+}
+
+// RowsWritten returns the number of data rows written by the most recent
+// export, not counting the header or rows skipped by the preprocessor
+func (c Converter) RowsWritten() int64 {
+	if c.last == nil {
+		return 0
+	}
+	return c.last.stats.RowsWritten
+}
+
+// Truncated reports whether the most recent export stopped at MaxRows
+// while there were still rows left to read, in any of the result sets with
+// AllResultSets
+func (c Converter) Truncated() bool {
+	if c.last == nil {
+		return false
+	}
+	return c.last.truncated
+}
+
+// String returns the CSV as a string in an fmt package friendly way. It
+// returns "" if the export fails, see LastError for why.
 func (c Converter) String() string {
 	csv, err := c.WriteString()
 	if err != nil {
@@ -104,106 +508,942 @@ func (c Converter) WriteString() (string, error) {
 	return buffer.String(), err
 }
 
+// Preview returns the CSV as WriteString would, but with at most n data rows,
+// to show what the export will look like. It stops reading once it has
+// them, peeking at one more row so that Truncated afterwards reports
+// whether there were any left. Only the first result set is previewed.
+// Like any export it reads the rows, so a later export from the Converter
+// returns ErrRowsConsumed; to export the rows in full, run the query again
+// and Reset the Converter. Unless they came from NewFromQuery, closing the
+// rows is still up to the caller.
+func (c Converter) Preview(n int) (string, error) {
+	if n <= 0 {
+		return "", fmt.Errorf("n must be positive, got %d", n)
+	}
+	c.MaxRows = int64(n)
+	c.TruncateError = false
+	c.AllResultSets = false
+	return c.WriteString()
+}
+
 // WriteFile writes the CSV to the filename specified, return an error if problem
 func (c Converter) WriteFile(csvFileName string) error {
-	f, err := os.Create(csvFileName)
+	return c.WriteFileContext(context.Background(), csvFileName)
+}
+
+// WriteFileContext is like WriteFile but can be cancelled through ctx
+func (c Converter) WriteFileContext(ctx context.Context, csvFileName string) (err error) {
+	defer c.closeRows(&err)
+	c.ownsRows, c.CloseRows = false, false // closed by the defer, just the once
+
+	if c.SchemaSidecar {
+		return c.writeFileWithSchema(ctx, csvFileName)
+	}
+	return c.writeFile(ctx, csvFileName)
+}
+
+func (c Converter) writeFile(ctx context.Context, csvFileName string) error {
+	if c.EmptyMode != EmptyWriteHeader {
+		return c.writeFileLazily(ctx, csvFileName)
+	}
+
+	f, err := c.createFile(csvFileName)
+	if err != nil {
+		return err
+	}
+
+	err = c.WriteContext(ctx, f)
+	if err != nil {
+		f.abort() // close, but only return/handle the write error
+		return err
+	}
+
+	return f.commit()
+}
+
+// AppendFile writes the CSV to the end of the filename specified, creating
+// it if it doesn't exist. If the file already has something in it the BOM and
+// the headers are left out (unless AppendHeaders is set), so that a running
+// file keeps a single header line. Otherwise it behaves like WriteFile,
+// except that Atomic doesn't apply: the rows are appended to the file as
+// they're written, and a failed export leaves those it got to.
+func (c Converter) AppendFile(csvFileName string) (err error) {
+	defer c.closeRows(&err)
+	c.ownsRows, c.CloseRows = false, false // closed by the defer, just the once
+
+	f, err := os.OpenFile(csvFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, c.fileMode())
 	if err != nil {
 		return err
 	}
 
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if info.Size() > 0 {
+		c.WriteBOM, c.WriteSepHint = false, false
+		c.WriteHeaders = c.WriteHeaders && c.AppendHeaders
+	}
+
 	err = c.Write(f)
 	if err != nil {
 		f.Close() // close, but only return/handle the write error
 		return err
 	}
 
+	if c.Sync {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+	}
 	return f.Close()
 }
 
 // Write writes the CSV to the Writer provided
 func (c Converter) Write(writer io.Writer) error {
-	rows := c.rows
-	csvWriter := csv.NewWriter(writer)
-	if c.Delimiter != '\x00' {
-		csvWriter.Comma = c.Delimiter
+	return c.WriteContext(context.Background(), writer)
+}
+
+// WriteContext writes the CSV to the Writer provided, checking ctx before
+// each row. If ctx is done the rows written so far are flushed and an error
+// wrapping ctx.Err() is returned, so errors.Is(err, context.Canceled) works.
+func (c Converter) WriteContext(ctx context.Context, writer io.Writer) error {
+	return c.writeCSV(ctx, writer)
+}
+
+// writeCSV does the work for WriteContext. If FlushEvery or FlushInterval is
+// set the output is flushed at that cadence, including calling Flush on dest
+// if it is an http.Flusher, so that streaming consumers see rows arrive.
+func (c Converter) writeCSV(ctx context.Context, dest io.Writer) (err error) {
+	defer c.recordError(&err)
+	defer c.closeRows(&err)
+
+	if err := c.Validate(); err != nil {
+		return err
 	}
 
-	columnNames, err := rows.Columns()
+	p, err := c.newPlan()
 	if err != nil {
 		return err
 	}
+	p.keepValues = c.needsNulls()
 
-	if c.WriteHeaders {
-		// use Headers if set, otherwise default to
-		// query Columns
-		var headers []string
-		if len(c.Headers) > 0 {
-			headers = c.Headers
-		} else {
-			headers = columnNames
-		}
-		err = csvWriter.Write(headers)
+	start := time.Now()
+	defer func() {
+		p.last.stats.Duration = time.Since(start)
+	}()
+
+	var sum hash.Hash
+	if c.checksum != nil {
+		c.checksum.Reset()
+		defer c.saveChecksum(p)
+		sum = c.checksum
+	}
+	out := c.newCSVOutput(dest, &p.last.stats.BytesWritten, sum)
+	writer, csvWriter, flush := out.writer, out.csvWriter, out.flush
+
+	// without EmptyWriteHeader nothing's written until there's a row
+	current, pending := p, c.EmptyMode != EmptyWriteHeader
+	if !pending {
+		if err = c.writePreamble(writer, csvWriter, p); err != nil {
+			return err
+		}
+	}
+
+	flusher, _ := dest.(http.Flusher)
+	written := 0
+	lastFlush := time.Now()
+	writeRow := func(row []string, values []any) error {
+		if pending {
+			if err := c.writePreamble(writer, csvWriter, current); err != nil {
+				return err
+			}
+			pending = false
+		}
+		if err := writeRecord(csvWriter, row, values); err != nil {
+			return fmt.Errorf("failed to write data row to csv %w", err)
+		}
+
+		written++
+		due := c.FlushEvery > 0 && written%c.FlushEvery == 0
+		if !due && c.FlushInterval > 0 {
+			due = time.Since(lastFlush) >= c.FlushInterval
+		}
+		if due {
+			if err := flush(false); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			lastFlush = time.Now()
+		}
+		return nil
+	}
+	// startSet begins each result set after the first, with a blank line
+	// and its own header row
+	startSet := func(next *plan) error {
+		current = next
+		if pending {
+			return nil
+		}
+		newline := "\n"
+		if c.UseCRLF {
+			newline = "\r\n"
+		}
+		if _, err := io.WriteString(writer, newline); err != nil {
+			return fmt.Errorf("failed to write result set separator: %w", err)
+		}
+		if c.WriteHeaders {
+			if err := csvWriter.Write(next.headers); err != nil {
+				return fmt.Errorf("failed to write headers: %w", err)
+			}
+		}
+		return nil
+	}
+
+	err = c.eachRow(ctx, p, writeRow)
+	if err == nil && c.AllResultSets {
+		err = c.writeResultSets(ctx, p, start, flush, writeRow, startSet)
+	}
+	if err == nil && c.EmptyMode == EmptyError && p.last.stats.RowsWritten == 0 {
+		err = ErrNoRows
+	}
+	if err == nil && !pending {
+		err = c.writeFooters(csvWriter, flush, func() Stats {
+			stats := p.last.stats
+			stats.Duration = time.Since(start)
+			return stats
+		})
+	}
+
+	if flushErr := flush(true); err == nil {
+		err = flushErr
+	}
+
+	return err
+}
+
+// saveChecksum records the digest of the export that has just finished.
+func (c Converter) saveChecksum(p *plan) {
+	p.last.checksum = c.checksum.Sum(nil)
+}
+
+// plan is the column layout of an export, worked out once before any rows
+// are read.
+type plan struct {
+	queryColumns []string    // as returned by rows.Columns()
+	selected     []int       // indexes into queryColumns, in output order
+	columnNames  []string    // query column names of the output columns
+	headers      []string    // header row to write
+	perColumn    []Converter // copies of the Converter with any per-column overrides applied
+
+	last          *lastRun          // where the results of this export are recorded
+	keepValues    bool              // set by writers that need typed values, see fieldValues
+	scanTypes     []reflect.Type    // what UseColumnTypes scans each query column into, nil for any
+	fieldReplacer *strings.Replacer // for TabNewlineReplacement and NewlineReplacement, nil if neither is set
+	quarantine    *quarantine       // for SetQuarantineWriter, nil if it isn't set
+	masks         []MaskFunc        // for MaskColumns, by output column, nil if none are masked
+
+	// Columns added to the output that don't come from the query, such as
+	// RowNumberHeader's, are counted in headers but not in selected.
+	before []extraColumn // extra columns before the selected ones
+	after  []extraColumn // extra columns after them
+
+	// the query columns left out of the output and their Converters, for
+	// AddComputedColumn's values
+	unselected           []int
+	unselectedConverters []Converter
+}
+
+// column returns the index into selected and columnNames of output column i,
+// or -1 if it's one of the extra columns.
+func (p *plan) column(i int) int {
+	i -= len(p.before)
+	if i < 0 || i >= len(p.selected) {
+		return -1
+	}
+	return i
+}
+
+// newPlan reads the columns of the result set and applies the column
+// selection settings to them.
+func (c Converter) newPlan() (*plan, error) {
+	if c.last != nil && c.last.consumed {
+		return nil, ErrRowsConsumed
+	}
+	queryColumns, err := c.rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := c.selectColumns(queryColumns)
+	if err != nil {
+		return nil, err
+	}
+	columnNames := make([]string, len(selected))
+	for i, idx := range selected {
+		columnNames[i] = queryColumns[idx]
+	}
+
+	// use Headers if set, otherwise default to
+	// query Columns
+	headers, err := c.makeHeaders(queryColumns, columnNames)
+	if err != nil {
+		return nil, err
+	}
+
+	var q *quarantine
+	if c.quarantineWriter != nil {
+		q = c.newQuarantine(headers)
+	}
+
+	before, after := c.extraColumns()
+	if len(before) > 0 || len(after) > 0 {
+		headers = withExtraHeaders(headers, before, after)
+	}
+	var unselected []int
+	var unselectedConverters []Converter
+	if len(c.computedColumns) > 0 {
+		for i, name := range queryColumns {
+			if !slices.Contains(selected, i) {
+				unselected = append(unselected, i)
+				unselectedConverters = append(unselectedConverters, c.forColumn(name))
+			}
+		}
+	}
+
+	perColumn := make([]Converter, len(columnNames))
+	for i, name := range columnNames {
+		perColumn[i] = c.forColumn(name)
+	}
+
+	last := c.last
+	if last == nil {
+		last = &lastRun{}
+	}
+	*last = lastRun{consumed: true}
+	last.stats.Columns = len(headers)
+	masks, masked, hashed, err := c.columnMasks(columnNames)
+	if err != nil {
+		return nil, err
+	}
+	last.stats.MaskedColumns, last.stats.HashedColumns = masked, hashed
+
+	p := &plan{
+		last:         last,
+		queryColumns: queryColumns,
+		selected:     selected,
+		columnNames:  columnNames,
+		headers:      headers,
+		perColumn:    perColumn,
+		masks:        masks,
+		before:       before,
+		after:        after,
+
+		unselected:           unselected,
+		unselectedConverters: unselectedConverters,
+		quarantine:           q,
+	}
+	if c.UseColumnTypes {
+		columnTypes, err := c.columnTypes()
 		if err != nil {
+			return nil, err
+		}
+		p.scanTypes = make([]reflect.Type, len(columnTypes))
+		for i, columnType := range columnTypes {
+			p.scanTypes[i] = scanTypeFor(columnType)
+		}
+	} else {
+		p.keepIntervalText(c)
+	}
+	if c.TabNewlineReplacement != nil || c.NewlineReplacement != nil {
+		var pairs []string
+		if r := c.TabNewlineReplacement; r != nil {
+			pairs = append(pairs, "\t", *r)
+		}
+		newline := c.NewlineReplacement
+		if newline == nil {
+			newline = c.TabNewlineReplacement
+		}
+		if r := newline; r != nil {
+			pairs = append(pairs, "\r\n", *r, "\r", *r, "\n", *r)
+		}
+		p.fieldReplacer = strings.NewReplacer(pairs...)
+	}
+	return p, nil
+}
+
+// forColumn returns a copy of the Converter with the per-column settings
+// for the named query column applied, for converting that column's values.
+func (c Converter) forColumn(name string) Converter {
+	if format, ok := c.TimeFormats[name]; ok {
+		c.TimeFormat = format
+	}
+	if format, ok := c.FloatFormats[name]; ok {
+		c.FloatFormat = format
+	}
+	if converter, ok := c.BinaryConverters[name]; ok {
+		c.BinaryConverter = converter
+	}
+	if slices.Contains(c.TrimColumns, name) {
+		c.TrimSpace = true
+	}
+	c.isJSON = slices.Contains(c.JSONColumns, name)
+	c.isDecimal = slices.Contains(c.DecimalColumns, name)
+	if format, ok := c.NumberFormats[name]; ok {
+		c.numberFormat = &format
+	}
+	return c
+}
+
+// recordWriter is the part of csv.Writer used to write the output, so that
+// the internal encoder can stand in for it when encoding/csv can't produce
+// what has been asked for.
+type recordWriter interface {
+	Write(record []string) error
+	Flush()
+	Error() error
+}
+
+// newCSVWriter returns a recordWriter configured with the Converter's
+// settings. This is a csv.Writer unless an option needs the internal encoder.
+func (c Converter) newCSVWriter(writer io.Writer) recordWriter {
+	comma, quote := c.delimiter(), c.quote()
+
+	if c.EscapeMode {
+		return &encoder{
+			w:          bufio.NewWriter(writer),
+			comma:      comma,
+			useCRLF:    c.UseCRLF,
+			escape:     c.escapeChar(),
+			copyText:   c.copyText,
+			nullString: c.NullString,
+		}
+	}
+
+	if c.UnquotedOutput {
+		return &encoder{
+			w:        bufio.NewWriter(writer),
+			comma:    comma,
+			useCRLF:  c.UseCRLF,
+			unquoted: true,
+			replacer: c.unquotedReplacer(),
+		}
+	}
+
+	if c.QuoteAll || quote != '"' || c.DelimiterString != "" || c.PreserveNullVsEmpty {
+		return &encoder{
+			w:             bufio.NewWriter(writer),
+			comma:         comma,
+			quote:         quote,
+			quoteAll:      c.QuoteAll,
+			useCRLF:       c.UseCRLF,
+			preserveNulls: c.PreserveNullVsEmpty,
+			nullString:    c.NullString,
+		}
+	}
+
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.Comma = c.comma()
+	csvWriter.UseCRLF = c.UseCRLF
+	return csvWriter
+}
+
+// comma returns the Delimiter, or a comma if it isn't set. Validate makes
+// sure it is for the CSV writers, but the quarantine of the others still falls
+// back on a comma.
+func (c Converter) comma() rune {
+	if c.Delimiter != '\x00' {
+		return c.Delimiter
+	}
+	return ','
+}
+
+// delimiter returns the DelimiterString, or the Delimiter if it isn't set.
+func (c Converter) delimiter() string {
+	if c.DelimiterString != "" {
+		return c.DelimiterString
+	}
+	return string(c.comma())
+}
+
+// quote returns the QuoteChar, or a double quote if it isn't set.
+func (c Converter) quote() rune {
+	if c.QuoteChar != '\x00' {
+		return c.QuoteChar
+	}
+	return '"'
+}
+
+// writePreamble writes everything that comes before the first data row.
+func (c Converter) writePreamble(writer io.Writer, csvWriter recordWriter, p *plan) error {
+	newline := "\n"
+	if c.UseCRLF {
+		newline = "\r\n"
+	}
+	if c.WriteSepHint {
+		if _, err := io.WriteString(writer, "sep="+c.delimiter()+newline); err != nil {
+			return fmt.Errorf("failed to write sep= line: %w", err)
+		}
+	} else if c.WriteBOM {
+		if _, err := io.WriteString(writer, utf8BOM); err != nil {
+			return fmt.Errorf("failed to write BOM: %w", err)
+		}
+	}
+
+	for _, line := range c.PrologueLines {
+		if _, err := io.WriteString(writer, line+newline); err != nil {
+			return fmt.Errorf("failed to write prologue: %w", err)
+		}
+	}
+
+	if c.WriteHeaders {
+		if err := csvWriter.Write(p.headers); err != nil {
 			return fmt.Errorf("failed to write headers: %w", err)
 		}
 	}
+	return nil
+}
 
-	count := len(columnNames)
-	values := make([]any, count)
-	valuePtrs := make([]any, count)
+// eachRow scans the remaining rows, converts them to strings and runs the
+// preprocessor, passing every row that should be written on to fn. If
+// p.keepValues is set fn also gets the typed value behind each field, see
+// plan.fieldValues; otherwise values is nil. Neither is valid once fn returns.
+func (c Converter) eachRow(ctx context.Context, p *plan, fn func(row []string, values []any) error) (err error) {
+	stats := &p.last.stats
+	if p.quarantine != nil {
+		defer func() {
+			if flushErr := p.quarantine.flush(); err == nil {
+				err = flushErr
+			}
+		}()
+	}
+	scan := c.scanRows
+	if c.Pipelined {
+		scan = c.scanRowsPipelined
+	}
 
-	for rows.Next() {
-		row := make([]string, count)
+	// The preprocessors are free to hang on to the slices they're given, but
+	// without them the same ones can be used for every row.
+	var rowBuf []string
+	if c.rowPreProcessor == nil {
+		rowBuf = make([]string, len(p.selected))
+	}
+	var fieldsBuf []any
+	if c.rawRowPreProcessor == nil {
+		fieldsBuf = make([]any, len(p.selected))
+	}
+	var (
+		scratch        []byte
+		extended       []string // row with the extra columns added
+		extendedValues []any    // and its values
+		source         map[string]string
+	)
+	if len(c.computedColumns) > 0 {
+		source = make(map[string]string, len(p.queryColumns))
+	}
+	dedup := c.newDeduplicator()
+	sorter, err := c.newRowSorter(p)
+	if err != nil {
+		return err
+	}
 
-		for i := range columnNames {
-			valuePtrs[i] = &values[i]
+	write := func(row []string, fieldValues []any) (bool, error) {
+		if err := fn(row, fieldValues); err != nil {
+			return false, err
+		}
+		stats.RowsWritten++
+		if c.progress != nil && c.progressEvery > 0 && stats.RowsWritten%int64(c.progressEvery) == 0 {
+			c.progress(stats.RowsWritten)
 		}
+		return c.MaxRows > 0 && stats.RowsWritten >= c.MaxRows, nil
+	}
+	appended := make([]bool, len(p.selected))
+	custom := make([]bool, len(p.selected))
+	ends := make([]int, len(p.selected))
 
-		if err = rows.Scan(valuePtrs...); err != nil {
-			return err
+	truncated, err := scan(ctx, p, func(rowNumber int, values []any) (bool, error) {
+		row := rowBuf
+		if row == nil {
+			row = make([]string, len(p.selected))
 		}
 
-		for i := range columnNames {
-			row[i] = c.toString(values[i])
+		fields := fieldsBuf
+		if fields == nil {
+			fields = make([]any, len(p.selected))
+		}
+		for i, idx := range p.selected {
+			fields[i] = values[idx]
+		}
+
+		if c.rawRowPreProcessor != nil {
+			var writeRow bool
+			writeRow, fields = c.rawRowPreProcessor(fields, p.columnNames)
+			if !writeRow {
+				stats.RowsSkipped++
+				return false, nil
+			}
+			if len(fields) != len(p.selected) {
+				return false, c.rowFailed(p, rowNumber, c.rawFields(fields), fmt.Errorf("raw row preprocessor returned %d values for row %d, expected %d", len(fields), rowNumber, len(p.selected)))
+			}
+		}
+
+		scratch = scratch[:0]
+		for i, name := range p.columnNames {
+			appended[i], custom[i] = false, false
+			var err error
+			if formatter := c.columnFormatters[name]; formatter != nil {
+				if row[i], err = formatter(fields[i]); err != nil {
+					return false, c.rowFailed(p, rowNumber, c.rawFields(fields), fmt.Errorf("failed to format column %q in row %d: %w", name, rowNumber, err))
+				}
+				custom[i] = true
+			} else if c.valueConverter != nil {
+				if row[i], custom[i], err = c.valueConverter(name, fields[i]); err != nil {
+					return false, c.rowFailed(p, rowNumber, c.rawFields(fields), fmt.Errorf("failed to convert column %q in row %d: %w", name, rowNumber, err))
+				}
+			}
+			if !custom[i] {
+				if v := unwrapNull(fields[i]); v != nil {
+					scratch, appended[i] = p.perColumn[i].appendValue(scratch, v)
+				}
+				if !appended[i] {
+					if row[i], err = p.perColumn[i].convert(fields[i]); err != nil {
+						return false, c.rowFailed(p, rowNumber, c.rawFields(fields), fmt.Errorf("failed to convert column %q in row %d: %w", name, rowNumber, err))
+					}
+				}
+			}
+			ends[i] = len(scratch)
+		}
+		if len(scratch) > 0 {
+			text, start := string(scratch), 0
+			for i, end := range ends {
+				if appended[i] {
+					row[i] = text[start:end]
+				}
+				start = end
+			}
+		}
+
+		var converted []string
+		if p.keepValues && c.rowPreProcessor != nil {
+			converted = append([]string(nil), row...)
+		}
+		if source != nil {
+			if err := c.sourceValues(p, source, row, values, rowNumber); err != nil {
+				return false, c.rowFailed(p, rowNumber, row, err)
+			}
 		}
 
 		writeRow := true
 		if c.rowPreProcessor != nil {
-			writeRow, row = c.rowPreProcessor(row, columnNames)
+			writeRow, row = c.rowPreProcessor(row, p.columnNames)
+		}
+		if !writeRow || (c.SkipEmptyRows && allEmpty(row)) {
+			stats.RowsSkipped++
+			return false, nil
+		}
+
+		if width := len(p.headers) - len(p.before) - len(p.after); !c.AllowRaggedRows && len(row) != width {
+			return false, c.rowFailed(p, rowNumber, row, fmt.Errorf("row %d has %d fields but there are %d headers", rowNumber, len(row), width))
+		}
+		c.rewriteFields(p, row)
+		if c.rowValidator != nil {
+			if err := c.rowValidator(row, p.columnNames); err != nil {
+				return false, c.rowFailed(p, rowNumber, row, fmt.Errorf("row %d rejected: %w", rowNumber, err))
+			}
+		}
+		if dedup != nil && dedup.duplicate(row) {
+			stats.DuplicateRows++
+			return false, nil
+		}
+		var fieldValues []any
+		if p.keepValues {
+			fieldValues = p.fieldValues(row, converted, fields, custom)
 		}
-		if writeRow {
-			err = csvWriter.Write(row)
+		if p.masks != nil {
+			c.maskFields(p, row, fields, fieldValues)
+		}
+		if len(p.before) > 0 || len(p.after) > 0 {
+			var err error
+			row, fieldValues, err = p.addExtraColumns(extended[:0], extendedValues[:0], row, fieldValues, stats.RowsWritten+1, source, rowNumber)
 			if err != nil {
-				return fmt.Errorf("failed to write data row to csv %w", err)
+				return false, c.rowFailed(p, rowNumber, row, err)
 			}
+			extended, extendedValues = row, fieldValues
+		}
+		if sorter != nil {
+			return false, sorter.add(row, fieldValues, rowNumber)
+		}
+		return write(row, fieldValues)
+	})
+	if err != nil {
+		return err
+	}
+	if sorter != nil {
+		if truncated, err = c.writeSorted(sorter, write); err != nil {
+			return err
 		}
 	}
-	err = rows.Err()
+	p.last.truncated = truncated
+
+	if c.progress != nil {
+		c.progress(stats.RowsWritten)
+	}
+	if p.last.truncated && c.TruncateError {
+		return ErrTruncated
+	}
+	return nil
+}
 
-	csvWriter.Flush()
+// scanRowFunc is handed each scanned row by a scanner, with its 1-based
+// position in the result set. It returns true once it wants no more rows.
+type scanRowFunc func(rowNumber int, values []any) (bool, error)
 
-	return err
+// scanRows reads the remaining rows one at a time, dropping the first
+// SkipRows, and passes them on to fn. values, and the destinations scanned
+// into, are reused from row to row. If fn stops early scanRows peeks to
+// report whether any rows were left unread, but doesn't read them.
+func (c Converter) scanRows(ctx context.Context, p *plan, fn scanRowFunc) (bool, error) {
+	rows := c.rows
+	values := make([]any, len(p.queryColumns))
+	valuePtrs := make([]any, len(p.queryColumns))
+	p.scanPointers(values, valuePtrs)
+	rowNumber := 0
+
+	for rows.Next() {
+		rowNumber++
+		if err := ctx.Err(); err != nil {
+			return false, fmt.Errorf("export cancelled: %w", err)
+		}
+
+		if err := rows.Scan(valuePtrs...); err != nil {
+			if err := c.rowFailed(p, rowNumber, c.scannedFields(p), err); err != nil {
+				return false, err
+			}
+			continue
+		}
+		if int64(rowNumber) <= c.SkipRows {
+			continue
+		}
+		p.collectScanned(values, valuePtrs)
+
+		done, err := fn(rowNumber, values)
+		if err != nil {
+			return false, err
+		}
+		if done {
+			return rows.Next(), nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// allEmpty reports whether every field of row is an empty string.
+func allEmpty(row []string) bool {
+	for _, field := range row {
+		if field != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// rewriteFields applies the in-place substitutions configured for data
+// values once the preprocessor has had its turn, truncating them last so
+// that the fields written respect MaxFieldLength.
+func (c Converter) rewriteFields(p *plan, row []string) {
+	if p.fieldReplacer != nil {
+		for i, field := range row {
+			row[i] = p.fieldReplacer.Replace(field)
+		}
+	}
+	if c.MaxFieldLength > 0 {
+		for i, field := range row {
+			if len(field) > c.MaxFieldLength && utf8.RuneCountInString(field) > c.MaxFieldLength {
+				row[i] = truncateField(field, c.MaxFieldLength, c.TruncateMarker)
+				p.last.stats.FieldsTruncated++
+			}
+		}
+	}
+}
+
+// truncateField cuts field, which is longer than limit runes, down to limit
+// runes ending with marker. The marker is left out if it doesn't fit.
+func truncateField(field string, limit int, marker string) string {
+	keep := limit - utf8.RuneCountInString(marker)
+	if keep < 0 {
+		keep, marker = limit, ""
+	}
+	for i := range field {
+		if keep == 0 {
+			return field[:i] + marker
+		}
+		keep--
+	}
+	return field + marker
+}
+
+// fieldValues pairs each field of row with the scanned value it was
+// converted from, for writers that can represent some types natively. A
+// field holds its string instead when a column formatter or value converter
+// produced it (as flagged in custom), when the preprocessor changed it, or
+// when the preprocessor changed the width of the row. converted is the row as
+// it was before the preprocessor ran, or nil if there is no preprocessor, and
+// values are the scanned values of the output columns.
+func (p *plan) fieldValues(row []string, converted []string, values []any, custom []bool) []any {
+	out := make([]any, len(row))
+	sameShape := converted == nil || len(converted) == len(row)
+	for i, field := range row {
+		out[i] = field
+		if !sameShape || i >= len(p.selected) || custom[i] {
+			continue
+		}
+		if converted != nil && converted[i] != field {
+			continue
+		}
+		out[i] = unwrapNull(values[i])
+	}
+	return out
 }
 
 // New will return a Converter which will write your CSV however you like
 // but will allow you to set a bunch of non-default behaivour like overriding
 // headers or injecting a pre-processing step into your conversion
 func New(rows *sql.Rows) *Converter {
-	return &Converter{
-		rows:         rows,
-		WriteHeaders: true,
-		Delimiter:    ',',
+	return NewFromSource(rows)
+}
+
+// newClosing is New for the package's Write functions, which close the rows
+// once they're done with them.
+func newClosing(rows *sql.Rows) *Converter {
+	c := New(rows)
+	c.CloseRows = true
+	return c
+}
+
+// formatUUID returns the canonical text form of a 16 byte UUID.
+func formatUUID(b []byte) string {
+	var text [36]byte
+	hex.Encode(text[0:8], b[0:4])
+	text[8] = '-'
+	hex.Encode(text[9:13], b[4:6])
+	text[13] = '-'
+	hex.Encode(text[14:18], b[6:8])
+	text[18] = '-'
+	hex.Encode(text[19:23], b[8:10])
+	text[23] = '-'
+	hex.Encode(text[24:], b[10:])
+	return string(text[:])
+}
+
+// unwrapNull returns the value held by the database/sql Null types, or nil
+// if they aren't Valid. Any other value is returned as is.
+func unwrapNull(v any) any {
+	switch val := v.(type) {
+	case sql.NullString:
+		if val.Valid {
+			return val.String
+		}
+		return nil
+	case sql.NullInt64:
+		if val.Valid {
+			return val.Int64
+		}
+		return nil
+	case sql.Null[uint64]:
+		if val.Valid {
+			return val.V
+		}
+		return nil
+	case sql.NullInt32:
+		if val.Valid {
+			return val.Int32
+		}
+		return nil
+	case sql.NullInt16:
+		if val.Valid {
+			return val.Int16
+		}
+		return nil
+	case sql.NullByte:
+		if val.Valid {
+			return val.Byte
+		}
+		return nil
+	case sql.NullFloat64:
+		if val.Valid {
+			return val.Float64
+		}
+		return nil
+	case sql.NullBool:
+		if val.Valid {
+			return val.Bool
+		}
+		return nil
+	case sql.NullTime:
+		if val.Valid {
+			return val.Time
+		}
+		return nil
+	}
+	return v
+}
+
+// convert is toString for values that might be a driver.Valuer, such as
+// custom ID and money types. Those are converted through the base type their
+// Value method returns, and an error from it is returned.
+func (c Converter) convert(v any) (string, error) {
+	if valuer, ok := v.(driver.Valuer); ok {
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return c.toString(nil), nil
+		}
+		value, err := valuer.Value()
+		if err != nil {
+			return "", err
+		}
+		// by contract value is a base type, but don't go round in circles
+		// if it isn't
+		if _, again := value.(driver.Valuer); !again {
+			v = value
+		}
+	}
+	if c.isJSON {
+		if text, ok, err := c.jsonColumnText(v); ok {
+			return text, err
+		}
+	}
+	return c.toString(v), nil
+}
+
+// text applies the options for text values to a string or []byte value.
+func (c Converter) text(s string) string {
+	if c.TrimSpace {
+		s = strings.TrimSpace(s)
+	}
+	if c.isDecimal && isNumericText(s) {
+		s = c.decimalText(s)
 	}
+	return c.sanitizeFormula(s)
 }
 
 // toString converts any value to string.
 func (c Converter) toString(v any) string {
+	v = unwrapNull(v)
 	if v == nil {
-		return ""
+		return c.NullString
+	}
+	if c.numberFormat != nil {
+		var scratch [64]byte
+		if b, ok := c.appendNumber(scratch[:0], v); ok {
+			return string(b)
+		}
 	}
 	switch val := v.(type) {
 	case string:
-		return val
+		return c.text(val)
 	case []byte:
 		switch c.BinaryConverter {
 		case StdBase64:
@@ -216,56 +1456,121 @@ func (c Converter) toString(v any) string {
 			return base64.RawURLEncoding.EncodeToString(val)
 		case Hex:
 			return hex.EncodeToString(val)
+		case UUIDCanonical:
+			if len(val) == 16 {
+				return formatUUID(val)
+			}
 		}
-		return string(val)
+		return c.text(string(val))
 	case bool:
+		if val && c.TrueString != "" {
+			return c.TrueString
+		}
+		if !val && c.FalseString != "" {
+			return c.FalseString
+		}
 		return strconv.FormatBool(val)
+	case float32:
+		if special, ok := c.specialFloat(float64(val)); ok {
+			return special
+		}
+		if c.FloatFormat != "" {
+			return c.decimalText(fmt.Sprintf(c.FloatFormat, val))
+		}
+	case float64:
+		if special, ok := c.specialFloat(val); ok {
+			return special
+		}
+		if c.FloatFormat != "" {
+			return c.decimalText(fmt.Sprintf(c.FloatFormat, val))
+		}
+	case time.Duration:
+		return c.formatDuration(val)
+	}
+	var scratch [64]byte
+	if b, ok := c.appendValue(scratch[:0], v); ok {
+		return string(b)
+	}
+	if jsonMarshaler, ok := v.(json.Marshaler); ok {
+		if jsonData, err := jsonMarshaler.MarshalJSON(); err == nil {
+			return jsonText(jsonData)
+		}
+	}
+	if fmtStringer, ok := v.(fmt.Stringer); ok {
+		return fmtStringer.String()
+	}
+	if jsonData, err := json.Marshal(v); err == nil {
+		return jsonText(jsonData)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// jsonText returns the text to write for a value marshalled to JSON: the
+// string itself if it's a JSON string, or else the JSON as it is.
+func jsonText(data []byte) string {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '"' {
+		var s string
+		if err := json.Unmarshal(trimmed, &s); err == nil {
+			return s
+		}
+	}
+	return string(data)
+}
+
+// appendValue appends the text of the numbers and times that toString would
+// otherwise format into a string of their own to dst, so that a row's worth
+// of them can share one allocation. It reports false, leaving dst as it is,
+// for any other value.
+func (c Converter) appendValue(dst []byte, v any) ([]byte, bool) {
+	if number, ok := c.appendNumber(dst, v); ok {
+		return number, true
+	}
+	switch val := v.(type) {
 	case int:
-		return strconv.Itoa(val)
+		return strconv.AppendInt(dst, int64(val), 10), true
 	case int8:
-		return strconv.FormatInt(int64(val), 10)
+		return strconv.AppendInt(dst, int64(val), 10), true
 	case int16:
-		return strconv.FormatInt(int64(val), 10)
+		return strconv.AppendInt(dst, int64(val), 10), true
 	case int32:
-		return strconv.FormatInt(int64(val), 10)
+		return strconv.AppendInt(dst, int64(val), 10), true
 	case int64:
-		return strconv.FormatInt(val, 10)
+		return strconv.AppendInt(dst, val, 10), true
 	case uint:
-		return strconv.FormatUint(uint64(val), 10)
+		return strconv.AppendUint(dst, uint64(val), 10), true
 	case uint8:
-		return strconv.FormatUint(uint64(val), 10)
+		return strconv.AppendUint(dst, uint64(val), 10), true
 	case uint16:
-		return strconv.FormatUint(uint64(val), 10)
+		return strconv.AppendUint(dst, uint64(val), 10), true
 	case uint32:
-		return strconv.FormatUint(uint64(val), 10)
+		return strconv.AppendUint(dst, uint64(val), 10), true
 	case uint64:
-		return strconv.FormatUint(val, 10)
+		return strconv.AppendUint(dst, val, 10), true
 	case time.Time:
+		if epoch, ok := c.appendEpoch(dst, val); ok {
+			return epoch, true
+		}
+		if c.TimeLocation != nil {
+			val = val.In(c.TimeLocation)
+		}
 		if c.TimeFormat != "" {
-			return val.Format(c.TimeFormat)
+			return val.AppendFormat(dst, c.TimeFormat), true
 		}
-		return val.Format(time.RFC3339Nano)
+		return val.AppendFormat(dst, time.RFC3339Nano), true
 	case float32:
-		if c.FloatFormat != "" {
-			return fmt.Sprintf(c.FloatFormat, val)
+		if special, ok := c.specialFloat(float64(val)); ok {
+			return append(dst, special...), true
+		}
+		if c.FloatFormat == "" {
+			return c.appendDecimal(strconv.AppendFloat(dst, float64(val), 'f', -1, 32), len(dst)), true
 		}
-		return strconv.FormatFloat(float64(val), 'f', -1, 32)
 	case float64:
-		if c.FloatFormat != "" {
-			return fmt.Sprintf(c.FloatFormat, val)
+		if special, ok := c.specialFloat(val); ok {
+			return append(dst, special...), true
 		}
-		return strconv.FormatFloat(val, 'f', -1, 64)
-	}
-	if jsonMarshaler, ok := v.(json.Marshaler); ok {
-		if jsonData, err := jsonMarshaler.MarshalJSON(); err == nil {
-			return strings.Trim(string(jsonData), `"`)
+		if c.FloatFormat == "" {
+			return c.appendDecimal(strconv.AppendFloat(dst, val, 'f', -1, 64), len(dst)), true
 		}
 	}
-	if fmtStringer, ok := v.(fmt.Stringer); ok {
-		return fmtStringer.String()
-	}
-	if jsonData, err := json.Marshal(v); err == nil {
-		return strings.Trim(string(jsonData), `"`)
-	}
-	return fmt.Sprintf("%v", v)
+	return dst, false
 }
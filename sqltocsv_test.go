@@ -2,13 +2,24 @@ package sqltocsv_test
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
+	_ "time/tzdata"
 
-	"github.com/joho/sqltocsv"
+	"github.com/armantarkhanian/sqltocsv"
 )
 
 func init() {
@@ -32,6 +43,47 @@ func TestWriteFile(t *testing.T) {
 	})
 }
 
+func TestAppendFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "running.csv")
+	for i := 0; i < 2; i++ {
+		converter := getConverter(t)
+		converter.WriteBOM = true
+		if err := converter.AppendFile(name); err != nil {
+			t.Fatalf("error in AppendFile: %v", err)
+		}
+	}
+
+	contents, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("error reading %v: %v", name, err)
+	}
+
+	expected := "\xEF\xBB\xBFname,age,bdate\nAlice,1,1973-11-29T21:33:09Z\nAlice,1,1973-11-29T21:33:09Z\n"
+	assertCsvMatch(t, expected, string(contents))
+	if headers := strings.Count(string(contents), "name,age,bdate"); headers != 1 {
+		t.Errorf("expected a single header line, got %d", headers)
+	}
+}
+
+func TestAppendFileHeaders(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "running.csv")
+	if err := os.WriteFile(name, []byte("old,row\n"), 0o644); err != nil {
+		t.Fatalf("error writing %v: %v", name, err)
+	}
+
+	converter := getConverter(t)
+	converter.AppendHeaders = true
+	if err := converter.AppendFile(name); err != nil {
+		t.Fatalf("error in AppendFile: %v", err)
+	}
+
+	contents, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("error reading %v: %v", name, err)
+	}
+	assertCsvMatch(t, "old,row\nname,age,bdate\nAlice,1,1973-11-29T21:33:09Z\n", string(contents))
+}
+
 func TestWrite(t *testing.T) {
 	checkQueryAgainstResult(t, func(rows *sql.Rows) string {
 		buffer := &bytes.Buffer{}
@@ -62,7 +114,7 @@ func TestWriteHeaders(t *testing.T) {
 
 	converter.WriteHeaders = false
 
-	expected := "Alice,1,1973-11-29 21:33:09 +0000 UTC\n"
+	expected := "Alice,1,1973-11-29T21:33:09Z\n"
 	actual := converter.String()
 
 	assertCsvMatch(t, expected, actual)
@@ -73,7 +125,7 @@ func TestSetHeaders(t *testing.T) {
 
 	converter.Headers = []string{"Name", "Age", "Birthday"}
 
-	expected := "Name,Age,Birthday\nAlice,1,1973-11-29 21:33:09 +0000 UTC\n"
+	expected := "Name,Age,Birthday\nAlice,1,1973-11-29T21:33:09Z\n"
 	actual := converter.String()
 
 	assertCsvMatch(t, expected, actual)
@@ -131,7 +183,7 @@ func TestAlternateDelimiter(t *testing.T) {
 
 	converter.Delimiter = '|'
 
-	expected := "name|age|bdate\nAlice|1|1973-11-29 21:33:09 +0000 UTC\n"
+	expected := "name|age|bdate\nAlice|1|1973-11-29T21:33:09Z\n"
 	actual := converter.String()
 
 	assertCsvMatch(t, expected, actual)
@@ -143,30 +195,1318 @@ func TestMissingDelimiter(t *testing.T) {
 	var delimiter rune
 	converter.Delimiter = delimiter
 
-	expected := "name,age,bdate\nAlice,1,1973-11-29 21:33:09 +0000 UTC\n"
+	csv, err := converter.WriteString()
+	if err == nil || !strings.Contains(err.Error(), "NUL") {
+		t.Errorf("expected an error for a NUL delimiter, got %v", err)
+	}
+	if csv != "" {
+		t.Errorf("expected nothing to be written, got %q", csv)
+	}
+}
+
+func TestWriteContextCancelled(t *testing.T) {
+	converter := getConverter(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	buffer := &bytes.Buffer{}
+	err := converter.WriteContext(ctx, buffer)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	assertCsvMatch(t, "name,age,bdate\n", buffer.String())
+}
+
+func TestWriteContext(t *testing.T) {
+	checkQueryAgainstResult(t, func(rows *sql.Rows) string {
+		buffer := &bytes.Buffer{}
+
+		err := sqltocsv.WriteContext(context.Background(), buffer, rows)
+		if err != nil {
+			t.Fatalf("error in WriteContext: %v", err)
+		}
+
+		return buffer.String()
+	})
+}
+
+func TestWriteBOM(t *testing.T) {
+	converter := getConverter(t)
+	converter.WriteBOM = true
+
+	actual := converter.String()
+	if !strings.HasPrefix(actual, "\xEF\xBB\xBF") {
+		t.Fatalf("expected output to start with a UTF-8 BOM, got %q", actual[:3])
+	}
+
+	assertCsvMatch(t, "\xEF\xBB\xBFname,age,bdate\nAlice,1,1973-11-29T21:33:09Z\n", actual)
+}
+
+func TestWriteBOMWithoutHeaders(t *testing.T) {
+	converter := getConverter(t)
+	converter.WriteBOM = true
+	converter.WriteHeaders = false
+
+	expected := "\xEF\xBB\xBFAlice,1,1973-11-29T21:33:09Z\n"
 	actual := converter.String()
 
 	assertCsvMatch(t, expected, actual)
 }
 
-func checkQueryAgainstResult(t *testing.T, innerTestFunc func(*sql.Rows) string) {
-	rows := getTestRows(t)
+func TestWriteSepHint(t *testing.T) {
+	converter := getConverter(t)
+	converter.WriteSepHint = true
+	converter.Delimiter = ';'
+	assertCsvMatch(t, "sep=;\nname;age;bdate\nAlice;1;1973-11-29T21:33:09Z\n", converter.String())
 
-	expected := "name,age,bdate\nAlice,1,1973-11-29 21:33:09 +0000 UTC\n"
+	converter = getConverter(t)
+	converter.WriteSepHint = true
+	converter.UseCRLF = true
+	assertCsvMatch(t, "sep=,\r\nname,age,bdate\r\nAlice,1,1973-11-29T21:33:09Z\r\n", converter.String())
+}
 
-	actual := innerTestFunc(rows)
+func TestWriteSepHintWithBOM(t *testing.T) {
+	converter := getConverter(t)
+	converter.WriteSepHint = true
+	converter.WriteBOM = true
+	converter.Delimiter = '\t'
+
+	// the sep= line has to come first, and Excel ignores a BOM after it
+	assertCsvMatch(t, "sep=\t\nname\tage\tbdate\nAlice\t1\t1973-11-29T21:33:09Z\n", converter.String())
+}
+
+func TestPrologueLines(t *testing.T) {
+	converter := getConverter(t)
+	converter.PrologueLines = []string{"# generated: 2024-01-02T03:04:05Z", "# source: billing, \"eu\""}
+	converter.UseCRLF = true
+	converter.WriteBOM = true
+	sum := sha256.New()
+	converter.SetChecksum(sum)
+
+	csv, stats, err := converter.WriteStringWithStats()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+	expected := "\xEF\xBB\xBF# generated: 2024-01-02T03:04:05Z\r\n# source: billing, \"eu\"\r\nname,age,bdate\r\nAlice,1,1973-11-29T21:33:09Z\r\n"
+	assertCsvMatch(t, expected, csv)
+	if stats.BytesWritten != int64(len(expected)) {
+		t.Errorf("expected %d bytes written, got %d", len(expected), stats.BytesWritten)
+	}
+	if digest := sha256.Sum256([]byte(expected)); !bytes.Equal(converter.Checksum(), digest[:]) {
+		t.Errorf("expected the checksum to cover the prologue")
+	}
+}
+
+func TestPrologueLinesNewline(t *testing.T) {
+	converter := getConverter(t)
+	converter.PrologueLines = []string{"# one", "# two\n# three"}
+	if _, err := converter.WriteString(); err == nil || !strings.Contains(err.Error(), "prologue line 2") {
+		t.Errorf("expected an error for the line break, got %v", err)
+	}
+}
+
+func TestDurationFormat(t *testing.T) {
+	durations := []any{time.Hour + 23*time.Minute + 45*time.Second + 500*time.Millisecond, time.Duration(0), -90*time.Second - time.Microsecond, nil}
+	for format, expected := range map[sqltocsv.DurationFormat]string{
+		sqltocsv.DurationString:       "d\n1h23m45.5s\n0s\n-1m30.000001s\n\n",
+		sqltocsv.DurationSeconds:      "d\n5025.5\n0\n-90.000001\n\n",
+		sqltocsv.DurationMilliseconds: "d\n5025500\n0\n-90000\n\n",
+	} {
+		rows := make([][]any, len(durations))
+		for i, d := range durations {
+			rows[i] = []any{d}
+		}
+		converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"d"}, rows: rows})
+		converter.DurationFormat = format
+		assertCsvMatch(t, expected, converter.String())
+	}
+}
+
+func TestIntervalColumns(t *testing.T) {
+	for _, useColumnTypes := range []bool{true, false} {
+		db := sql.OpenDB(typedConnector{
+			columns: []typedColumn{
+				{"age", "INTERVAL", reflect.TypeFor[[]byte]()},
+				{"data", "BYTEA", reflect.TypeFor[[]byte]()},
+			},
+			rows: [][]driver.Value{{[]byte("1 year 2 mons -3 days 04:05:06"), []byte("hi")}, {nil, nil}},
+		})
+		t.Cleanup(func() { db.Close() })
+		rows, err := db.Query("SELECT")
+		if err != nil {
+			t.Fatalf("error querying: %v", err)
+		}
+
+		converter := sqltocsv.New(rows)
+		converter.UseColumnTypes = useColumnTypes
+		converter.BinaryConverter = sqltocsv.Hex
+		converter.NullString = "NULL"
+		assertCsvMatch(t, "age,data\n1 year 2 mons -3 days 04:05:06,6869\nNULL,NULL\n", converter.String())
+	}
+}
+
+func TestEpochTimeFormats(t *testing.T) {
+	times := []any{
+		time.Date(2024, 1, 2, 3, 4, 5, 678901000, time.FixedZone("", 3600)),
+		time.Unix(0, 0),
+		time.Date(1969, 12, 31, 23, 59, 59, 500000000, time.UTC),
+		sql.NullTime{Time: time.Unix(-86400, 0), Valid: true},
+		sql.NullTime{},
+	}
+	for format, expected := range map[string]string{
+		sqltocsv.EpochSeconds: "t\n1704161045\n0\n-1\n-86400\n\n",
+		sqltocsv.EpochMillis:  "t\n1704161045678\n0\n-500\n-86400000\n\n",
+		sqltocsv.EpochMicros:  "t\n1704161045678901\n0\n-500000\n-86400000000\n\n",
+	} {
+		rows := make([][]any, len(times))
+		for i, value := range times {
+			rows[i] = []any{value}
+		}
+		converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"t"}, rows: rows})
+		converter.TimeFormat = format
+		converter.TimeLocation = time.UTC
+		assertCsvMatch(t, expected, converter.String())
+	}
+}
+
+func TestEpochTimeFormatsPerColumn(t *testing.T) {
+	converter := getConverter(t)
+	converter.TimeFormats = map[string]string{"bdate": sqltocsv.EpochMillis}
+	assertCsvMatch(t, "name,age,bdate\nAlice,1,123456789000\n", converter.String())
+}
+
+func TestSpecialFloats(t *testing.T) {
+	rows := [][]any{
+		{math.NaN(), float32(math.NaN())},
+		{math.Inf(1), float32(math.Inf(1))},
+		{math.Inf(-1), float32(math.Inf(-1))},
+		{1.5, float32(2.5)},
+	}
+	empty, null, inf, negInf := "", `\N`, "Infinity", "-Infinity"
+	for i, test := range []struct {
+		nan, inf, negInf *string
+		floatFormat      string
+		expected         string
+	}{
+		{expected: "f64,f32\nNaN,NaN\n+Inf,+Inf\n-Inf,-Inf\n1.5,2.5\n"},
+		{nan: &empty, inf: &null, negInf: &null, expected: "f64,f32\n,\n\\N,\\N\n\\N,\\N\n1.5,2.5\n"},
+		{nan: &null, inf: &inf, floatFormat: "%.2f", expected: "f64,f32\n\\N,\\N\nInfinity,Infinity\n-Infinity,-Infinity\n1.50,2.50\n"},
+		{negInf: &negInf, expected: "f64,f32\nNaN,NaN\n+Inf,+Inf\n-Infinity,-Infinity\n1.5,2.5\n"},
+	} {
+		converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"f64", "f32"}, rows: rows})
+		converter.NaNString, converter.InfString, converter.NegInfString = test.nan, test.inf, test.negInf
+		converter.FloatFormat = test.floatFormat
+		csv, err := converter.WriteString()
+		if err != nil {
+			t.Fatalf("%d: error in WriteString: %v", i, err)
+		}
+		assertCsvMatch(t, test.expected, csv)
+	}
+}
+
+func TestUseCRLF(t *testing.T) {
+	converter := getConverter(t)
+	assertCsvMatch(t, "name,age,bdate\nAlice,1,1973-11-29T21:33:09Z\n", converter.String())
+
+	converter = getConverter(t)
+	converter.UseCRLF = true
+	assertCsvMatch(t, "name,age,bdate\r\nAlice,1,1973-11-29T21:33:09Z\r\n", converter.String())
+}
+
+func TestNullString(t *testing.T) {
+	converter := sqltocsv.New(getTestRowsByQuery(t, "SELECT|people|name,nickname,age|"))
+	converter.NullString = `\N`
+
+	expected := "name,nickname,age\nAlice,\\N,1\n"
+	actual := converter.String()
 
 	assertCsvMatch(t, expected, actual)
 }
 
-func getTestRows(t *testing.T) *sql.Rows {
-	return getTestRowsByQuery(t, "SELECT|people|name,age,bdate|")
+func TestNullStringSkipsFormatters(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "CREATE|measurements|label=string,reading=nullfloat64,taken=datetime")
+	exec(t, db, "INSERT|measurements|label=a,reading=?,taken=?", nil, nil)
+
+	rows, err := db.Query("SELECT|measurements|label,reading,taken|")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+
+	converter := sqltocsv.New(rows)
+	converter.NullString = "NULL"
+	converter.FloatFormat = "%.2f"
+	converter.TimeFormat = time.Kitchen
+
+	expected := "label,reading,taken\na,NULL,NULL\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
 }
 
-func getTestRowsByQuery(t *testing.T, query string) *sql.Rows {
+func TestSetColumnFormatter(t *testing.T) {
+	converter := getConverter(t)
+
+	converter.SetColumnFormatter("age", func(value any) (string, error) {
+		return fmt.Sprintf("%T:%v", value, value), nil
+	})
+	converter.SetColumnFormatter("missing", func(value any) (string, error) {
+		return "", errors.New("should not be called")
+	})
+
+	expected := "name,age,bdate\nAlice,int64:1,1973-11-29T21:33:09Z\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+}
+
+func TestSetColumnFormatterError(t *testing.T) {
+	converter := getConverter(t)
+
+	formatErr := errors.New("bad age")
+	converter.SetColumnFormatter("age", func(value any) (string, error) {
+		return "", formatErr
+	})
+
+	_, err := converter.WriteString()
+	if !errors.Is(err, formatErr) {
+		t.Fatalf("expected formatter error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `"age"`) || !strings.Contains(err.Error(), "row 1") {
+		t.Errorf("expected error to mention column and row, got %v", err)
+	}
+}
+
+func TestSetValueConverter(t *testing.T) {
+	converter := getConverter(t)
+
+	var seen []string
+	converter.SetValueConverter(func(columnName string, value any) (string, bool, error) {
+		seen = append(seen, columnName)
+		if n, ok := value.(int64); ok {
+			return fmt.Sprintf("#%d", n), true, nil
+		}
+		return "", false, nil
+	})
+	converter.SetColumnFormatter("bdate", func(value any) (string, error) {
+		return "formatted", nil
+	})
+
+	expected := "name,age,bdate\nAlice,#1,formatted\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+	if strings.Join(seen, ",") != "name,age" {
+		t.Errorf("expected the converter to see name and age, got %v", seen)
+	}
+}
+
+func TestSetValueConverterError(t *testing.T) {
+	converter := getConverter(t)
+
+	convertErr := errors.New("unknown type")
+	converter.SetValueConverter(func(columnName string, value any) (string, bool, error) {
+		if columnName == "bdate" {
+			return "", false, convertErr
+		}
+		return "", false, nil
+	})
+
+	_, err := converter.WriteString()
+	if !errors.Is(err, convertErr) {
+		t.Fatalf("expected converter error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `"bdate"`) || !strings.Contains(err.Error(), "row 1") {
+		t.Errorf("expected error to mention column and row, got %v", err)
+	}
+}
+
+type userID int64
+
+func (id userID) Value() (driver.Value, error) { return int64(id) * 10, nil }
+
+type money struct{ cents int64 }
+
+func (m *money) Value() (driver.Value, error) {
+	return []byte(fmt.Sprintf("%d.%02d", m.cents/100, m.cents%100)), nil
+}
+
+type brokenValuer struct{}
+
+var errBrokenValuer = errors.New("no value")
+
+func (brokenValuer) Value() (driver.Value, error) { return nil, errBrokenValuer }
+
+func TestDriverValuer(t *testing.T) {
+	converter := getConverter(t)
+	converter.NullString = "NULL"
+	converter.Headers = []string{"id", "price", "missing"}
+	converter.SetRawRowPreProcessor(func(values []any, _ []string) (bool, []any) {
+		return true, []any{userID(7), &money{cents: 1234}, (*money)(nil)}
+	})
+
+	expected := "id,price,missing\n70,12.34,NULL\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+}
+
+func TestDriverValuerError(t *testing.T) {
+	converter := getConverter(t)
+	converter.SetRawRowPreProcessor(func(values []any, _ []string) (bool, []any) {
+		values[1] = brokenValuer{}
+		return true, values
+	})
+
+	_, err := converter.WriteString()
+	if !errors.Is(err, errBrokenValuer) {
+		t.Fatalf("expected the Value error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `"age"`) || !strings.Contains(err.Error(), "row 1") {
+		t.Errorf("expected error to mention column and row, got %v", err)
+	}
+}
+
+func TestRowsWritten(t *testing.T) {
 	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=Bob,age=?,bdate=?,nickname=?", 2, time.Unix(0, 0), nil)
+	exec(t, db, "INSERT|people|name=Carol,age=?,bdate=?,nickname=?", 3, time.Unix(0, 0), nil)
 
-	rows, err := db.Query(query)
+	rows, err := db.Query("SELECT|people|name,age|")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+
+	converter := sqltocsv.New(rows)
+	converter.SetRowPreProcessor(func(row []string, columnNames []string) (bool, []string) {
+		return row[0] != "Bob", row
+	})
+
+	expected := "name,age\nAlice,1\nCarol,3\n"
+	actual, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+
+	assertCsvMatch(t, expected, actual)
+	if converter.RowsWritten() != 2 {
+		t.Errorf("expected 2 rows written, got %d", converter.RowsWritten())
+	}
+}
+
+func TestRowsWrittenWriteFile(t *testing.T) {
+	converter := getConverter(t)
+
+	err := converter.WriteFile(filepath.Join(t.TempDir(), "test.csv"))
+	if err != nil {
+		t.Fatalf("error in WriteFile: %v", err)
+	}
+
+	if converter.RowsWritten() != 1 {
+		t.Errorf("expected 1 row written, got %d", converter.RowsWritten())
+	}
+}
+
+func TestSetProgressCallback(t *testing.T) {
+	db := setupDatabase(t)
+	for i := 2; i <= 5; i++ {
+		exec(t, db, "INSERT|people|name=Bob,age=?,bdate=?,nickname=?", i, time.Unix(0, 0), nil)
+	}
+
+	rows, err := db.Query("SELECT|people|name,age|")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+
+	converter := sqltocsv.New(rows)
+	converter.SetRowPreProcessor(func(row []string, columnNames []string) (bool, []string) {
+		return row[1] != "3", row
+	})
+	var calls []int64
+	converter.SetProgressCallback(2, func(rowsWritten int64) {
+		calls = append(calls, rowsWritten)
+	})
+
+	if _, err := converter.WriteString(); err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+
+	if fmt.Sprint(calls) != "[2 4 4]" {
+		t.Errorf("unexpected progress calls %v", calls)
+	}
+}
+
+func TestSetProgressCallbackEveryZero(t *testing.T) {
+	converter := getConverter(t)
+
+	var calls []int64
+	converter.SetProgressCallback(0, func(rowsWritten int64) {
+		calls = append(calls, rowsWritten)
+	})
+
+	if _, err := converter.WriteString(); err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+
+	if fmt.Sprint(calls) != "[1]" {
+		t.Errorf("unexpected progress calls %v", calls)
+	}
+}
+
+func TestSetHeaderTransform(t *testing.T) {
+	converter := getConverter(t)
+
+	var seenColumns []string
+	converter.SetHeaderTransform(strings.ToUpper)
+	converter.SetRowPreProcessor(func(row []string, columnNames []string) (bool, []string) {
+		seenColumns = columnNames
+		return true, row
+	})
+
+	expected := "NAME,AGE,BDATE\nAlice,1,1973-11-29T21:33:09Z\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+	if strings.Join(seenColumns, ",") != "name,age,bdate" {
+		t.Errorf("preprocessor saw columns %v", seenColumns)
+	}
+}
+
+func TestSetHeaderTransformWithHeaders(t *testing.T) {
+	converter := getConverter(t)
+
+	converter.SetHeaderTransform(strings.ToUpper)
+	converter.Headers = []string{"Name", "Age", "Birthday"}
+
+	expected := "Name,Age,Birthday\nAlice,1,1973-11-29T21:33:09Z\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+}
+
+func TestSetRawRowPreProcessor(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=Bob,age=?,bdate=?,nickname=?", 2, time.Unix(0, 0), nil)
+	exec(t, db, "INSERT|people|name=Carol,age=?,bdate=?,nickname=?", 3, time.Unix(0, 0), nil)
+
+	rows, err := db.Query("SELECT|people|name,age|")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+
+	converter := sqltocsv.New(rows)
+	converter.SetRawRowPreProcessor(func(values []any, columnNames []string) (bool, []any) {
+		age := values[1].(int64)
+		if age == 2 {
+			return false, nil
+		}
+		return true, []any{values[0], age * 10}
+	})
+	converter.SetRowPreProcessor(func(row []string, columnNames []string) (bool, []string) {
+		return true, []string{strings.ToUpper(row[0]), row[1]}
+	})
+
+	expected := "name,age\nALICE,10\nCAROL,30\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+}
+
+func TestRowWidthMismatch(t *testing.T) {
+	converter := getConverter(t)
+	converter.SetRowPreProcessor(func(row []string, columnNames []string) (bool, []string) {
+		return true, row[:2]
+	})
+
+	_, err := converter.WriteString()
+	if err == nil || !strings.Contains(err.Error(), "row 1 has 2 fields but there are 3 headers") {
+		t.Errorf("expected row width error, got %v", err)
+	}
+}
+
+func TestAllowRaggedRows(t *testing.T) {
+	converter := getConverter(t)
+	converter.AllowRaggedRows = true
+	converter.SetRowPreProcessor(func(row []string, columnNames []string) (bool, []string) {
+		return true, row[:2]
+	})
+
+	expected := "name,age,bdate\nAlice,1\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+}
+
+func TestConvertingNullTypes(t *testing.T) {
+	bdate := time.Unix(123456789, 0)
+	nullValues := []any{
+		sql.NullString{String: "foo", Valid: true}, sql.NullString{},
+		sql.NullInt64{Int64: 64, Valid: true}, sql.NullInt64{},
+		sql.NullInt32{Int32: 32, Valid: true}, sql.NullInt32{},
+		sql.NullInt16{Int16: 16, Valid: true}, sql.NullInt16{},
+		sql.NullByte{Byte: 8, Valid: true}, sql.NullByte{},
+		sql.NullFloat64{Float64: 1.5, Valid: true}, sql.NullFloat64{},
+		sql.NullBool{Bool: true, Valid: true}, sql.NullBool{},
+		sql.NullTime{Time: bdate, Valid: true}, sql.NullTime{},
+	}
+
+	var converted []string
+	for _, value := range nullValues {
+		converter := sqltocsv.New(getTestRowsByQuery(t, "SELECT|people|name|"))
+		converter.WriteHeaders = false
+		converter.NullString = "NULL"
+		converter.TimeFormat = time.Kitchen
+		converter.SetRawRowPreProcessor(func([]any, []string) (bool, []any) {
+			return true, []any{value}
+		})
+
+		csv, err := converter.WriteString()
+		if err != nil {
+			t.Fatalf("error in WriteString: %v", err)
+		}
+		converted = append(converted, strings.TrimSuffix(csv, "\n"))
+	}
+
+	expected := "foo,NULL,64,NULL,32,NULL,16,NULL,8,NULL,1.5,NULL,true,NULL,9:33PM,NULL"
+	if actual := strings.Join(converted, ","); actual != expected {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestTimeLocation(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("error loading location: %v", err)
+	}
+
+	converter := getConverter(t)
+	converter.TimeLocation = moscow
+
+	expected := "name,age,bdate\nAlice,1,1973-11-30T00:33:09+03:00\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+
+	converter = getConverter(t)
+	converter.TimeLocation = moscow
+	converter.TimeFormat = "2006-01-02 15:04 MST"
+
+	expected = "name,age,bdate\nAlice,1,1973-11-30 00:33 MSK\n"
+	actual = converter.String()
+
+	assertCsvMatch(t, expected, actual)
+}
+
+func TestTimeFormats(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "CREATE|events|name=string,day=datetime,at=datetime")
+	exec(t, db, "INSERT|events|name=launch,day=?,at=?", time.Unix(123456789, 0), time.Unix(123456789, 0))
+
+	rows, err := db.Query("SELECT|events|name,day,at|")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+
+	converter := sqltocsv.New(rows)
+	converter.Headers = []string{"Name", "Day", "At"}
+	converter.TimeFormat = time.Kitchen
+	converter.TimeFormats = map[string]string{
+		"day":     time.DateOnly,
+		"Day":     time.RFC822,
+		"missing": time.RFC850,
+	}
+
+	expected := "Name,Day,At\nlaunch,1973-11-29,9:33PM\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+}
+
+func TestBoolStrings(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "CREATE|flags|name=string,on=bool,maybe=nullbool")
+	exec(t, db, "INSERT|flags|name=a,on=?,maybe=?", true, false)
+	exec(t, db, "INSERT|flags|name=b,on=?,maybe=?", false, nil)
+
+	query := func() *sql.Rows {
+		rows, err := db.Query("SELECT|flags|name,on,maybe|")
+		if err != nil {
+			t.Fatalf("error querying: %v", err)
+		}
+		return rows
+	}
+
+	assertCsvMatch(t, "name,on,maybe\na,true,false\nb,false,\n", sqltocsv.New(query()).String())
+
+	converter := sqltocsv.New(query())
+	converter.TrueString = "Yes"
+	converter.FalseString = "No"
+	assertCsvMatch(t, "name,on,maybe\na,Yes,No\nb,No,\n", converter.String())
+
+	converter = sqltocsv.New(query())
+	converter.TrueString = "1"
+	converter.FalseString = "0"
+	converter.SetRawRowPreProcessor(func(values []any, columnNames []string) (bool, []any) {
+		valid := values[2] != nil
+		maybe, _ := values[2].(bool)
+		return true, []any{values[0], values[1], sql.NullBool{Bool: maybe, Valid: valid}}
+	})
+	assertCsvMatch(t, "name,on,maybe\na,1,0\nb,0,\n", converter.String())
+}
+
+func TestFloatFormats(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "CREATE|shops|name=string,price=float64,lat=float64,rating=float64")
+	exec(t, db, "INSERT|shops|name=corner,price=?,lat=?,rating=?", 4.5, -33.865143, 4.25)
+
+	rows, err := db.Query("SELECT|shops|name,price,lat,rating|")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+
+	converter := sqltocsv.New(rows)
+	converter.FloatFormat = "%.1f"
+	converter.FloatFormats = map[string]string{
+		"price": "%.2f",
+		"lat":   "%v",
+	}
+
+	expected := "name,price,lat,rating\ncorner,4.50,-33.865143,4.2\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+}
+
+func TestBinaryConverters(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "CREATE|documents|body=blob,signature=blob")
+	exec(t, db, "INSERT|documents|body=hello world,signature=sig")
+
+	rows, err := db.Query("SELECT|documents|body,signature|")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+
+	converter := sqltocsv.New(rows)
+	converter.BinaryConverters = map[string]sqltocsv.BinaryConverter{
+		"signature": sqltocsv.StdBase64,
+	}
+
+	expected := "body,signature\nhello world,c2ln\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+}
+
+func TestUUIDCanonical(t *testing.T) {
+	id := []byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+	source := &sliceSource{
+		columns: []string{"id", "blob"},
+		rows: [][]any{
+			{id, id},
+			{[]byte("123e4567-e89b-12d3-a456-426614174000"), []byte("short")},
+		},
+	}
+
+	converter := sqltocsv.NewFromSource(source)
+	converter.BinaryConverter = sqltocsv.Hex
+	converter.BinaryConverters = map[string]sqltocsv.BinaryConverter{"id": sqltocsv.UUIDCanonical}
+
+	expected := "id,blob\n" +
+		"123e4567-e89b-12d3-a456-426614174000,123e4567e89b12d3a456426614174000\n" +
+		"123e4567-e89b-12d3-a456-426614174000,73686f7274\n"
+	assertCsvMatch(t, expected, converter.String())
+}
+
+func TestSkipEmptyRows(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "", 2, time.Unix(0, 0), nil)
+
+	query := "SELECT|people|nickname,name|"
+
+	converter := sqltocsv.New(queryTestRows(t, db, query))
+	converter.SkipEmptyRows = true
+	var calls []int64
+	converter.SetProgressCallback(1, func(rowsWritten int64) {
+		calls = append(calls, rowsWritten)
+	})
+
+	assertCsvMatch(t, "nickname,name\n,Alice\n", converter.String())
+	if converter.RowsWritten() != 1 {
+		t.Errorf("expected 1 row written, got %d", converter.RowsWritten())
+	}
+	if fmt.Sprint(calls) != "[1 1]" {
+		t.Errorf("unexpected progress calls %v", calls)
+	}
+
+	converter = sqltocsv.New(queryTestRows(t, db, query))
+	converter.SkipEmptyRows = true
+	converter.NullString = `\N`
+
+	assertCsvMatch(t, "nickname,name\n\\N,Alice\n\\N,\n", converter.String())
+}
+
+func TestMaxRows(t *testing.T) {
+	db := setupDatabase(t)
+	for _, name := range []string{"Bob", "Carol", "Dave"} {
+		exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", name, 2, time.Unix(0, 0), nil)
+	}
+	query := "SELECT|people|name|"
+
+	converter := sqltocsv.New(queryTestRows(t, db, query))
+	converter.MaxRows = 2
+	converter.SetRowPreProcessor(func(row []string, columnNames []string) (bool, []string) {
+		return row[0] != "Bob", row
+	})
+
+	assertCsvMatch(t, "name\nAlice\nCarol\n", converter.String())
+	if !converter.Truncated() {
+		t.Error("expected output to be truncated")
+	}
+
+	converter = sqltocsv.New(queryTestRows(t, db, query))
+	converter.MaxRows = 4
+	assertCsvMatch(t, "name\nAlice\nBob\nCarol\nDave\n", converter.String())
+	if converter.Truncated() {
+		t.Error("expected output not to be truncated")
+	}
+}
+
+func TestMaxRowsTruncateError(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "Bob", 2, time.Unix(0, 0), nil)
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name|"))
+	converter.MaxRows = 1
+	converter.TruncateError = true
+
+	csv, err := converter.WriteString()
+	if !errors.Is(err, sqltocsv.ErrTruncated) {
+		t.Fatalf("expected ErrTruncated, got %v", err)
+	}
+	assertCsvMatch(t, "name\nAlice\n", csv)
+}
+
+func TestSkipRows(t *testing.T) {
+	db := setupDatabase(t)
+	for _, name := range []string{"Bob", "Carol", "Dave"} {
+		exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", name, 2, time.Unix(0, 0), nil)
+	}
+	query := "SELECT|people|name|"
+
+	converter := sqltocsv.New(queryTestRows(t, db, query))
+	converter.SkipRows = 3
+	var seen []string
+	converter.SetRowPreProcessor(func(row []string, columnNames []string) (bool, []string) {
+		seen = append(seen, row[0])
+		return true, row
+	})
+
+	assertCsvMatch(t, "name\nDave\n", converter.String())
+	if strings.Join(seen, ",") != "Dave" {
+		t.Errorf("preprocessor saw skipped rows: %v", seen)
+	}
+
+	converter = sqltocsv.New(queryTestRows(t, db, query))
+	converter.SkipRows = 1
+	converter.MaxRows = 2
+	converter.WriteHeaders = false
+
+	assertCsvMatch(t, "Bob\nCarol\n", converter.String())
+	if !converter.Truncated() {
+		t.Error("expected output to be truncated")
+	}
+}
+
+func TestSetChecksum(t *testing.T) {
+	converter := getConverter(t)
+	converter.WriteBOM = true
+	converter.SetChecksum(sha256.New())
+
+	csvFileName := filepath.Join(t.TempDir(), "test.csv")
+	if err := converter.WriteFile(csvFileName); err != nil {
+		t.Fatalf("error in WriteFile: %v", err)
+	}
+
+	data, err := os.ReadFile(csvFileName)
+	if err != nil {
+		t.Fatalf("error reading %v: %v", csvFileName, err)
+	}
+	expected := sha256.Sum256(data)
+	if !bytes.Equal(converter.Checksum(), expected[:]) {
+		t.Errorf("expected checksum %x, got %x", expected, converter.Checksum())
+	}
+}
+
+type writeCounter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *writeCounter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestBufferSize(t *testing.T) {
+	db := setupDatabase(t)
+	for i := 0; i < 200; i++ {
+		exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", strings.Repeat("x", 100), i, time.Unix(0, 0), nil)
+	}
+
+	unbuffered := &writeCounter{}
+	if err := sqltocsv.Write(unbuffered, queryTestRows(t, db, "SELECT|people|name,age|")); err != nil {
+		t.Fatalf("error in Write: %v", err)
+	}
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age|"))
+	converter.BufferSize = 1 << 20
+	buffered := &writeCounter{}
+	if err := converter.Write(buffered); err != nil {
+		t.Fatalf("error in Write: %v", err)
+	}
+
+	assertCsvMatch(t, unbuffered.String(), buffered.String())
+	if buffered.writes != 1 || unbuffered.writes <= 1 {
+		t.Errorf("expected a single write with BufferSize, got %d (and %d without)", buffered.writes, unbuffered.writes)
+	}
+}
+
+func TestFlushEvery(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "Bob", 2, time.Unix(0, 0), nil)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "Carol", 3, time.Unix(0, 0), nil)
+
+	for _, setup := range []func(*sqltocsv.Converter){
+		func(c *sqltocsv.Converter) { c.FlushEvery = 1 },
+		func(c *sqltocsv.Converter) { c.FlushInterval = time.Nanosecond },
+	} {
+		converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age|"))
+		setup(converter)
+		out := &writeCounter{}
+		if err := converter.Write(out); err != nil {
+			t.Fatalf("error in Write: %v", err)
+		}
+
+		assertCsvMatch(t, "name,age\nAlice,1\nBob,2\nCarol,3\n", out.String())
+		if out.writes != 3 {
+			t.Errorf("expected a write per row, got %d", out.writes)
+		}
+	}
+}
+
+func TestWriteReturnsFlushError(t *testing.T) {
+	err := sqltocsv.Write(failingWriter{}, getTestRows(t))
+	if !errors.Is(err, errWriterBroken) {
+		t.Errorf("expected the final flush error, got %v", err)
+	}
+}
+
+func TestNewlineReplacement(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "one\r\ntwo\nthree\rfour", 2, time.Unix(0, 0), "tab\there")
+
+	replacement := `\n`
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,nickname|age=?", 2))
+	converter.NewlineReplacement = &replacement
+	converter.Headers = []string{"multi\nline", "nickname"}
+
+	var seen string
+	converter.SetRowPreProcessor(func(row []string, _ []string) (bool, []string) {
+		seen = row[0]
+		return true, row
+	})
+
+	// tabs are left alone, and the header keeps its newline
+	expected := "\"multi\nline\",nickname\none\\ntwo\\nthree\\nfour,tab\there\n"
+	assertCsvMatch(t, expected, converter.String())
+	if seen != "one\r\ntwo\nthree\rfour" {
+		t.Errorf("expected the preprocessor to see the original value, got %q", seen)
+	}
+}
+
+func TestNewlineReplacementWithTabNewlineReplacement(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "a\tb\r\nc", 2, time.Unix(0, 0), nil)
+
+	space, newline := " ", "|"
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name|age=?", 2))
+	converter.TabNewlineReplacement = &space
+	converter.NewlineReplacement = &newline
+
+	assertCsvMatch(t, "name\na b|c\n", converter.String())
+}
+
+func TestTrimSpace(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "  abc  def  ", 2, time.Unix(0, 0), " \tpadded\n")
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age,nickname|"))
+	converter.TrimSpace = true
+	converter.NullString = " NULL "
+
+	// interior white space, numbers and the NULL placeholder are left alone
+	expected := "name,age,nickname\nAlice,1,\" NULL \"\nabc  def,2,padded\n"
+	assertCsvMatch(t, expected, converter.String())
+}
+
+func TestTrimColumns(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "  abc  ", 2, time.Unix(0, 0), "  padded  ")
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,nickname|age=?", 2))
+	converter.TrimColumns = []string{"nickname"}
+
+	expected := "name,nickname\n\"  abc  \",padded\n"
+	assertCsvMatch(t, expected, converter.String())
+}
+
+func TestMaxFieldLength(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "ünïcödé, ünïcödé", 123456, time.Unix(0, 0), "exactly10!")
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age,nickname|age=?", 123456))
+	converter.MaxFieldLength = 10
+	converter.TruncateMarker = "…"
+	converter.Headers = []string{"a header longer than the limit", "age", "nickname"}
+
+	csv, stats, err := converter.WriteStringWithStats()
+	if err != nil {
+		t.Fatalf("error in WriteStringWithStats: %v", err)
+	}
+
+	// the quotes around the comma aren't counted, and runes aren't split
+	expected := "a header longer than the limit,age,nickname\n\"ünïcödé, …\",123456,exactly10!\n"
+	assertCsvMatch(t, expected, csv)
+	if stats.FieldsTruncated != 1 {
+		t.Errorf("expected 1 truncated field, got %d", stats.FieldsTruncated)
+	}
+}
+
+func TestMaxFieldLengthLongMarker(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "abcdefghij", 2, time.Unix(0, 0), nil)
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name|age=?", 2))
+	converter.MaxFieldLength = 8
+	converter.TruncateMarker = "[truncated]"
+
+	assertCsvMatch(t, "name\nabcdefgh\n", converter.String())
+}
+
+func TestRowNumberHeader(t *testing.T) {
+	db := setupDatabase(t)
+	for i, name := range []string{"Bob", "Carol", "Dave", "Erin"} {
+		exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", name, i+2, time.Unix(0, 0), nil)
+	}
+
+	for _, test := range []struct {
+		last     bool
+		expected string
+	}{
+		{false, "#,Name,Age\n1,Alice,1\n2,Carol,3\n3,Erin,5\n"},
+		{true, "Name,Age,#\nAlice,1,1\nCarol,3,2\nErin,5,3\n"},
+	} {
+		converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age,bdate|"))
+		converter.RowNumberHeader = "#"
+		converter.RowNumberLast = test.last
+		converter.IncludeColumns = []string{"name", "age"}
+		converter.Headers = []string{"Name", "Age"}
+
+		// skipped rows don't use up numbers
+		var columns []string
+		converter.SetRowPreProcessor(func(row []string, columnNames []string) (bool, []string) {
+			columns = columnNames
+			return strings.ContainsAny(row[1], "135"), row // odd ages
+		})
+
+		csv, stats, err := converter.WriteStringWithStats()
+		if err != nil {
+			t.Fatalf("error in WriteStringWithStats: %v", err)
+		}
+		assertCsvMatch(t, test.expected, csv)
+		if strings.Join(columns, ",") != "name,age" {
+			t.Errorf("expected the preprocessor not to see the row number, got columns %q", columns)
+		}
+		if stats.Columns != 3 {
+			t.Errorf("expected 3 columns, got %d", stats.Columns)
+		}
+	}
+}
+
+func TestStaticColumns(t *testing.T) {
+	static := []sqltocsv.StaticColumn{{Name: "source_system", Value: "  =billing  "}, {Name: "export_date", Value: "2024-02-29"}}
+
+	converter := sqltocsv.New(getTestRowsByQuery(t, "SELECT|people|name,age|"))
+	converter.StaticColumns = static
+	// none of these apply to the static values
+	converter.SanitizeFormulas = true
+	converter.TrimSpace = true
+	converter.MaxFieldLength = 10
+
+	var width int
+	converter.SetRowPreProcessor(func(row []string, _ []string) (bool, []string) {
+		width = len(row)
+		return true, row
+	})
+
+	expected := "name,age,source_system,export_date\nAlice,1,\"  =billing  \",2024-02-29\n"
+	assertCsvMatch(t, expected, converter.String())
+	if width != 2 {
+		t.Errorf("expected the preprocessor to see 2 fields, got %d", width)
+	}
+
+	converter = sqltocsv.New(getTestRowsByQuery(t, "SELECT|people|name,age|"))
+	converter.StaticColumns = static
+	converter.StaticColumnsFirst = true
+	converter.RowNumberHeader = "#"
+
+	expected = "#,source_system,export_date,name,age\n1,\"  =billing  \",2024-02-29,Alice,1\n"
+	assertCsvMatch(t, expected, converter.String())
+}
+
+func TestAddComputedColumn(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "Robert", 42, time.Unix(0, 0), "Bob")
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age,nickname|"))
+	converter.ExcludeColumns = []string{"nickname"}
+	converter.NullString = "-"
+	converter.AddComputedColumn("display_name", func(values map[string]string) (string, error) {
+		return values["name"] + " (" + values["nickname"] + ")", nil
+	})
+	converter.AddComputedColumn("decade", func(values map[string]string) (string, error) {
+		age, err := strconv.Atoi(values["age"])
+		return strconv.Itoa(age/10*10) + "s", err
+	})
+
+	calls := 0
+	converter.SetRowPreProcessor(func(row []string, _ []string) (bool, []string) {
+		calls++
+		return row[0] != "Alice", row
+	})
+
+	// the excluded column is still there for the computed columns
+	expected := "name,age,display_name,decade\nRobert,42,Robert (Bob),40s\n"
+	assertCsvMatch(t, expected, converter.String())
+	if calls != 2 {
+		t.Errorf("expected the preprocessor to be called twice, got %d", calls)
+	}
+}
+
+func TestAddComputedColumnFirst(t *testing.T) {
+	converter := getConverter(t)
+	converter.ComputedColumnsFirst = true
+	converter.StaticColumns = []sqltocsv.StaticColumn{{Name: "source", Value: "test"}}
+	converter.StaticColumnsFirst = true
+	converter.AddComputedColumn("initial", func(values map[string]string) (string, error) {
+		return values["name"][:1], nil
+	})
+
+	expected := "source,initial,name,age,bdate\ntest,A,Alice,1,1973-11-29T21:33:09Z\n"
+	assertCsvMatch(t, expected, converter.String())
+}
+
+func TestAddComputedColumnError(t *testing.T) {
+	converter := getConverter(t)
+	errCompute := errors.New("can't compute")
+	converter.AddComputedColumn("broken", func(map[string]string) (string, error) {
+		return "", errCompute
+	})
+
+	_, err := converter.WriteString()
+	if !errors.Is(err, errCompute) {
+		t.Fatalf("expected the computed column's error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `column "broken" in row 1`) {
+		t.Errorf("expected the error to say where it happened, got %v", err)
+	}
+}
+
+func TestHeaderMap(t *testing.T) {
+	converter := sqltocsv.New(getTestRowsByQuery(t, "SELECT|people|name,age,bdate,nickname|"))
+	converter.ExcludeColumns = []string{"nickname"}
+	converter.HeaderMap = map[string]string{"bdate": "Birth Date", "nickname": "Nick"}
+	converter.SetHeaderTransform(strings.ToUpper)
+
+	// columns the map doesn't rename still go through the transform, and a
+	// name that's in the result set but not the output is fine too
+	converter.StrictHeaderMap = true
+	expected := "NAME,AGE,Birth Date\nAlice,1,1973-11-29T21:33:09Z\n"
+	assertCsvMatch(t, expected, converter.String())
+}
+
+func TestHeaderMapErrors(t *testing.T) {
+	converter := getConverter(t)
+	converter.HeaderMap = map[string]string{"name": "Name"}
+	converter.Headers = []string{"a", "b", "c"}
+	if _, err := converter.WriteString(); err == nil {
+		t.Error("expected error for Headers and HeaderMap both being set")
+	}
+
+	converter = getConverter(t)
+	converter.HeaderMap = map[string]string{"nmae": "Name"}
+	assertCsvMatch(t, "name,age,bdate\nAlice,1,1973-11-29T21:33:09Z\n", converter.String())
+
+	converter = getConverter(t)
+	converter.HeaderMap = map[string]string{"nmae": "Name"}
+	converter.StrictHeaderMap = true
+	if _, err := converter.WriteString(); err == nil || !strings.Contains(err.Error(), `"nmae"`) {
+		t.Errorf("expected error naming the unknown column, got %v", err)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	db := setupDatabase(t)
+	for _, name := range []string{"Bob", "Carol"} {
+		exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", name, 2, time.Unix(0, 0), nil)
+	}
+	query := "SELECT|people|name,age|"
+
+	converter := sqltocsv.New(queryTestRows(t, db, query))
+	converter.Delimiter = ';'
+	converter.QuoteAll = true
+	converter.MaxRows = 10
+	converter.TruncateError = true
+	preview, err := converter.Preview(2)
+	if err != nil {
+		t.Fatalf("error in Preview: %v", err)
+	}
+	assertCsvMatch(t, "\"name\";\"age\"\n\"Alice\";\"1\"\n\"Bob\";\"2\"\n", preview)
+	if !converter.Truncated() {
+		t.Error("expected Truncated to report the row left over")
+	}
+
+	converter = sqltocsv.New(queryTestRows(t, db, query))
+	preview, err = converter.Preview(3)
+	if err != nil {
+		t.Fatalf("error in Preview: %v", err)
+	}
+	assertCsvMatch(t, "name,age\nAlice,1\nBob,2\nCarol,2\n", preview)
+	if converter.Truncated() {
+		t.Error("expected no rows to be left over")
+	}
+	if _, err := converter.WriteString(); !errors.Is(err, sqltocsv.ErrRowsConsumed) {
+		t.Errorf("expected ErrRowsConsumed writing the previewed rows, got %v", err)
+	}
+	converter.Reset(queryTestRows(t, db, query))
+	csv, err := converter.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString after Reset: %v", err)
+	}
+	assertCsvMatch(t, "name,age\nAlice,1\nBob,2\nCarol,2\n", csv)
+
+	if _, err := converter.Preview(0); err == nil {
+		t.Error("expected an error previewing no rows")
+	}
+}
+
+// rawJSON marshals to the JSON it holds.
+type rawJSON string
+
+func (r rawJSON) MarshalJSON() ([]byte, error) { return []byte(r), nil }
+
+func TestJSONMarshalerValues(t *testing.T) {
+	source := &sliceSource{
+		columns: []string{"value"},
+		rows: [][]any{
+			{rawJSON(`"he said \"hi\""`)},
+			{rawJSON(`{"a":"b"}`)},
+			{rawJSON(`"\"quoted\""`)},
+			{rawJSON(`42`)},
+			{rawJSON(`null`)},
+			{[]string{"x", `"y"`}},
+		},
+	}
+
+	expected := "value\n" +
+		"\"he said \"\"hi\"\"\"\n" +
+		"\"{\"\"a\"\":\"\"b\"\"}\"\n" +
+		"\"\"\"quoted\"\"\"\n" +
+		"42\n" +
+		"null\n" +
+		"\"[\"\"x\"\",\"\"\\\"\"y\\\"\"\"\"]\"\n"
+	assertCsvMatch(t, expected, sqltocsv.NewFromSource(source).String())
+}
+
+func TestJSONColumns(t *testing.T) {
+	newConverter := func() *sqltocsv.Converter {
+		converter := sqltocsv.NewFromSource(&sliceSource{
+			columns: []string{"doc", "blob"},
+			rows: [][]any{
+				{[]byte(`{ "a": [1, 2],  "b": "c" }`), []byte("hi")},
+				{[]byte(`not json`), []byte("hi")},
+				{nil, nil},
+			},
+		})
+		converter.BinaryConverter = sqltocsv.StdBase64
+		converter.JSONColumns = []string{"doc"}
+		converter.NullString = "NULL"
+		return converter
+	}
+
+	converter := newConverter()
+	expected := "doc,blob\n" +
+		"\"{ \"\"a\"\": [1, 2],  \"\"b\"\": \"\"c\"\" }\",aGk=\n" +
+		"not json,aGk=\n" +
+		"NULL,NULL\n"
+	assertCsvMatch(t, expected, converter.String())
+
+	converter = newConverter()
+	converter.CompactJSON = true
+	expected = "doc,blob\n" +
+		"\"{\"\"a\"\":[1,2],\"\"b\"\":\"\"c\"\"}\",aGk=\n" +
+		"not json,aGk=\n" +
+		"NULL,NULL\n"
+	assertCsvMatch(t, expected, converter.String())
+
+	converter = newConverter()
+	converter.StrictJSON = true
+	if _, err := converter.WriteString(); err == nil || !strings.Contains(err.Error(), `column "doc" in row 2`) {
+		t.Errorf("expected an error for the invalid JSON in row 2, got %v", err)
+	}
+}
+
+func checkQueryAgainstResult(t *testing.T, innerTestFunc func(*sql.Rows) string) {
+	rows := getTestRows(t)
+
+	expected := "name,age,bdate\nAlice,1,1973-11-29T21:33:09Z\n"
+
+	actual := innerTestFunc(rows)
+
+	assertCsvMatch(t, expected, actual)
+}
+
+func getTestRows(t *testing.T) *sql.Rows {
+	return getTestRowsByQuery(t, "SELECT|people|name,age,bdate|")
+}
+
+func getTestRowsByQuery(t *testing.T, query string) *sql.Rows {
+	db := setupDatabase(t)
+
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+
+	return rows
+}
+
+func queryTestRows(t *testing.T, db *sql.DB, query string, args ...any) *sql.Rows {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+
+	return rows
+}
+
+func getEmptyTestRows(t *testing.T) *sql.Rows {
+	db := setupDatabase(t)
+
+	rows, err := db.Query("SELECT|people|name,age,bdate|name=?", "Nobody")
 	if err != nil {
 		t.Fatalf("error querying: %v", err)
 	}
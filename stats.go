@@ -0,0 +1,68 @@
+package sqltocsv
+
+import (
+	"io"
+	"time"
+)
+
+// Stats summarises an export.
+type Stats struct {
+	RowsWritten     int64         // Data rows written, not counting the header
+	RowsSkipped     int64         // Rows dropped by a preprocessor or SkipEmptyRows
+	RowsFailed      int64         // Rows that failed to scan or convert, skipped by the SetErrorHandler handler
+	BytesWritten    int64         // Bytes written to the destination, after CSV encoding
+	Columns         int           // Number of output columns
+	Duration        time.Duration // Time taken from reading the columns to the final flush
+	FieldsTruncated int64         // Data fields cut short by MaxFieldLength
+	DuplicateRows   int64         // Rows dropped by SkipDuplicateRows or DedupAllRows
+	MaskedColumns   []string      // Query columns whose values were hidden by MaskColumns, in output order
+	HashedColumns   []string      // Query columns whose values were replaced by HashColumns, in output order
+}
+
+// WriteWithStats is like Write but also returns Stats for the export
+func (c Converter) WriteWithStats(writer io.Writer) (Stats, error) {
+	if c.last == nil {
+		c.last = &lastRun{}
+	}
+	err := c.Write(writer)
+	return c.last.stats, err
+}
+
+// WriteFileWithStats is like WriteFile but also returns Stats for the export
+func (c Converter) WriteFileWithStats(csvFileName string) (Stats, error) {
+	if c.last == nil {
+		c.last = &lastRun{}
+	}
+	err := c.WriteFile(csvFileName)
+	return c.last.stats, err
+}
+
+// WriteStringWithStats is like WriteString but also returns Stats for the
+// export
+func (c Converter) WriteStringWithStats() (string, Stats, error) {
+	if c.last == nil {
+		c.last = &lastRun{}
+	}
+	csv, err := c.WriteString()
+	return csv, c.last.stats, err
+}
+
+// DryRun runs the export as Write would, through every conversion,
+// preprocessor and option, but throws the output away, to find out how many
+// rows and bytes it comes to. It reads the rows just as Write does, so they
+// can't be written afterwards; run the query again for the real export.
+func (c Converter) DryRun() (Stats, error) {
+	return c.WriteWithStats(io.Discard)
+}
+
+// countingWriter adds the number of bytes written through it to n.
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	*cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,97 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestWriteWithStats(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "Bob", 2, time.Unix(0, 0), nil)
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age|"))
+	converter.SetRowPreProcessor(func(row []string, columnNames []string) (bool, []string) {
+		return row[0] != "Bob", row
+	})
+
+	buffer := &bytes.Buffer{}
+	stats, err := converter.WriteWithStats(buffer)
+	if err != nil {
+		t.Fatalf("error in WriteWithStats: %v", err)
+	}
+
+	if stats.RowsWritten != 1 || stats.RowsSkipped != 1 || stats.Columns != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats.BytesWritten != int64(buffer.Len()) {
+		t.Errorf("expected %d bytes written, got %d", buffer.Len(), stats.BytesWritten)
+	}
+	if stats.Duration <= 0 {
+		t.Errorf("expected a duration, got %v", stats.Duration)
+	}
+}
+
+func TestWriteFileWithStats(t *testing.T) {
+	converter := getConverter(t)
+	converter.WriteBOM = true
+
+	csvFileName := filepath.Join(t.TempDir(), "test.csv")
+	stats, err := converter.WriteFileWithStats(csvFileName)
+	if err != nil {
+		t.Fatalf("error in WriteFileWithStats: %v", err)
+	}
+
+	if expected := int64(len(readFile(t, csvFileName))); stats.BytesWritten != expected {
+		t.Errorf("expected %d bytes written, got %d", expected, stats.BytesWritten)
+	}
+}
+
+func TestWriteStringWithStats(t *testing.T) {
+	csv, stats, err := getConverter(t).WriteStringWithStats()
+	if err != nil {
+		t.Fatalf("error in WriteStringWithStats: %v", err)
+	}
+
+	if stats.RowsWritten != 1 || stats.BytesWritten != int64(len(csv)) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "Bob", 2, time.Unix(0, 0), nil)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "Carol", 3, time.Unix(0, 0), nil)
+
+	configure := func(converter *sqltocsv.Converter) {
+		converter.MaxRows = 1
+		converter.QuoteAll = true
+		converter.SetRowPreProcessor(func(row []string, columnNames []string) (bool, []string) {
+			return row[0] != "Alice", row
+		})
+	}
+
+	converter := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age|"))
+	configure(converter)
+	stats, err := converter.DryRun()
+	if err != nil {
+		t.Fatalf("error in DryRun: %v", err)
+	}
+
+	written := sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,age|"))
+	configure(written)
+	csv, err := written.WriteString()
+	if err != nil {
+		t.Fatalf("error in WriteString: %v", err)
+	}
+
+	if stats.RowsWritten != 1 || stats.RowsSkipped != 1 || stats.BytesWritten != int64(len(csv)) {
+		t.Errorf("expected the stats of writing %q, got %+v", csv, stats)
+	}
+	if !converter.Truncated() {
+		t.Error("expected MaxRows to truncate the dry run")
+	}
+}
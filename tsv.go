@@ -0,0 +1,34 @@
+package sqltocsv
+
+import (
+	"database/sql"
+)
+
+// WriteTSVFile will write a tab separated file to the file name specified
+// (with headers) based on whatever is in the sql.Rows you pass in.
+func WriteTSVFile(tsvFileName string, rows *sql.Rows) error {
+	return newClosingTSV(rows).WriteFile(tsvFileName)
+}
+
+// WriteTSVString will return a string of the tab separated output. Don't
+// use this unless you've got a small data set or a lot of memory
+func WriteTSVString(rows *sql.Rows) (string, error) {
+	return newClosingTSV(rows).WriteString()
+}
+
+// NewTSV returns a Converter like New does but with tab as the delimiter.
+// Values containing tabs are quoted as usual; set TabNewlineReplacement for
+// consumers that don't understand quoting.
+func NewTSV(rows *sql.Rows) *Converter {
+	c := New(rows)
+	c.Delimiter = '\t'
+	return c
+}
+
+// newClosingTSV is NewTSV for the package's TSV functions, which close the
+// rows once they're done with them.
+func newClosingTSV(rows *sql.Rows) *Converter {
+	c := NewTSV(rows)
+	c.CloseRows = true
+	return c
+}
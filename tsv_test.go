@@ -0,0 +1,74 @@
+package sqltocsv_test
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestWriteTSVString(t *testing.T) {
+	tsv, err := sqltocsv.WriteTSVString(getTestRows(t))
+	if err != nil {
+		t.Fatalf("error in WriteTSVString: %v", err)
+	}
+
+	expected := "name\tage\tbdate\nAlice\t1\t1973-11-29T21:33:09Z\n"
+	assertCsvMatch(t, expected, tsv)
+}
+
+func TestWriteTSVFile(t *testing.T) {
+	tsvFileName := filepath.Join(t.TempDir(), "test.tsv")
+	err := sqltocsv.WriteTSVFile(tsvFileName, getTestRows(t))
+	if err != nil {
+		t.Fatalf("error in WriteTSVFile: %v", err)
+	}
+
+	expected := "name\tage\tbdate\nAlice\t1\t1973-11-29T21:33:09Z\n"
+	assertCsvMatch(t, expected, readFile(t, tsvFileName))
+}
+
+func TestTSVQuotedTab(t *testing.T) {
+	tsv, err := sqltocsv.WriteTSVString(getTabTestRows(t))
+	if err != nil {
+		t.Fatalf("error in WriteTSVString: %v", err)
+	}
+
+	reader := csv.NewReader(strings.NewReader(tsv))
+	reader.Comma = '\t'
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("error reading TSV back: %v", err)
+	}
+	if records[1][0] != "tab\there" {
+		t.Errorf("expected tab to survive the round trip, got %q", records[1][0])
+	}
+}
+
+func TestTSVTabNewlineReplacement(t *testing.T) {
+	converter := sqltocsv.NewTSV(getTabTestRows(t))
+	replacement := " "
+	converter.TabNewlineReplacement = &replacement
+
+	expected := "name\tage\ntab here\t1\n"
+	actual := converter.String()
+
+	assertCsvMatch(t, expected, actual)
+}
+
+func getTabTestRows(t *testing.T) *sql.Rows {
+	db := setupDatabase(t)
+	exec(t, db, "WIPE")
+	exec(t, db, "CREATE|people|name=string,age=int32,bdate=datetime,nickname=nullstring")
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "tab\there", 1, time.Unix(0, 0), nil)
+
+	rows, err := db.Query("SELECT|people|name,age|")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+	return rows
+}
@@ -0,0 +1,96 @@
+package sqltocsv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// UnquotedStrategy is what UnquotedOutput does with values it can't write
+// as they are, because they contain the delimiter or a line break.
+type UnquotedStrategy int
+
+const (
+	// Fail the export with an error, before any of the row is written.
+	UnquotedError UnquotedStrategy = iota + 1
+	// Remove the delimiters and line breaks from the value.
+	UnquotedStrip
+	// Replace each delimiter and line break (a \r\n pair counts as one)
+	// with UnquotedReplacement.
+	UnquotedReplace
+)
+
+// checkUnquoted returns an error if UnquotedOutput is set without a usable
+// UnquotedStrategy, so that a forgotten strategy can't corrupt the output.
+func (c Converter) checkUnquoted() error {
+	if !c.UnquotedOutput {
+		return nil
+	}
+	if c.PreserveNullVsEmpty {
+		return errors.New("UnquotedOutput can't be used with PreserveNullVsEmpty, which quotes empty strings")
+	}
+	switch c.UnquotedStrategy {
+	case UnquotedError, UnquotedStrip:
+		return nil
+	case UnquotedReplace:
+		if strings.Contains(c.UnquotedReplacement, c.delimiter()) || strings.ContainsAny(c.UnquotedReplacement, "\r\n") {
+			return fmt.Errorf("UnquotedReplacement %q can't contain the delimiter or a line break", c.UnquotedReplacement)
+		}
+		return nil
+	case 0:
+		return errors.New("UnquotedOutput needs an UnquotedStrategy for values containing the delimiter or a line break")
+	default:
+		return fmt.Errorf("unknown UnquotedStrategy %d", c.UnquotedStrategy)
+	}
+}
+
+// unquotedReplacer returns the replacer UnquotedStrategy calls for, nil for
+// UnquotedError.
+func (c Converter) unquotedReplacer() *strings.Replacer {
+	var replacement string
+	switch c.UnquotedStrategy {
+	case UnquotedStrip:
+	case UnquotedReplace:
+		replacement = c.UnquotedReplacement
+	default:
+		return nil
+	}
+	return strings.NewReplacer(c.delimiter(), replacement, "\r\n", replacement, "\r", replacement, "\n", replacement)
+}
+
+// writeUnquoted writes a record with its fields joined by the delimiter as
+// they are, for readers that split lines naively. Fields containing the
+// delimiter or a line break go through the replacer, or fail the whole
+// record before any of it is written if there isn't one.
+func (e *encoder) writeUnquoted(record []string) error {
+	fields := record
+	for i, field := range record {
+		if e.containsComma(field) || strings.ContainsAny(field, "\r\n") {
+			if e.replacer == nil {
+				return fmt.Errorf("field %d (%q) contains the delimiter or a line break, which can't be written unquoted", i+1, field)
+			}
+			if &fields[0] == &record[0] {
+				fields = append([]string(nil), record...) // leave the caller's record alone
+			}
+			fields[i] = e.replacer.Replace(field)
+			if e.containsComma(fields[i]) {
+				return fmt.Errorf("field %d (%q) ends with the start of the delimiter, which can't be written unquoted", i+1, field)
+			}
+		}
+	}
+
+	for i, field := range fields {
+		if i > 0 {
+			e.w.WriteString(e.comma)
+		}
+		e.w.WriteString(field)
+	}
+
+	var err error
+	if e.useCRLF {
+		_, err = e.w.WriteString("\r\n")
+	} else {
+		err = e.w.WriteByte('\n')
+	}
+	return err
+}
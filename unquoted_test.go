@@ -0,0 +1,95 @@
+package sqltocsv_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func getUnquotedConverter(t *testing.T) *sqltocsv.Converter {
+	db := setupDatabase(t)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", "Smith, John", 2, time.Unix(0, 0), "line one\r\nline two")
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", `say "hi"`, 3, time.Unix(0, 0), " padded ")
+	return sqltocsv.New(queryTestRows(t, db, "SELECT|people|name,nickname|"))
+}
+
+func TestUnquotedOutput(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		strategy    sqltocsv.UnquotedStrategy
+		replacement string
+		expected    string
+	}{
+		{"strip", sqltocsv.UnquotedStrip, "", "name,nickname\nAlice,\nSmith John,line oneline two\nsay \"hi\", padded \n"},
+		{"replace", sqltocsv.UnquotedReplace, "\\n", "name,nickname\nAlice,\nSmith\\n John,line one\\nline two\nsay \"hi\", padded \n"},
+	} {
+		converter := getUnquotedConverter(t)
+		converter.UnquotedOutput = true
+		converter.UnquotedStrategy = test.strategy
+		converter.UnquotedReplacement = test.replacement
+
+		actual, err := converter.WriteString()
+		if err != nil {
+			t.Fatalf("%s: error in WriteString: %v", test.name, err)
+		}
+		assertCsvMatch(t, test.expected, actual)
+
+		// every line splits into the right number of fields
+		for _, line := range strings.Split(strings.TrimSuffix(actual, "\n"), "\n") {
+			if fields := strings.Split(line, ","); len(fields) != 2 {
+				t.Errorf("%s: expected 2 fields in %q, got %d", test.name, line, len(fields))
+			}
+		}
+	}
+}
+
+func TestUnquotedOutputError(t *testing.T) {
+	converter := getUnquotedConverter(t)
+	converter.UnquotedOutput = true
+	converter.UnquotedStrategy = sqltocsv.UnquotedError
+
+	actual, err := converter.WriteString()
+	if err == nil || !strings.Contains(err.Error(), `"Smith, John"`) {
+		t.Fatalf("expected error naming the field, got %v", err)
+	}
+	// none of the failing row was written
+	assertCsvMatch(t, "name,nickname\nAlice,\n", actual)
+}
+
+func TestUnquotedOutputDelimiter(t *testing.T) {
+	converter := getUnquotedConverter(t)
+	converter.UnquotedOutput = true
+	converter.UnquotedStrategy = sqltocsv.UnquotedReplace
+	converter.UnquotedReplacement = ","
+	converter.Delimiter = '\x01'
+	converter.UseCRLF = true
+
+	expected := "name\x01nickname\r\nAlice\x01\r\nSmith, John\x01line one,line two\r\nsay \"hi\"\x01 padded \r\n"
+	assertCsvMatch(t, expected, converter.String())
+}
+
+func TestUnquotedOutputNeedsStrategy(t *testing.T) {
+	for _, setup := range []func(*sqltocsv.Converter){
+		func(c *sqltocsv.Converter) {},
+		func(c *sqltocsv.Converter) { c.UnquotedStrategy = 42 },
+		func(c *sqltocsv.Converter) {
+			c.UnquotedStrategy = sqltocsv.UnquotedReplace
+			c.UnquotedReplacement = ";"
+			c.Delimiter = ';'
+		},
+	} {
+		converter := getConverter(t)
+		converter.UnquotedOutput = true
+		setup(converter)
+
+		actual, err := converter.WriteString()
+		if err == nil {
+			t.Error("expected error for a missing or unusable strategy")
+		}
+		if actual != "" {
+			t.Errorf("expected nothing to be written, got %q", actual)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package sqltocsv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validate checks the options that decide how the CSV is laid out, which
+// Write and the other CSV writers do before writing anything, so that an
+// invalid combination fails the export rather than writing a file that can't
+// be read back. The Delimiter can't be the quote character, a carriage
+// return or newline, NUL or an invalid rune; a Converter not made by New
+// has to have it set. Nor can a DelimiterString contain any of them. The
+// QuoteChar can't be a carriage return or newline, and EscapeMode has to
+// have an EscapeChar that isn't either or part of the delimiter. The
+// PrologueLines can't contain line breaks either, and a DecimalSeparator
+// that is part of the delimiter needs QuoteAll. Options set by ApplyDialect
+// have to still suit the Dialect.
+func (c Converter) Validate() error {
+	if c.DelimiterString != "" {
+		if err := checkDelimiterString(c.DelimiterString, c.quote()); err != nil {
+			return fmt.Errorf("invalid DelimiterString: %w", err)
+		}
+	} else if err := checkDelimiter(c.Delimiter, c.quote()); err != nil {
+		return fmt.Errorf("invalid Delimiter: %w", err)
+	}
+	if quote := c.quote(); quote == '\r' || quote == '\n' {
+		return fmt.Errorf("invalid QuoteChar: %q can't be a carriage return or newline", quote)
+	}
+	for i, line := range c.PrologueLines {
+		if strings.ContainsAny(line, "\r\n") {
+			return fmt.Errorf("prologue line %d (%q) contains a line break", i+1, line)
+		}
+	}
+	if err := c.checkDialect(); err != nil {
+		return err
+	}
+	if err := c.checkDecimalSeparator(); err != nil {
+		return err
+	}
+	if err := c.checkEscape(); err != nil {
+		return err
+	}
+	return c.checkUnquoted()
+}
+
+// checkDelimiterString is checkDelimiter for a DelimiterString.
+func checkDelimiterString(s string, quote rune) error {
+	for _, r := range s {
+		if err := checkDelimiter(r, quote); err != nil {
+			return err
+		}
+	}
+	return nil
+}
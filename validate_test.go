@@ -0,0 +1,46 @@
+package sqltocsv_test
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	converter := getConverter(t)
+	if err := converter.Validate(); err != nil {
+		t.Errorf("expected the defaults to be valid, got %v", err)
+	}
+
+	for _, delimiter := range []rune{'"', '\r', '\n', 0, -1} {
+		converter.Delimiter = delimiter
+		if err := converter.Validate(); err == nil {
+			t.Errorf("expected an error for delimiter %q", delimiter)
+		}
+	}
+
+	converter.Delimiter = '"'
+	converter.QuoteChar = '\''
+	if err := converter.Validate(); err != nil {
+		t.Errorf("expected a double quote delimiter to be valid with another QuoteChar, got %v", err)
+	}
+	converter.Delimiter = '\''
+	if err := converter.Validate(); err == nil {
+		t.Error("expected an error for a delimiter the same as the QuoteChar")
+	}
+	converter.Delimiter, converter.QuoteChar = ',', '\n'
+	if err := converter.Validate(); err == nil {
+		t.Error("expected an error for a newline QuoteChar")
+	}
+}
+
+func TestWriteInvalidDelimiter(t *testing.T) {
+	converter := getConverter(t)
+	converter.Delimiter = '"'
+	var out bytes.Buffer
+	if err := converter.Write(&out); err == nil {
+		t.Fatal("expected an error for a quote delimiter")
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected nothing to be written, got %q", out.String())
+	}
+}
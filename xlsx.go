@@ -0,0 +1,328 @@
+package sqltocsv
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WriteXlsxFile writes the rows to the filename specified as an Excel
+// workbook with a single sheet, return an error if problem. The sheet is
+// streamed straight into the zip archive so memory use doesn't grow with the
+// size of the result set. Numbers and booleans become numeric and boolean
+// cells, and times become real dates displayed using the TimeFormat of their
+// column, from TimeFormats or else TimeFormat, or numbers in columns whose
+// TimeFormat is one of the epoch formats such as EpochSeconds.
+func (c Converter) WriteXlsxFile(xlsxFileName, sheetName string) (err error) {
+	defer c.closeRows(&err)
+
+	f, err := c.createFile(xlsxFileName)
+	if err != nil {
+		return err
+	}
+
+	err = c.writeXlsx(f, sheetName)
+	if err != nil {
+		f.abort() // close, but only return/handle the write error
+		return err
+	}
+
+	return f.commit()
+}
+
+func (c Converter) writeXlsx(writer io.Writer, sheetName string) error {
+	if err := validateSheetName(sheetName); err != nil {
+		return err
+	}
+
+	p, err := c.newPlan()
+	if err != nil {
+		return err
+	}
+	p.keepValues = true
+
+	styles, formats := c.xlsxDateStyles(p)
+
+	zipWriter := zip.NewWriter(writer)
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, xmlEscape(sheetName))},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyleSheet(formats)},
+	}
+	for _, part := range parts {
+		partWriter, err := zipWriter.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(partWriter, part.content); err != nil {
+			return err
+		}
+	}
+
+	sheetWriter, err := zipWriter.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	sheet := bufio.NewWriter(sheetWriter)
+	sheet.WriteString(xml.Header)
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	if c.WriteHeaders {
+		c.writeXlsxRow(sheet, styles, p.headers, nil)
+	}
+	err = c.eachRow(context.Background(), p, func(row []string, values []any) error {
+		c.writeXlsxRow(sheet, styles, row, values)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sheet.WriteString(`</sheetData></worksheet>`)
+	if err = sheet.Flush(); err != nil {
+		return err
+	}
+	return zipWriter.Close()
+}
+
+// writeXlsxRow writes a <row> element, using values (if not nil) to pick
+// the cell type for each field and styles, from xlsxDateStyles, to pick the
+// date style of time cells.
+func (c Converter) writeXlsxRow(sheet *bufio.Writer, styles []int, row []string, values []any) {
+	sheet.WriteString("<row>")
+	for i, field := range row {
+		var value any = field
+		if values != nil {
+			value = values[i]
+		}
+
+		switch val := value.(type) {
+		case nil:
+			sheet.WriteString("<c/>")
+		case bool:
+			if val {
+				sheet.WriteString(`<c t="b"><v>1</v></c>`)
+			} else {
+				sheet.WriteString(`<c t="b"><v>0</v></c>`)
+			}
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			sheet.WriteString("<c><v>" + plainNumber(val, field) + "</v></c>")
+		case float32:
+			writeXlsxFloat(sheet, float64(val), field)
+		case float64:
+			writeXlsxFloat(sheet, val, field)
+		case time.Time:
+			style := 1
+			if i < len(styles) {
+				style = styles[i]
+			}
+			if style == 0 {
+				// an epoch TimeFormat, already written as a number
+				sheet.WriteString("<c><v>" + field + "</v></c>")
+				break
+			}
+			if c.TimeLocation != nil {
+				val = val.In(c.TimeLocation)
+			}
+			sheet.WriteString(`<c s="` + strconv.Itoa(style) + `"><v>` + strconv.FormatFloat(excelSerial(val), 'f', -1, 64) + "</v></c>")
+		default:
+			writeXlsxString(sheet, field)
+		}
+	}
+	sheet.WriteString("</row>")
+}
+
+func writeXlsxFloat(sheet *bufio.Writer, val float64, field string) {
+	if math.IsNaN(val) || math.IsInf(val, 0) {
+		writeXlsxString(sheet, field)
+		return
+	}
+	sheet.WriteString("<c><v>" + strconv.FormatFloat(val, 'g', -1, 64) + "</v></c>")
+}
+
+func writeXlsxString(sheet *bufio.Writer, field string) {
+	sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+	xml.EscapeText(sheet, []byte(field))
+	sheet.WriteString("</t></is></c>")
+}
+
+// excelEpoch is day zero for Excel's serial dates, chosen so that dates from
+// March 1900 onwards line up despite Excel treating 1900 as a leap year.
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// excelSerial converts t to Excel's serial date using its wall clock time,
+// since Excel has no notion of time zones.
+func excelSerial(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return wall.Sub(excelEpoch).Hours() / 24
+}
+
+// goToExcelTime maps Go time layout elements to Excel number format codes,
+// longest first so that e.g. "2006" is matched before "2". Elements mapped
+// to "" (time zones) can't be represented and are dropped.
+var goToExcelTime = []struct{ layout, excel string }{
+	{"January", "mmmm"}, {"Monday", "dddd"}, {"Jan", "mmm"}, {"Mon", "ddd"}, {"MST", ""},
+	{"2006", "yyyy"}, {"-07:00:00", ""}, {"Z07:00:00", ""}, {"-07:00", ""}, {"Z07:00", ""},
+	{"-0700", ""}, {"Z0700", ""}, {"-07", ""}, {"Z07", ""},
+	{"01", "mm"}, {"02", "dd"}, {"03", "hh"}, {"04", "mm"}, {"05", "ss"}, {"06", "yy"},
+	{"_2", "d"}, {"15", "hh"}, {"PM", "AM/PM"}, {"pm", "AM/PM"},
+	{"1", "m"}, {"2", "d"}, {"3", "h"}, {"4", "m"}, {"5", "s"},
+}
+
+// excelTimeFormat translates a Go time layout into an Excel number format
+// code, falling back to an ISO style date and time for an empty layout.
+func excelTimeFormat(layout string) string {
+	if layout == "" {
+		return "yyyy-mm-dd hh:mm:ss"
+	}
+
+	var format strings.Builder
+	for len(layout) > 0 {
+		if n := fractionalSecondsLength(layout); n > 0 {
+			format.WriteString("." + strings.Repeat("0", min(n-1, 3)))
+			layout = layout[n:]
+			continue
+		}
+
+		matched := false
+		for _, element := range goToExcelTime {
+			if strings.HasPrefix(layout, element.layout) {
+				format.WriteString(element.excel)
+				layout = layout[len(element.layout):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		ch := layout[0]
+		if strings.IndexByte(" -/:.,()", ch) < 0 {
+			format.WriteByte('\\')
+		}
+		format.WriteByte(ch)
+		layout = layout[1:]
+	}
+	return strings.TrimSpace(format.String())
+}
+
+// fractionalSecondsLength returns the length of a ".000" or ".999" style
+// element at the start of layout, or 0 if there isn't one.
+func fractionalSecondsLength(layout string) int {
+	if len(layout) < 2 || (layout[0] != '.' && layout[0] != ',') {
+		return 0
+	}
+	digit := layout[1]
+	if digit != '0' && digit != '9' {
+		return 0
+	}
+	n := 1
+	for n < len(layout) && layout[n] == digit {
+		n++
+	}
+	return n
+}
+
+func validateSheetName(name string) error {
+	if name == "" || len([]rune(name)) > 31 {
+		return fmt.Errorf("sheet name %q must be between 1 and 31 characters", name)
+	}
+	if strings.ContainsAny(name, `[]:*?/\`) {
+		return fmt.Errorf("sheet name %q can't contain any of []:*?/\\", name)
+	}
+	return nil
+}
+
+func xmlEscape(s string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(s)) // writing to a strings.Builder can't fail
+	return escaped.String()
+}
+
+const xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const xlsxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// xlsxDateStyles picks the style of the time cells of each output column:
+// 0 for a column whose TimeFormat is one of the epoch formats, written as a
+// plain number, or else one date style for each distinct TimeFormat, style
+// 1 being the Converter's own. The number formats of the date styles are
+// returned in order for xlsxStyleSheet.
+func (c Converter) xlsxDateStyles(p *plan) (styles []int, formats []string) {
+	// an epoch TimeFormat has no Excel equivalent, but the columns that
+	// override it with TimeFormats still need a date format
+	layout := c.TimeFormat
+	if isEpochFormat(layout) {
+		layout = ""
+	}
+	formats = []string{excelTimeFormat(layout)}
+
+	styles = make([]int, len(p.headers))
+	for i := range styles {
+		layout := c.TimeFormat
+		if n := p.column(i); n >= 0 {
+			layout = p.perColumn[n].TimeFormat
+		}
+		if isEpochFormat(layout) {
+			continue
+		}
+		format := excelTimeFormat(layout)
+		n := slices.Index(formats, format)
+		if n < 0 {
+			n = len(formats)
+			formats = append(formats, format)
+		}
+		styles[i] = n + 1
+	}
+	return styles, formats
+}
+
+// xlsxStyleSheet returns the styles part defining the default cell style
+// and, from style 1 on, a date style for each of the number formats.
+func xlsxStyleSheet(formats []string) string {
+	var numFmts, cellXfs strings.Builder
+	for i, format := range formats {
+		fmt.Fprintf(&numFmts, `<numFmt numFmtId="%d" formatCode="%s"/>`, 164+i, xmlEscape(format))
+		fmt.Fprintf(&cellXfs, `<xf numFmtId="%d" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>`, 164+i)
+	}
+	return fmt.Sprintf(xlsxStyles, len(formats), numFmts.String(), len(formats)+1, cellXfs.String())
+}
+
+// xlsxStyles is the styles part, filled in by xlsxStyleSheet.
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="%d">%s</numFmts>` +
+	`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="%d"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`%s</cellXfs>` +
+	`</styleSheet>`
@@ -0,0 +1,187 @@
+package sqltocsv_test
+
+import (
+	"archive/zip"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestWriteXlsxFile(t *testing.T) {
+	converter := sqltocsv.New(getTestRowsByQuery(t, "SELECT|people|name,nickname,age,bdate|"))
+	converter.TimeFormat = "2006-01-02 15:04"
+
+	xlsxFileName := filepath.Join(t.TempDir(), "test.xlsx")
+	if err := converter.WriteXlsxFile(xlsxFileName, "People & Co"); err != nil {
+		t.Fatalf("error in WriteXlsxFile: %v", err)
+	}
+
+	sheet := readXlsxPart(t, xlsxFileName, "xl/worksheets/sheet1.xml")
+	expectedRows := `<row>` +
+		`<c t="inlineStr"><is><t xml:space="preserve">name</t></is></c>` +
+		`<c t="inlineStr"><is><t xml:space="preserve">nickname</t></is></c>` +
+		`<c t="inlineStr"><is><t xml:space="preserve">age</t></is></c>` +
+		`<c t="inlineStr"><is><t xml:space="preserve">bdate</t></is></c>` +
+		`</row><row>` +
+		`<c t="inlineStr"><is><t xml:space="preserve">Alice</t></is></c>` +
+		`<c/>` +
+		`<c><v>1</v></c>` +
+		`<c s="1"><v>26997.898020833334</v></c>` +
+		`</row>`
+	if !strings.Contains(sheet, expectedRows) {
+		t.Errorf("unexpected sheet contents:\n%v", sheet)
+	}
+
+	styles := readXlsxPart(t, xlsxFileName, "xl/styles.xml")
+	if !strings.Contains(styles, `formatCode="yyyy-mm-dd hh:mm"`) {
+		t.Errorf("expected date format in styles:\n%v", styles)
+	}
+
+	workbook := readXlsxPart(t, xlsxFileName, "xl/workbook.xml")
+	if !strings.Contains(workbook, `name="People &amp; Co"`) {
+		t.Errorf("expected escaped sheet name in workbook:\n%v", workbook)
+	}
+}
+
+func TestWriteXlsxFileInvalidSheetName(t *testing.T) {
+	xlsxFileName := filepath.Join(t.TempDir(), "test.xlsx")
+	if err := getConverter(t).WriteXlsxFile(xlsxFileName, "a/b"); err == nil {
+		t.Error("expected error for invalid sheet name")
+	}
+}
+
+func TestWriteXlsxFileConstantMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large export in short mode")
+	}
+
+	db := setupDatabase(t)
+	name := strings.Repeat("x", 200)
+	for i := 0; i < 100000; i++ {
+		exec(t, db, "INSERT|people|name=?,age=?,bdate=?,nickname=?", name, i, time.Unix(0, 0), nil)
+	}
+	rows, err := db.Query("SELECT|people|name,age,bdate|")
+	if err != nil {
+		t.Fatalf("error querying: %v", err)
+	}
+
+	var first, peak uint64
+	converter := sqltocsv.New(rows)
+	converter.SetProgressCallback(10000, func(rowsWritten int64) {
+		var stats runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+		if first == 0 {
+			first = stats.HeapAlloc
+		}
+		peak = max(peak, stats.HeapAlloc)
+	})
+
+	xlsxFileName := filepath.Join(t.TempDir(), "test.xlsx")
+	if err := converter.WriteXlsxFile(xlsxFileName, "Sheet1"); err != nil {
+		t.Fatalf("error in WriteXlsxFile: %v", err)
+	}
+
+	// the sheet is over 20MB uncompressed, so holding it in memory would
+	// show up well above this
+	if growth := peak - first; growth > 4<<20 {
+		t.Errorf("heap grew by %v bytes during the export", growth)
+	}
+}
+
+func TestWriteXlsxFileNumberFormats(t *testing.T) {
+	converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"id", "amt"}, rows: [][]any{{int64(1234567), 1234567.0}}})
+	converter.NumberFormats = map[string]sqltocsv.NumberFormat{
+		"id":  {GroupSeparator: ","},
+		"amt": {GroupSeparator: ",", Decimals: 2},
+	}
+
+	xlsxFileName := filepath.Join(t.TempDir(), "test.xlsx")
+	if err := converter.WriteXlsxFile(xlsxFileName, "Sheet1"); err != nil {
+		t.Fatalf("error in WriteXlsxFile: %v", err)
+	}
+
+	// a <v> has to be a plain number, or the workbook is corrupt
+	sheet := readXlsxPart(t, xlsxFileName, "xl/worksheets/sheet1.xml")
+	if expected := `<row><c><v>1234567</v></c><c><v>1.234567e+06</v></c></row>`; !strings.Contains(sheet, expected) {
+		t.Errorf("expected %v in the sheet:\n%v", expected, sheet)
+	}
+}
+
+func TestWriteXlsxFileEpochTimeFormat(t *testing.T) {
+	day := time.Unix(86400, 0).UTC()
+	converter := sqltocsv.NewFromSource(&sliceSource{columns: []string{"created", "updated"}, rows: [][]any{{day, day}}})
+	converter.TimeFormat = sqltocsv.EpochSeconds
+	converter.TimeFormats = map[string]string{"updated": time.DateOnly}
+
+	xlsxFileName := filepath.Join(t.TempDir(), "test.xlsx")
+	if err := converter.WriteXlsxFile(xlsxFileName, "Sheet1"); err != nil {
+		t.Fatalf("error in WriteXlsxFile: %v", err)
+	}
+
+	// epoch times are plain numbers, the others still dates
+	sheet := readXlsxPart(t, xlsxFileName, "xl/worksheets/sheet1.xml")
+	if expected := `<row><c><v>86400</v></c><c s="2"><v>25570</v></c></row>`; !strings.Contains(sheet, expected) {
+		t.Errorf("expected %v in the sheet:\n%v", expected, sheet)
+	}
+	styles := readXlsxPart(t, xlsxFileName, "xl/styles.xml")
+	if !strings.Contains(styles, `<numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/><numFmt numFmtId="165" formatCode="yyyy-mm-dd"/>`) {
+		t.Errorf("expected the default and DateOnly formats in styles:\n%v", styles)
+	}
+}
+
+func TestWriteXlsxFileTimeFormats(t *testing.T) {
+	day := time.Unix(86400, 0).UTC()
+	converter := sqltocsv.NewFromSource(&sliceSource{
+		columns: []string{"created", "updated", "deleted", "seen"},
+		rows:    [][]any{{day, day, day, day}},
+	})
+	converter.TimeFormat = time.DateTime
+	converter.TimeFormats = map[string]string{"updated": time.DateOnly, "deleted": time.Kitchen, "seen": time.DateOnly}
+
+	xlsxFileName := filepath.Join(t.TempDir(), "test.xlsx")
+	if err := converter.WriteXlsxFile(xlsxFileName, "Sheet1"); err != nil {
+		t.Fatalf("error in WriteXlsxFile: %v", err)
+	}
+
+	sheet := readXlsxPart(t, xlsxFileName, "xl/worksheets/sheet1.xml")
+	expected := `<row><c s="1"><v>25570</v></c><c s="2"><v>25570</v></c><c s="3"><v>25570</v></c><c s="2"><v>25570</v></c></row>`
+	if !strings.Contains(sheet, expected) {
+		t.Errorf("expected %v in the sheet:\n%v", expected, sheet)
+	}
+
+	styles := readXlsxPart(t, xlsxFileName, "xl/styles.xml")
+	expectedFormats := `<numFmts count="3">` +
+		`<numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/>` +
+		`<numFmt numFmtId="165" formatCode="yyyy-mm-dd"/>` +
+		`<numFmt numFmtId="166" formatCode="h:mmAM/PM"/>` +
+		`</numFmts>`
+	if !strings.Contains(styles, expectedFormats) || !strings.Contains(styles, `<cellXfs count="4">`) {
+		t.Errorf("expected a date style for each format in styles:\n%v", styles)
+	}
+}
+
+func readXlsxPart(t *testing.T, xlsxFileName, partName string) string {
+	archive, err := zip.OpenReader(xlsxFileName)
+	if err != nil {
+		t.Fatalf("error opening %v: %v", xlsxFileName, err)
+	}
+	defer archive.Close()
+
+	part, err := archive.Open(partName)
+	if err != nil {
+		t.Fatalf("error opening %v in %v: %v", partName, xlsxFileName, err)
+	}
+	defer part.Close()
+
+	data, err := io.ReadAll(part)
+	if err != nil {
+		t.Fatalf("error reading %v: %v", partName, err)
+	}
+	return string(data)
+}
@@ -0,0 +1,71 @@
+package sqltocsv
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// WriteZipFile writes the CSV to the filename specified as a zip archive
+// holding a single entry called innerName, return an error if problem. The
+// entry is deflated at ZipLevel, or stored as it is if ZipStore is set, and
+// is streamed straight from the rows without being buffered. The CSV is
+// flushed, then the entry and archive are closed before the file so that the
+// central directory is always written, and errors from each are returned.
+func (c Converter) WriteZipFile(zipFileName, innerName string) (err error) {
+	defer c.closeRows(&err)
+	c.ownsRows, c.CloseRows = false, false // closed by the defer, just the once
+
+	if innerName == "" {
+		return errors.New("zip entry name must not be empty")
+	}
+
+	level := c.ZipLevel
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		return fmt.Errorf("zip level must be between %d and %d, got %d", flate.HuffmanOnly, flate.BestCompression, level)
+	}
+
+	f, err := c.createFile(zipFileName)
+	if err != nil {
+		return err
+	}
+
+	err = c.writeZip(f, innerName, level)
+	if err != nil {
+		f.abort() // close, but only return/handle the write error
+		return err
+	}
+
+	return f.commit()
+}
+
+func (c Converter) writeZip(writer io.Writer, innerName string, level int) error {
+	zipWriter := zip.NewWriter(writer)
+	zipWriter.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, level)
+	})
+
+	header := &zip.FileHeader{Name: innerName, Method: zip.Deflate, Modified: time.Now()}
+	if c.ZipStore {
+		header.Method = zip.Store
+	}
+	entry, err := zipWriter.CreateHeader(header)
+	if err != nil {
+		zipWriter.Close()
+		return err
+	}
+
+	// Write flushes the CSV into the entry, which is closed along with
+	// the archive
+	if err := c.Write(entry); err != nil {
+		zipWriter.Close()
+		return err
+	}
+	return zipWriter.Close()
+}
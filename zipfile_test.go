@@ -0,0 +1,90 @@
+package sqltocsv_test
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestWriteZipFile(t *testing.T) {
+	db := setupTypesDatabase(t)
+	golden := filepath.Join("testdata", "default.golden.csv")
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("error reading %s: %v", golden, err)
+	}
+
+	for _, test := range []struct {
+		level  int
+		store  bool
+		method uint16
+	}{
+		{0, false, zip.Deflate},
+		{9, false, zip.Deflate},
+		{0, true, zip.Store},
+	} {
+		converter := sqltocsv.New(queryTestRows(t, db, "SELECT|types|s,b,i32,i64,t,nt,ns,f,nf,ni,d|"))
+		converter.ZipLevel = test.level
+		converter.ZipStore = test.store
+
+		start := time.Now().Add(-2 * time.Second) // zip times are to the second
+		zipFileName := filepath.Join(t.TempDir(), "test.zip")
+		if err := converter.WriteZipFile(zipFileName, "export/test.csv"); err != nil {
+			t.Fatalf("error in WriteZipFile: %v", err)
+		}
+
+		archive, err := zip.OpenReader(zipFileName)
+		if err != nil {
+			t.Fatalf("error opening %s: %v", zipFileName, err)
+		}
+		defer archive.Close()
+		if len(archive.File) != 1 {
+			t.Fatalf("expected a single entry, got %d", len(archive.File))
+		}
+
+		entry := archive.File[0]
+		if entry.Name != "export/test.csv" {
+			t.Errorf("expected entry export/test.csv, got %s", entry.Name)
+		}
+		if entry.Method != test.method {
+			t.Errorf("expected method %d, got %d", test.method, entry.Method)
+		}
+		if entry.Modified.Before(start) || entry.Modified.After(time.Now()) {
+			t.Errorf("expected the entry to be modified now, got %v", entry.Modified)
+		}
+
+		r, err := entry.Open()
+		if err != nil {
+			t.Fatalf("error opening entry: %v", err)
+		}
+		actual, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("error reading entry: %v", err)
+		}
+		if string(actual) != string(expected) {
+			t.Errorf("output doesn't match %s\nexpected:\n%s\nactual:\n%s", golden, expected, actual)
+		}
+	}
+}
+
+func TestWriteZipFileInvalidArguments(t *testing.T) {
+	zipFileName := filepath.Join(t.TempDir(), "test.zip")
+
+	converter := getConverter(t)
+	if err := converter.WriteZipFile(zipFileName, ""); err == nil {
+		t.Error("expected error for empty entry name")
+	}
+
+	converter.ZipLevel = 10
+	if err := converter.WriteZipFile(zipFileName, "test.csv"); err == nil {
+		t.Error("expected error for invalid zip level")
+	}
+	if _, err := os.Stat(zipFileName); !os.IsNotExist(err) {
+		t.Errorf("expected no file to be created, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package sqltocsv
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// WriteZstdFile will write a zstd compressed CSV file to the file name
+// specified (with headers) based on whatever is in the sql.Rows you pass in.
+func WriteZstdFile(zstdFileName string, rows *sql.Rows) error {
+	return newClosing(rows).WriteZstdFile(zstdFileName)
+}
+
+// WriteZstdFile writes the CSV zstd compressed to the filename specified,
+// return an error if problem. ZstdLevel is a level as given to the zstd
+// command, from 1 to 22, which is mapped to the nearest level the encoder
+// has. The output is streamed, so memory use doesn't grow with the size of
+// the result set, and the encoder is closed before the file so that the
+// last frame is always written.
+func (c Converter) WriteZstdFile(zstdFileName string) (err error) {
+	defer c.closeRows(&err)
+	c.ownsRows, c.CloseRows = false, false // closed by the defer, just the once
+
+	level := zstd.SpeedDefault
+	if c.ZstdLevel != 0 {
+		if c.ZstdLevel < 1 || c.ZstdLevel > 22 {
+			return fmt.Errorf("zstd level must be between 1 and 22, got %d", c.ZstdLevel)
+		}
+		level = zstd.EncoderLevelFromZstd(c.ZstdLevel)
+	}
+
+	f, err := c.createFile(zstdFileName)
+	if err != nil {
+		return err
+	}
+
+	zw, err := zstd.NewWriter(f, zstd.WithEncoderLevel(level))
+	if err != nil {
+		f.abort()
+		return err
+	}
+
+	err = c.Write(zw)
+	if err != nil {
+		zw.Close() // close, but only return/handle the write error
+		f.abort()
+		return err
+	}
+
+	if err = zw.Close(); err != nil {
+		f.abort()
+		return err
+	}
+
+	return f.commit()
+}
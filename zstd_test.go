@@ -0,0 +1,80 @@
+package sqltocsv_test
+
+import (
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/armantarkhanian/sqltocsv"
+)
+
+func TestWriteZstdFile(t *testing.T) {
+	checkQueryAgainstResult(t, func(rows *sql.Rows) string {
+		zstdFileName := filepath.Join(t.TempDir(), "test.csv.zst")
+		err := sqltocsv.WriteZstdFile(zstdFileName, rows)
+		if err != nil {
+			t.Fatalf("error in WriteZstdFile: %v", err)
+		}
+
+		return readZstdFile(t, zstdFileName)
+	})
+}
+
+func TestWriteZstdFileGolden(t *testing.T) {
+	db := setupTypesDatabase(t)
+	golden := filepath.Join("testdata", "default.golden.csv")
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("error reading %s: %v", golden, err)
+	}
+
+	for _, level := range []int{0, 1, 19} {
+		converter := sqltocsv.New(queryTestRows(t, db, "SELECT|types|s,b,i32,i64,t,nt,ns,f,nf,ni,d|"))
+		converter.ZstdLevel = level
+
+		zstdFileName := filepath.Join(t.TempDir(), "test.csv.zst")
+		if err := converter.WriteZstdFile(zstdFileName); err != nil {
+			t.Fatalf("level %d: error in WriteZstdFile: %v", level, err)
+		}
+		if actual := readZstdFile(t, zstdFileName); actual != string(expected) {
+			t.Errorf("level %d: output doesn't match %s\nexpected:\n%s\nactual:\n%s", level, golden, expected, actual)
+		}
+	}
+}
+
+func TestZstdLevel(t *testing.T) {
+	converter := getConverter(t)
+	converter.ZstdLevel = 23
+
+	zstdFileName := filepath.Join(t.TempDir(), "test.csv.zst")
+	if err := converter.WriteZstdFile(zstdFileName); err == nil {
+		t.Error("expected error for invalid zstd level")
+	}
+	if _, err := os.Stat(zstdFileName); !os.IsNotExist(err) {
+		t.Errorf("expected no file to be created, got %v", err)
+	}
+}
+
+func readZstdFile(t *testing.T, name string) string {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("error opening %v: %v", name, err)
+	}
+	defer f.Close()
+
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		t.Fatalf("error reading zstd header of %v: %v", name, err)
+	}
+	defer zr.Close()
+
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("error decompressing %v: %v", name, err)
+	}
+	return string(data)
+}